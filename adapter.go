@@ -2,8 +2,10 @@ package throughput
 
 import (
 	"context"
+	"fmt"
 	"golang.org/x/time/rate"
 	"math"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,24 +17,37 @@ import (
 //
 // Additionally, the limiter's burst capacity is mutable (SetBurst) and protected internally by a lock. As there's
 // no transaction between checking Burst and calling WaitN/ReserveN, extra care is needed.
+//
+// To keep the hot path to a single lock acquisition, the burst capacity is cached by the adapter. Change it via
+// the adapter's SetBurst so the cache stays current: reductions made directly on the rate.Limiter are detected
+// when a reservation fails, but increases are only picked up by SetBurst.
 type RateLimiterAdapter struct {
-	lim *rate.Limiter
+	lim   *rate.Limiter
+	burst atomic.Int64
+	w     waiter
+}
+
+func NewRateLimiterAdapter(lim *rate.Limiter, opts ...Option) *RateLimiterAdapter {
+	a := &RateLimiterAdapter{lim: lim, w: newWaiter(opts)}
+	a.burst.Store(int64(lim.Burst()))
+	return a
 }
 
-func NewRateLimiterAdapter(lim *rate.Limiter) *RateLimiterAdapter {
-	return &RateLimiterAdapter{lim: lim}
+// chunk returns how many of n bytes can be reserved at once, given the cached burst capacity.
+// A burst of zero or less doesn't limit chunks, as it only allows reservations at an infinite rate.
+func chunk(n int, burst int) int {
+	if burst <= 0 {
+		return n
+	}
+	return min(burst, n)
 }
 
 func (a *RateLimiterAdapter) Wait(ctx context.Context, n int) error {
-	// Don't invoke lim.Burst() unless necessary, as it is an additional lock acquisition
-	//
-	// This allows the happy path to do the minimum amount of locking, which is a consideration due to how
-	// hot this code path may be in high throughput scenarios.
-	burst := math.MaxInt
+	burst := int(a.burst.Load())
 
 	for {
-		now := time.Now()
-		nn := min(burst, n)
+		now := a.w.now()
+		nn := chunk(n, burst)
 
 		// ReserveN+timer, because WaitN doesn't provide structured errors.
 		res := a.lim.ReserveN(now, nn)
@@ -40,20 +55,19 @@ func (a *RateLimiterAdapter) Wait(ctx context.Context, n int) error {
 			// n exceeds burst capacity
 			//
 			// This should not happen in normal io use-cases, as an individual read/write is likely to be much
-			// smaller than the limiter's per-second capacity.
-			//
-			// Increases won't be picked up until the next call to Wait, as an accepted trade-off.
-			burst = a.lim.Burst()
+			// smaller than the limiter's per-second capacity. It means the burst was reduced on the rate.Limiter
+			// directly, so the cache is refreshed.
+			burst = a.refreshBurst()
+			if burst <= 0 {
+				return fmt.Errorf("reserving %d bytes: exceeds burst capacity", nn)
+			}
 			continue
 		}
 
-		if res.Delay() > 0 {
-			select {
-			case <-time.After(res.DelayFrom(now)):
-			case <-ctx.Done():
-				res.Cancel()
-				return ctx.Err()
-			}
+		err := a.w.sleep(ctx, res.DelayFrom(now))
+		if err != nil {
+			res.CancelAt(a.w.now())
+			return err
 		}
 
 		n -= nn
@@ -64,4 +78,71 @@ func (a *RateLimiterAdapter) Wait(ctx context.Context, n int) error {
 	}
 }
 
-var _ Limiter = (*RateLimiterAdapter)(nil)
+// Reserve charges n bytes without waiting, and returns how long the caller must wait before proceeding.
+// As with Wait, n may exceed the burst capacity, in which case several reservations are made back to back.
+func (a *RateLimiterAdapter) Reserve(n int) (delay time.Duration, cancel func()) {
+	now := a.w.now()
+	burst := int(a.burst.Load())
+
+	var reservations []*rate.Reservation
+	cancel = func() {
+		at := a.w.now()
+		for i := len(reservations) - 1; i >= 0; i-- {
+			reservations[i].CancelAt(at)
+		}
+	}
+
+	for n > 0 {
+		nn := chunk(n, burst)
+		res := a.lim.ReserveN(now, nn)
+		if !res.OK() {
+			burst = a.refreshBurst()
+			if burst <= 0 {
+				// Nothing can ever be reserved
+				return math.MaxInt64, cancel
+			}
+			continue
+		}
+
+		// Each reservation is made at the same instant, so the last one's delay accounts for all of them.
+		reservations = append(reservations, res)
+		delay = res.DelayFrom(now)
+		n -= nn
+	}
+	return delay, cancel
+}
+
+// refreshBurst updates the cached burst capacity from the wrapped limiter, and returns it.
+func (a *RateLimiterAdapter) refreshBurst() int {
+	burst := a.lim.Burst()
+	a.burst.Store(int64(burst))
+	return burst
+}
+
+// Burst returns the wrapped limiter's burst capacity, as cached by the adapter.
+func (a *RateLimiterAdapter) Burst() int {
+	return int(a.burst.Load())
+}
+
+// SetBurst changes the wrapped limiter's burst capacity to n, keeping the adapter's cache current.
+func (a *RateLimiterAdapter) SetBurst(n int) {
+	a.lim.SetBurstAt(a.w.now(), n)
+	a.burst.Store(int64(n))
+}
+
+// Limit returns the wrapped limiter's rate in bytes per second, or math.MaxInt64 if it is rate.Inf.
+func (a *RateLimiterAdapter) Limit() int64 {
+	l := a.lim.Limit()
+	if l == rate.Inf || l >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(l)
+}
+
+// SetLimit changes the wrapped limiter's rate to bytesPerSec. Burst capacity is unaffected.
+func (a *RateLimiterAdapter) SetLimit(bytesPerSec int64) {
+	a.lim.SetLimitAt(a.w.now(), rate.Limit(bytesPerSec))
+}
+
+var _ AdjustableLimiter = (*RateLimiterAdapter)(nil)
+var _ Reserver = (*RateLimiterAdapter)(nil)
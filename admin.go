@@ -0,0 +1,119 @@
+package throughput
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NewAdminHandler returns an http.Handler exposing a Manager's groups, so limits can be inspected and changed at
+// runtime without a redeploy:
+//
+//   - GET / lists every group, with its limit, current rate and members.
+//   - GET /{group} returns a single group.
+//   - PATCH /{group} changes an existing group, given a JSON body with "limit" (bytes per second) and/or "enabled".
+//   - POST /{group} does the same, creating the group if necessary, in which case "limit" is required.
+//
+// The handler has no authentication, so it should only be served to operators. Use http.StripPrefix to mount it
+// under a path.
+func NewAdminHandler(m *Manager) http.Handler {
+	a := &adminHandler{m: m}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", a.list)
+	mux.HandleFunc("GET /{group}", a.get)
+	mux.HandleFunc("PATCH /{group}", a.update)
+	mux.HandleFunc("POST /{group}", a.update)
+	return mux
+}
+
+type adminHandler struct {
+	m *Manager
+}
+
+type adminGroup struct {
+	Name    string        `json:"name"`
+	Limit   int64         `json:"limit"`
+	Enabled bool          `json:"enabled"`
+	Rate    float64       `json:"rate"`
+	Members []adminMember `json:"members"`
+}
+
+type adminMember struct {
+	Name  string  `json:"name"`
+	Rate  float64 `json:"rate"`
+	Bytes int64   `json:"bytes"`
+}
+
+type adminUpdate struct {
+	Limit   *int64 `json:"limit"`
+	Enabled *bool  `json:"enabled"`
+}
+
+func (a *adminHandler) list(w http.ResponseWriter, _ *http.Request) {
+	groups := []adminGroup{}
+	for _, g := range a.m.Groups() {
+		groups = append(groups, describeGroup(g))
+	}
+	writeJSON(w, map[string]any{"groups": groups})
+}
+
+func (a *adminHandler) get(w http.ResponseWriter, r *http.Request) {
+	g := a.m.Group(r.PathValue("group"))
+	if g == nil {
+		http.Error(w, "no such group", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, describeGroup(g))
+}
+
+func (a *adminHandler) update(w http.ResponseWriter, r *http.Request) {
+	var u adminUpdate
+	err := json.NewDecoder(r.Body).Decode(&u)
+	if err != nil {
+		http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if u.Limit != nil && *u.Limit < 0 {
+		http.Error(w, "limit must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	name := r.PathValue("group")
+	g := a.m.Group(name)
+	switch {
+	case g == nil && r.Method == http.MethodPatch:
+		http.Error(w, "no such group", http.StatusNotFound)
+		return
+	case g == nil && u.Limit == nil:
+		http.Error(w, "limit is required to create a group", http.StatusBadRequest)
+		return
+	case g == nil:
+		g = a.m.SetGroup(name, *u.Limit)
+	case u.Limit != nil:
+		g.SetLimit(*u.Limit)
+	}
+
+	if u.Enabled != nil {
+		g.SetEnabled(*u.Enabled)
+	}
+	writeJSON(w, describeGroup(g))
+}
+
+func describeGroup(g *Group) adminGroup {
+	desc := adminGroup{
+		Name:    g.Name(),
+		Limit:   g.Limit(),
+		Enabled: g.Enabled(),
+		Rate:    g.Rate(),
+		Members: []adminMember{},
+	}
+	for _, gm := range g.Members() {
+		desc.Members = append(desc.Members, adminMember{Name: gm.Name(), Rate: gm.Rate(), Bytes: gm.Bytes()})
+	}
+	return desc
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,62 @@
+package throughput
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandler(t *testing.T) {
+	m := NewManager()
+	g := m.SetGroup("backup", 1000)
+	_ = g.Join("nightly").Wait(context.Background(), 10)
+
+	h := NewAdminHandler(m)
+	do := func(method, path, body string) (int, adminGroup) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		var desc adminGroup
+		_ = json.Unmarshal(w.Body.Bytes(), &desc)
+		return w.Code, desc
+	}
+
+	code, desc := do(http.MethodGet, "/backup", "")
+	if code != http.StatusOK || desc.Limit != 1000 || !desc.Enabled {
+		t.Errorf("GET: %d %+v", code, desc)
+	}
+	if len(desc.Members) != 1 || desc.Members[0].Name != "nightly" || desc.Members[0].Bytes != 10 {
+		t.Errorf("GET members: %+v", desc.Members)
+	}
+
+	code, desc = do(http.MethodPatch, "/backup", `{"limit": 500, "enabled": false}`)
+	if code != http.StatusOK || g.Limit() != 500 || g.Enabled() {
+		t.Errorf("PATCH: %d %+v", code, desc)
+	}
+
+	code, _ = do(http.MethodPatch, "/api", `{"limit": 500}`)
+	if code != http.StatusNotFound {
+		t.Errorf("PATCH missing group: %d, want 404", code)
+	}
+	code, _ = do(http.MethodPost, "/api", `{"enabled": true}`)
+	if code != http.StatusBadRequest {
+		t.Errorf("POST without limit: %d, want 400", code)
+	}
+	code, _ = do(http.MethodPost, "/api", `{"limit": 2000}`)
+	if code != http.StatusOK || m.Group("api") == nil {
+		t.Errorf("POST: %d, group created = %t", code, m.Group("api") != nil)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	var list struct{ Groups []adminGroup }
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Groups) != 2 || list.Groups[0].Name != "api" {
+		t.Errorf("GET /: %s", w.Body)
+	}
+}
@@ -0,0 +1,67 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrTooManyTransfers is returned by Admission.TryAcquire when the maximum number of transfers are active.
+var ErrTooManyTransfers = errors.New("throughput: too many transfers")
+
+// Admission caps the number of transfers active at once. Bandwidth limits alone don't stop a thousand trickling
+// transfers from exhausting file descriptors, memory or backend connections; admitting transfers through an
+// Admission before they start does.
+//
+// Acquire queues transfers beyond the maximum until a slot is released, whilst TryAcquire rejects them.
+type Admission struct {
+	slots  chan struct{}
+	queued atomic.Int64
+}
+
+// NewAdmission returns an Admission allowing at most max transfers at once.
+func NewAdmission(max int) *Admission {
+	return &Admission{slots: make(chan struct{}, max)}
+}
+
+// Acquire waits for a slot, returning a func which releases it once the transfer is complete. Release may be
+// called more than once.
+func (a *Admission) Acquire(ctx context.Context) (release func(), err error) {
+	a.queued.Add(1)
+	defer a.queued.Add(-1)
+
+	select {
+	case a.slots <- struct{}{}:
+		return a.release(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// TryAcquire takes a slot if one is free, or returns ErrTooManyTransfers.
+func (a *Admission) TryAcquire() (release func(), err error) {
+	select {
+	case a.slots <- struct{}{}:
+		return a.release(), nil
+	default:
+		return nil, ErrTooManyTransfers
+	}
+}
+
+func (a *Admission) release() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-a.slots })
+	}
+}
+
+// Active returns the number of transfers holding a slot.
+func (a *Admission) Active() int {
+	return len(a.slots)
+}
+
+// Queued returns the number of transfers waiting in Acquire for a slot.
+func (a *Admission) Queued() int {
+	return int(a.queued.Load())
+}
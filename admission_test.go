@@ -0,0 +1,60 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAdmission(t *testing.T) {
+	a := NewAdmission(2)
+	release1, _ := a.Acquire(context.Background())
+	release2, _ := a.TryAcquire()
+	if a.Active() != 2 {
+		t.Errorf("got %d active, want 2", a.Active())
+	}
+
+	// Beyond the maximum, TryAcquire rejects...
+	if _, err := a.TryAcquire(); !errors.Is(err, ErrTooManyTransfers) {
+		t.Errorf("TryAcquire returned %v, want ErrTooManyTransfers", err)
+	}
+
+	// ...and Acquire queues until a slot is released
+	acquired := make(chan struct{})
+	go func() {
+		release, err := a.Acquire(context.Background())
+		if err == nil {
+			defer release()
+		}
+		close(acquired)
+	}()
+	for a.Queued() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-acquired:
+		t.Fatal("Acquire returned before a slot was released")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release1()
+	release1() // no-op
+	<-acquired
+	release2()
+	if a.Active() != 0 || a.Queued() != 0 {
+		t.Errorf("got %d active, %d queued, want none", a.Active(), a.Queued())
+	}
+}
+
+func TestAdmissionCancel(t *testing.T) {
+	a := NewAdmission(1)
+	release, _ := a.Acquire(context.Background())
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := a.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire returned %v, want context.DeadlineExceeded", err)
+	}
+}
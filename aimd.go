@@ -0,0 +1,99 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// AIMDLimiter wraps an AdjustableLimiter, adapting its rate by additive increase, multiplicative decrease -- the
+// congestion control used by TCP. This gives self-tuning throttling for flaky or overloaded upstreams.
+//
+// Whilst transfers succeed, the rate increases by a fixed step each interval. When the application reports
+// congestion (timeouts, 5xx responses, dropped packets...) via ReportFailure, the rate halves. At most one
+// decrease happens per interval, so a burst of failures caused by the same congestion only halves the rate once.
+//
+// Like RampLimiter, increases are applied lazily as Wait is called, so an idle limiter doesn't keep increasing.
+type AIMDLimiter struct {
+	lim      AdjustableLimiter
+	min, max int64
+	step     int64
+	interval time.Duration
+	w        waiter
+
+	mu           sync.Mutex
+	rate         int64
+	lastIncrease time.Time
+	lastDecrease time.Time
+}
+
+// NewAIMDLimiter returns a limiter adapting lim's rate between minBytesPerSec and maxBytesPerSec, increasing by
+// step bytes per second each interval. The rate begins at lim's current rate.
+func NewAIMDLimiter(
+	lim AdjustableLimiter,
+	minBytesPerSec, maxBytesPerSec, step int64,
+	interval time.Duration,
+	opts ...Option,
+) *AIMDLimiter {
+	a := &AIMDLimiter{
+		lim:      lim,
+		min:      minBytesPerSec,
+		max:      maxBytesPerSec,
+		step:     step,
+		interval: interval,
+		w:        newWaiter(opts),
+	}
+	a.rate = min(max(lim.Limit(), a.min), a.max)
+	a.lastIncrease = a.w.now()
+	lim.SetLimit(a.rate)
+	return a
+}
+
+func (a *AIMDLimiter) Wait(ctx context.Context, n int) error {
+	a.increase()
+	return a.lim.Wait(ctx, n)
+}
+
+func (a *AIMDLimiter) increase() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.w.now()
+	if now.Sub(a.lastIncrease) < a.interval || a.rate >= a.max {
+		return
+	}
+	a.lastIncrease = now
+	a.set(min(a.rate+a.step, a.max))
+}
+
+// ReportFailure reports congestion, halving the rate unless it has already been decreased within the last
+// interval.
+func (a *AIMDLimiter) ReportFailure() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.w.now()
+	if !a.lastDecrease.IsZero() && now.Sub(a.lastDecrease) < a.interval {
+		return
+	}
+	a.lastDecrease = now
+	a.lastIncrease = now
+	a.set(max(a.rate/2, a.min))
+}
+
+// set changes the rate. Must be called with mu held.
+func (a *AIMDLimiter) set(bytesPerSec int64) {
+	if bytesPerSec != a.rate {
+		a.rate = bytesPerSec
+		a.lim.SetLimit(bytesPerSec)
+	}
+}
+
+// Limit returns the current rate in bytes per second.
+func (a *AIMDLimiter) Limit() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.rate
+}
+
+var _ Limiter = (*AIMDLimiter)(nil)
@@ -0,0 +1,50 @@
+package throughput
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAIMDLimiter(t *testing.T) {
+	c := &stepClock{now: time.Unix(0, 0)}
+	tb := NewTokenBucket(1000, 1<<20, WithClock(c))
+	a := NewAIMDLimiter(tb, 300, 1200, 100, time.Second, WithClock(c))
+	ctx := context.Background()
+
+	check := func(when string, want int64) {
+		t.Helper()
+		if got := a.Limit(); got != want {
+			t.Errorf("%s: Limit() = %d, want %d", when, got, want)
+		}
+		if got := tb.Limit(); got != want {
+			t.Errorf("%s: wrapped limiter's rate = %d, want %d", when, got, want)
+		}
+	}
+
+	_ = a.Wait(ctx, 1)
+	check("immediately", 1000)
+
+	c.NewTimer(time.Second)
+	_ = a.Wait(ctx, 1)
+	check("after 1 interval", 1100)
+
+	// Increases are capped at max
+	for range 5 {
+		c.NewTimer(time.Second)
+		_ = a.Wait(ctx, 1)
+	}
+	check("after 6 intervals", 1200)
+
+	a.ReportFailure()
+	check("after failure", 600)
+
+	// Failures within the same interval only decrease once
+	a.ReportFailure()
+	check("after second failure", 600)
+
+	// Decreases are capped at min
+	c.NewTimer(time.Second)
+	a.ReportFailure()
+	check("after failure in next interval", 300)
+}
@@ -0,0 +1,105 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+)
+
+// Refunder is implemented by limiters that can return capacity charged by a successful Wait, which allows
+// combinators to undo a charge when a Wait on another limiter fails. A Refunder should also undo the charge for
+// a Wait of its own which fails.
+type Refunder interface {
+	// Refund returns n bytes of capacity to the limiter.
+	Refund(n int)
+}
+
+// refunds reports whether lim undoes the charge for a Wait which fails. Wrappers such as DebugLimiter implement
+// Refunder whatever they wrap, so they report whether the limiter they wrap refunds.
+func refunds(lim Limiter) bool {
+	if w, ok := lim.(interface{ refunds() bool }); ok {
+		return w.refunds()
+	}
+	_, ok := lim.(Refunder)
+	return ok
+}
+
+// AllLimiter enforces several limiters at once, e.g. "per-connection AND per-user AND global" limits.
+//
+// Each Wait charges n to every limiter concurrently, so the delay is that of the slowest limiter rather than the
+// sum of them all. If any limiter fails, the waits on the others are cancelled (which limiters such as
+// RateLimiterAdapter treat as a refund), and limiters which had already succeeded are refunded if they implement
+// Refunder.
+type AllLimiter struct {
+	lims []Limiter
+}
+
+// NewAllLimiter returns a limiter which waits on all of lims.
+func NewAllLimiter(lims ...Limiter) *AllLimiter {
+	return &AllLimiter{lims: lims}
+}
+
+func (a *AllLimiter) Wait(ctx context.Context, n int) error {
+	switch len(a.lims) {
+	case 0:
+		return nil
+	case 1:
+		return a.lims[0].Wait(ctx, n)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The first error is the cause; errors from the limiters cancelled as a result are not interesting.
+	var once sync.Once
+	var cause error
+	succeeded := make([]bool, len(a.lims))
+
+	wait := func(i int) {
+		err := a.lims[i].Wait(ctx, n)
+		if err != nil {
+			once.Do(func() {
+				cause = err
+				cancel()
+			})
+			return
+		}
+		succeeded[i] = true
+	}
+
+	var wg sync.WaitGroup
+	for i := 1; i < len(a.lims); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait(i)
+		}()
+	}
+	// Save a goroutine by waiting on the first limiter inline
+	wait(0)
+	wg.Wait()
+
+	if cause == nil {
+		return nil
+	}
+
+	for i, ok := range succeeded {
+		if r, isRefunder := a.lims[i].(Refunder); ok && isRefunder {
+			r.Refund(n)
+		}
+	}
+	return cause
+}
+
+// PerConnAndGlobal returns a function creating limiters for the common "each client at most perConnRate, all
+// clients at most global" policy. Each limiter returned waits on its own token bucket, holding a second of
+// perConnRate, and on global. For example:
+//
+//	newLim := throughput.PerConnAndGlobal(1<<20, throughput.NewTokenBucket(10<<20, 10<<20))
+//	conn = throughput.NewConn(ctx, conn, newLim(), newLim())
+func PerConnAndGlobal(perConnRate int64, global Limiter) func() Limiter {
+	return func() Limiter {
+		return NewAllLimiter(NewTokenBucket(perConnRate, perConnRate), global)
+	}
+}
+
+var _ Limiter = (*AllLimiter)(nil)
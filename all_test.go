@@ -0,0 +1,56 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAllLimiterWaitsForSlowest(t *testing.T) {
+	lim := NewAllLimiter(NewPacer(10*1024), NewPacer(1024), NewPacer(100*1024))
+
+	// The delays overlap, so the total is the slowest limiter's delay rather than the sum
+	start := time.Now()
+	_ = lim.Wait(context.Background(), 100)
+	err := verifyWithSlop(time.Since(start), 100*time.Second/1024, 20*time.Millisecond)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestAllLimiterRefundsOnFailure(t *testing.T) {
+	fast := NewTokenBucket(1024, 1024)
+	failing := NewThreeColorLimiter(1, 0, 1, 0, ColorPolicy{Red: Drop})
+
+	lim := NewAllLimiter(fast, failing)
+	if err := lim.Wait(context.Background(), 512); !errors.Is(err, ErrDropped) {
+		t.Fatalf("got %v, want ErrDropped", err)
+	}
+
+	if tokens := fast.Tokens(); tokens < 1024 {
+		t.Errorf("tokens = %.0f, want 1024 after refund", tokens)
+	}
+}
+
+func TestPerConnAndGlobal(t *testing.T) {
+	global := NewTokenBucket(1024, 1024)
+	newLim := PerConnAndGlobal(512, global)
+	a, b := newLim(), newLim()
+
+	// Each connection's burst is its own, but both draw from the global bucket
+	_ = a.Wait(context.Background(), 512)
+	_ = b.Wait(context.Background(), 512)
+	if tokens := global.Tokens(); tokens > 1 {
+		t.Errorf("global tokens = %.0f, want 0", tokens)
+	}
+
+	// A connection over its own rate waits, even with global capacity to spare
+	global.Refund(1024)
+	start := time.Now()
+	_ = a.Wait(context.Background(), 50)
+	err := verifyWithSlop(time.Since(start), 50*time.Second/512, 20*time.Millisecond)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
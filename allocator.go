@@ -0,0 +1,240 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrOverallocated is returned when registering a stream would guarantee more than an Allocator's rate.
+var ErrOverallocated = errors.New("throughput: guarantees exceed allocator rate")
+
+// Allocator divides a total rate between registered streams, each of which gets its own limiter.
+//
+// Shares are rebalanced whenever a stream registers or closes: 3 streams each get 1/3 of the rate, and a 4th
+// joining drops everyone to 1/4. Unlike FairShare, a stream's share is reserved whether or not it is in use.
+//
+// Streams may also be registered with a guaranteed minimum rate and a weight, using RegisterShare. Each stream
+// always gets at least its minimum, and whatever is left over is divided between streams by weight.
+//
+// By default, shares are reserved for streams whether or not they are in use. In work-conserving mode (see
+// SetWorkConserving), only streams which are waiting or have recently waited get a share, so the rate of idle
+// streams -- including their minimums -- is lent to the others, and returned as soon as they resume.
+type Allocator struct {
+	opts []Option
+	w    waiter
+
+	mu             sync.Mutex
+	rate           int64
+	burst          int64
+	streams        []*Allocation // in order of registration
+	workConserving bool
+}
+
+// NewAllocator returns an allocator dividing bytesPerSec between its streams. Burst is divided in proportion to
+// each stream's share.
+// Opts are applied to the limiter of every stream.
+func NewAllocator(bytesPerSec, burst int64, opts ...Option) *Allocator {
+	return &Allocator{
+		opts:  opts,
+		w:     newWaiter(opts),
+		rate:  bytesPerSec,
+		burst: burst,
+	}
+}
+
+// Register adds a stream with no guaranteed minimum and a weight of 1, rebalancing the shares of existing
+// streams, and returns its limiter. The stream should be closed once it is no longer needed.
+//
+// If SetLimit has lowered the rate below the other streams' minimums, the stream gets no share until the rate is
+// raised, or streams close.
+func (a *Allocator) Register() *Allocation {
+	s, _ := a.RegisterShare(0, 1) // only a minimum can overallocate
+	return s
+}
+
+// RegisterShare adds a stream which is guaranteed at least minBytesPerSec, and shares any excess with other
+// streams in proportion to weight. If weight is not positive, the stream only gets its minimum.
+//
+// ErrOverallocated is returned if the stream has a minimum, and the minimums of all streams would exceed the
+// allocator's rate.
+func (a *Allocator) RegisterShare(minBytesPerSec int64, weight float64) (*Allocation, error) {
+	s := &Allocation{
+		a:      a,
+		min:    max(minBytesPerSec, 0),
+		weight: max(weight, 0),
+		lim:    NewTokenBucket(0, 0, a.opts...),
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if guaranteed := a.guaranteed(); s.min > 0 && guaranteed+s.min > a.rate {
+		return nil, fmt.Errorf("%w: guaranteeing %d B/s on top of %d B/s, of %d B/s",
+			ErrOverallocated, s.min, guaranteed, a.rate)
+	}
+
+	a.streams = append(a.streams, s)
+	a.rebalance()
+	return s, nil
+}
+
+// Limit returns the rate divided between streams, in bytes per second.
+func (a *Allocator) Limit() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.rate
+}
+
+// SetLimit changes the rate divided between streams to bytesPerSec. If this is less than the sum of the streams'
+// minimums, each stream gets a share in proportion to its minimum instead.
+func (a *Allocator) SetLimit(bytesPerSec int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rate = bytesPerSec
+	a.rebalance()
+}
+
+// SetWorkConserving enables or disables lending the shares of idle streams to active ones.
+func (a *Allocator) SetWorkConserving(enabled bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.workConserving = enabled
+	a.updateActive(a.w.now())
+	a.rebalance()
+}
+
+// Len returns the number of registered streams.
+func (a *Allocator) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.streams)
+}
+
+// guaranteed returns the sum of the streams' minimums. Must be called with mu held.
+func (a *Allocator) guaranteed() int64 {
+	var total int64
+	for _, s := range a.streams {
+		total += s.min
+	}
+	return total
+}
+
+// rebalance recalculates every stream's share. Must be called with mu held.
+func (a *Allocator) rebalance() {
+	var guaranteed int64
+	var weights float64
+	for _, s := range a.streams {
+		if a.entitled(s) {
+			guaranteed += s.min
+			weights += s.weight
+		}
+	}
+	excess := float64(a.rate - guaranteed)
+
+	for _, s := range a.streams {
+		var share float64
+		switch {
+		case !a.entitled(s):
+			share = 0
+		case excess < 0:
+			share = float64(a.rate) * float64(s.min) / float64(guaranteed)
+		case weights > 0:
+			share = float64(s.min) + excess*s.weight/weights
+		default:
+			share = float64(s.min)
+		}
+
+		s.lim.SetLimit(int64(share))
+		if a.rate > 0 {
+			s.lim.SetBurst(int64(float64(a.burst) * share / float64(a.rate)))
+		}
+	}
+}
+
+// entitled returns whether s should currently get a share. Must be called with mu held.
+func (a *Allocator) entitled(s *Allocation) bool {
+	return !a.workConserving || s.active
+}
+
+// updateActive recalculates which streams are active, returning whether any have changed.
+// Must be called with mu held.
+func (a *Allocator) updateActive(now time.Time) (changed bool) {
+	for _, s := range a.streams {
+		active := s.waiting > 0 || now.Before(s.lastWait.Add(idleGrace))
+		if active != s.active {
+			s.active = active
+			changed = true
+		}
+	}
+	return changed
+}
+
+// beginWait marks s as active, rebalancing if this or the lapse of another stream changes the active set.
+func (a *Allocator) beginWait(s *Allocation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.workConserving {
+		return
+	}
+
+	s.waiting++
+	if a.updateActive(a.w.now()) {
+		a.rebalance()
+	}
+}
+
+func (a *Allocator) endWait(s *Allocation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if s.waiting > 0 {
+		s.waiting--
+	}
+	s.lastWait = a.w.now()
+}
+
+func (a *Allocator) unregister(s *Allocation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, other := range a.streams {
+		if other == s {
+			a.streams = append(a.streams[:i], a.streams[i+1:]...)
+			a.rebalance()
+			return
+		}
+	}
+}
+
+// Allocation is a limiter for one stream of an Allocator.
+type Allocation struct {
+	a      *Allocator
+	min    int64
+	weight float64
+	lim    *TokenBucket
+
+	// Guarded by a.mu
+	active   bool
+	waiting  int
+	lastWait time.Time
+}
+
+func (s *Allocation) Wait(ctx context.Context, n int) error {
+	s.a.beginWait(s)
+	defer s.a.endWait(s)
+	return s.lim.Wait(ctx, n)
+}
+
+// Limit returns the stream's current share of the rate, in bytes per second.
+// In work-conserving mode, this is zero whilst the stream is idle.
+func (s *Allocation) Limit() int64 {
+	return s.lim.Limit()
+}
+
+// Close unregisters the stream, so its share is divided amongst the others.
+func (s *Allocation) Close() error {
+	s.a.unregister(s)
+	return nil
+}
+
+var _ Limiter = (*Allocation)(nil)
@@ -0,0 +1,151 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAllocator(t *testing.T) {
+	a := NewAllocator(1200, 120)
+
+	s1 := a.Register()
+	if got := s1.Limit(); got != 1200 {
+		t.Errorf("lone stream has %d B/s, want 1200", got)
+	}
+
+	s2 := a.Register()
+	s3 := a.Register()
+	for i, s := range []*Allocation{s1, s2, s3} {
+		if got := s.Limit(); got != 400 {
+			t.Errorf("stream %d of 3 has %d B/s, want 400", i, got)
+		}
+	}
+
+	s4 := a.Register()
+	for i, s := range []*Allocation{s1, s2, s3, s4} {
+		if got := s.Limit(); got != 300 {
+			t.Errorf("stream %d of 4 has %d B/s, want 300", i, got)
+		}
+	}
+
+	_ = s2.Close()
+	_ = s2.Close() // no-op
+	if got := a.Len(); got != 3 {
+		t.Errorf("Len() = %d, want 3", got)
+	}
+	if got := s4.Limit(); got != 400 {
+		t.Errorf("after close, stream has %d B/s, want 400", got)
+	}
+
+	a.SetLimit(3000)
+	if got := s1.Limit(); got != 1000 {
+		t.Errorf("after SetLimit, stream has %d B/s, want 1000", got)
+	}
+}
+
+func TestAllocator_RegisterShare(t *testing.T) {
+	a := NewAllocator(1000, 0)
+
+	// 600 guaranteed, so 400 excess shared 3:1
+	s1, err := a.RegisterShare(500, 3)
+	if err != nil {
+		t.Fatalf("RegisterShare: %v", err)
+	}
+	s2, err := a.RegisterShare(100, 1)
+	if err != nil {
+		t.Fatalf("RegisterShare: %v", err)
+	}
+	if got1, got2 := s1.Limit(), s2.Limit(); got1 != 800 || got2 != 200 {
+		t.Errorf("shares = %d, %d, want 800, 200", got1, got2)
+	}
+
+	// A stream with no weight only gets its minimum
+	s3, err := a.RegisterShare(200, 0)
+	if err != nil {
+		t.Fatalf("RegisterShare: %v", err)
+	}
+	if got1, got2, got3 := s1.Limit(), s2.Limit(), s3.Limit(); got1 != 650 || got2 != 150 || got3 != 200 {
+		t.Errorf("shares = %d, %d, %d, want 650, 150, 200", got1, got2, got3)
+	}
+
+	_, err = a.RegisterShare(201, 1)
+	if !errors.Is(err, ErrOverallocated) {
+		t.Errorf("overallocating: err = %v, want ErrOverallocated", err)
+	}
+	if got := a.Len(); got != 3 {
+		t.Errorf("Len() = %d after failed registration, want 3", got)
+	}
+
+	// Shrinking the rate below the guarantees scales them down
+	a.SetLimit(400)
+	if got1, got2, got3 := s1.Limit(), s2.Limit(), s3.Limit(); got1 != 250 || got2 != 50 || got3 != 100 {
+		t.Errorf("shares = %d, %d, %d, want 250, 50, 100", got1, got2, got3)
+	}
+
+	// A stream without a minimum can still register, though there's nothing left for it
+	s4 := a.Register()
+	if s4 == nil {
+		t.Fatal("Register returned nil with the rate below the guarantees")
+	}
+	if got := s4.Limit(); got != 0 {
+		t.Errorf("share without a minimum = %d, want 0", got)
+	}
+	a.SetLimit(1200) // 400 excess, shared 3:1:0:1
+	if got := s4.Limit(); got != 80 {
+		t.Errorf("share without a minimum = %d once the rate is raised, want 80", got)
+	}
+}
+
+func TestAllocator_Wait(t *testing.T) {
+	c := &stepClock{now: time.Unix(0, 0)}
+	a := NewAllocator(1000, 0, WithClock(c))
+	s1 := a.Register()
+	_ = a.Register()
+
+	// Each stream gets 500 B/s, so 1000 bytes takes 2s
+	start := c.Now()
+	err := s1.Wait(context.Background(), 1000)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if got := c.Now().Sub(start); got != 2*time.Second {
+		t.Errorf("Wait took %v, want 2s", got)
+	}
+}
+
+func TestAllocator_WorkConserving(t *testing.T) {
+	c := &stepClock{now: time.Unix(0, 0)}
+	a := NewAllocator(1200, 0, WithClock(c))
+	s1, _ := a.RegisterShare(300, 1)
+	s2, _ := a.RegisterShare(300, 1)
+	a.SetWorkConserving(true)
+
+	ctx := context.Background()
+	shares := func() (int64, int64) { return s1.Limit(), s2.Limit() }
+
+	// A lone active stream gets the whole rate, including s2's minimum
+	_ = s1.Wait(ctx, 0)
+	if got1, got2 := shares(); got1 != 1200 || got2 != 0 {
+		t.Errorf("s1 active: shares = %d, %d, want 1200, 0", got1, got2)
+	}
+
+	// Resuming takes the share back
+	_ = s2.Wait(ctx, 0)
+	if got1, got2 := shares(); got1 != 600 || got2 != 600 {
+		t.Errorf("both active: shares = %d, %d, want 600, 600", got1, got2)
+	}
+
+	// Once s1 has been idle for a while, s2 gets everything
+	c.NewTimer(time.Second)
+	_ = s2.Wait(ctx, 0)
+	if got1, got2 := shares(); got1 != 0 || got2 != 1200 {
+		t.Errorf("s2 active: shares = %d, %d, want 0, 1200", got1, got2)
+	}
+
+	a.SetWorkConserving(false)
+	if got1, got2 := shares(); got1 != 600 || got2 != 600 {
+		t.Errorf("disabled: shares = %d, %d, want 600, 600", got1, got2)
+	}
+}
@@ -0,0 +1,94 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestAtomicTokenBucket(t *testing.T) {
+	clock := &stepClock{now: time.Unix(0, 0)}
+	lim := NewAtomicTokenBucket(1024, 1024, WithClock(clock))
+
+	// Burst is available immediately
+	_ = lim.Wait(context.Background(), 1024)
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 0 {
+		t.Errorf("burst took %s, want 0", elapsed)
+	}
+
+	// n larger than the burst puts the bucket into debt, rather than failing
+	_ = lim.Wait(context.Background(), 4096)
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 4*time.Second {
+		t.Errorf("4KB at 1KB/sec took %s, want 4s", elapsed)
+	}
+}
+
+func TestAtomicTokenBucketConcurrent(t *testing.T) {
+	// Time stands still, so the bucket's debt is exactly the bytes charged.
+	lim := NewAtomicTokenBucket(10_000_000, 0, WithClock(frozenClock{time.Unix(0, 0)}))
+
+	// Every byte is charged exactly once, however the CAS races resolve.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10_000; j++ {
+				_ = lim.Wait(context.Background(), 125)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if delay, _ := lim.Reserve(0); delay != time.Second {
+		t.Errorf("10MB at 10MB/sec left %s of debt, want 1s", delay)
+	}
+}
+
+// frozenClock is a Clock which never advances, and whose timers fire immediately.
+type frozenClock struct {
+	now time.Time
+}
+
+func (c frozenClock) Now() time.Time {
+	return c.now
+}
+
+func (c frozenClock) NewTimer(time.Duration) Timer {
+	ch := make(firedTimer, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestAtomicTokenBucketCancelRefunds(t *testing.T) {
+	lim := NewAtomicTokenBucket(1024, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := lim.Wait(ctx, 10*1024); err == nil {
+		t.Fatal("expected wait to be cancelled")
+	}
+
+	delay, _ := lim.Reserve(10)
+	if delay > 100*time.Millisecond {
+		t.Errorf("reservation after cancel delayed %s, expected refund", delay)
+	}
+}
+
+// BenchmarkContention compares limiters shared by many goroutines, at a rate high enough never to delay.
+func BenchmarkContention(b *testing.B) {
+	for name, lim := range map[string]Limiter{
+		"RateLimiterAdapter": NewRateLimiterAdapter(rate.NewLimiter(1<<40, 1<<30)),
+		"TokenBucket":        NewTokenBucket(1<<40, 1<<40),
+		"AtomicTokenBucket":  NewAtomicTokenBucket(1<<40, 1<<40),
+		"ShardedLimiter":     NewShardedLimiter(1<<40, 1<<40, 8, time.Second),
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.SetParallelism(8)
+			benchmarkSmallWaits(b, lim)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package throughput
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BackoffTransport is an http.RoundTripper which throttles request and response bodies with an AIMDLimiter, and
+// turns the server's rate limiting hints into client-side pacing.
+//
+// When the server responds 429 Too Many Requests or 503 Service Unavailable, the limiter's rate is halved (then
+// ramps back up as the AIMDLimiter allows), and if the response has a Retry-After header, subsequent requests are
+// held until it has passed. Requests are also held when the server reports through RateLimit-Remaining and
+// RateLimit-Reset headers (or their X-RateLimit- equivalents) that no requests remain.
+//
+// Responses are returned to the caller as-is: requests are not retried.
+type BackoffTransport struct {
+	next http.RoundTripper
+	lim  *AIMDLimiter
+	acct httpAccounting
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+// NewBackoffTransport returns a transport sending requests via next, throttled by lim.
+// If next is nil, http.DefaultTransport is used. Requests are held using lim's clock.
+func NewBackoffTransport(next http.RoundTripper, lim *AIMDLimiter, opts ...HTTPOption) *BackoffTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &BackoffTransport{next: next, lim: lim, acct: newHTTPAccounting(opts)}
+}
+
+func (t *BackoffTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	err := t.lim.w.sleep(ctx, t.PausedUntil().Sub(t.lim.w.now()))
+	if err != nil {
+		closeRequestBody(req)
+		return nil, err
+	}
+
+	err = t.acct.chargeHeaders(ctx, t.lim, requestLineSize(req), req.Header)
+	if err != nil {
+		closeRequestBody(req)
+		return nil, err
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(ctx)
+		req.Body = &limitedBody{Reader: NewReader(ctx, req.Body, t.lim), Closer: req.Body}
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := t.lim.w.now()
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+		t.lim.ReportFailure()
+		if until, ok := retryAfter(res.Header, now); ok {
+			t.pause(until)
+		}
+	} else if until, ok := rateLimitReset(res.Header, now); ok {
+		t.pause(until)
+	}
+
+	if t.acct.exempt(res.ContentLength) {
+		return res, nil
+	}
+	err = t.acct.chargeHeaders(ctx, t.lim, statusLineSize, res.Header)
+	if err != nil {
+		_ = res.Body.Close()
+		return nil, err
+	}
+	res.Body = &limitedBody{Reader: NewReader(ctx, res.Body, t.lim), Closer: res.Body}
+	return res, nil
+}
+
+// closeRequestBody closes req's body, as a RoundTripper must even when it returns an error.
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
+
+// PausedUntil returns the time until which requests are being held, which may be in the past.
+func (t *BackoffTransport) PausedUntil() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pausedUntil
+}
+
+func (t *BackoffTransport) pause(until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until.After(t.pausedUntil) {
+		t.pausedUntil = until
+	}
+}
+
+// retryAfter parses a Retry-After header, which is either a number of seconds or an HTTP date.
+func retryAfter(h http.Header, now time.Time) (time.Time, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return time.Time{}, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return now.Add(time.Duration(secs) * time.Second), true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// rateLimitReset returns when requests may resume, if RateLimit headers report that none remain.
+//
+// The reset is usually a number of seconds, but some servers send a Unix timestamp instead, so large values are
+// treated as timestamps.
+func rateLimitReset(h http.Header, now time.Time) (time.Time, bool) {
+	for _, prefix := range []string{"RateLimit-", "X-RateLimit-"} {
+		remaining, err := strconv.ParseInt(h.Get(prefix+"Remaining"), 10, 64)
+		if err != nil || remaining > 0 {
+			continue
+		}
+		reset, err := strconv.ParseInt(h.Get(prefix+"Reset"), 10, 64)
+		if err != nil || reset < 0 {
+			continue
+		}
+
+		if reset > 1e9 {
+			return time.Unix(reset, 0), true
+		}
+		return now.Add(time.Duration(reset) * time.Second), true
+	}
+	return time.Time{}, false
+}
+
+var _ http.RoundTripper = (*BackoffTransport)(nil)
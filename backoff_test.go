@@ -0,0 +1,103 @@
+package throughput
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoffTransport(t *testing.T) {
+	var status atomic.Int64
+	status.Store(http.StatusOK)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		switch status.Load() {
+		case http.StatusTooManyRequests:
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case http.StatusOK:
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.Header().Set("RateLimit-Reset", "5")
+			_, _ = w.Write([]byte("hello"))
+		}
+	}))
+	defer srv.Close()
+
+	c := &stepClock{now: time.Unix(0, 0)}
+	tb := NewTokenBucket(1<<20, 1<<20, WithClock(c))
+	lim := NewAIMDLimiter(tb, 1024, 1<<20, 1024, time.Second, WithClock(c))
+	client := &http.Client{Transport: NewBackoffTransport(nil, lim)}
+
+	do := func() {
+		t.Helper()
+		res, err := client.Post(srv.URL, "text/plain", strings.NewReader("body"))
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}
+
+	status.Store(http.StatusTooManyRequests)
+	do()
+	if got := lim.Limit(); got != 1<<19 {
+		t.Errorf("after 429, Limit() = %d, want halved", got)
+	}
+
+	// The next request is held until Retry-After has passed
+	start := c.Now()
+	status.Store(http.StatusOK)
+	do()
+	if got := c.Now().Sub(start); got != 2*time.Second {
+		t.Errorf("request after 429 held for %v, want 2s", got)
+	}
+
+	// No requests remaining holds the next request until the reset
+	start = c.Now()
+	do()
+	if got := c.Now().Sub(start); got != 5*time.Second {
+		t.Errorf("request after exhausting RateLimit held for %v, want 5s", got)
+	}
+}
+
+func TestBackoffTransport_ClosesBodyOnError(t *testing.T) {
+	tr := NewBackoffTransport(nil, NewAIMDLimiter(NewTokenBucket(1024, 1024), 1024, 1024, 1024, time.Second))
+	tr.pause(time.Now().Add(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	body := &closeRecorder{Reader: strings.NewReader("body")}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "http://example.com", body)
+
+	_, err := tr.RoundTrip(req)
+	if err == nil {
+		t.Fatal("expected error when the request is cancelled whilst held")
+	}
+	if !body.closed.Load() {
+		t.Error("request body wasn't closed")
+	}
+}
+
+func TestBackoffTransport_HeaderBytes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	c := &stepClock{now: time.Unix(0, 0)}
+	tb := NewTokenBucket(1<<20, 1<<20, WithClock(c))
+	lim := NewAIMDLimiter(tb, 1024, 1<<20, 1024, time.Second, WithClock(c))
+	client := &http.Client{Transport: NewBackoffTransport(nil, lim, WithHeaderBytes())}
+
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	_ = res.Body.Close()
+	if charged := 1<<20 - tb.Tokens(); charged <= 0 {
+		t.Errorf("charged %v bytes with WithHeaderBytes, want the headers charged", charged)
+	}
+}
@@ -0,0 +1,71 @@
+package throughput
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// BatchingLimiter wraps a limiter, accumulating small waits locally and charging them to the wrapped limiter in
+// batches. For tiny reads at high rates, e.g. tens of bytes at a time, calling Wait on every read can dominate
+// the cost of a transfer -- especially with a limiter shared between many goroutines.
+//
+// Bytes are let through uncharged until threshold bytes have accumulated, or interval has passed since the last
+// batch, so a stream may run up to threshold bytes ahead of its limit.
+type BatchingLimiter struct {
+	lim       Limiter
+	threshold int64
+	interval  time.Duration
+	w         waiter
+
+	pending atomic.Int64
+	last    atomic.Int64 // unix nanos of the last batch, if interval > 0
+}
+
+// NewBatchingLimiter returns a limiter which charges lim in batches of threshold bytes. A non-zero interval also
+// charges whatever has accumulated once interval has passed since the last batch, bounding how stale a slow
+// stream's charges can become.
+func NewBatchingLimiter(lim Limiter, threshold int, interval time.Duration, opts ...Option) *BatchingLimiter {
+	b := &BatchingLimiter{lim: lim, threshold: int64(max(threshold, 1)), interval: interval, w: newWaiter(opts)}
+	b.last.Store(b.w.now().UnixNano())
+	return b
+}
+
+func (b *BatchingLimiter) Wait(ctx context.Context, n int) error {
+	pending := b.pending.Add(int64(n))
+	if pending < b.threshold {
+		if b.interval <= 0 {
+			return nil
+		}
+		if now := b.w.now().UnixNano(); now-b.last.Load() < int64(b.interval) {
+			return nil
+		}
+	}
+	return b.Flush(ctx)
+}
+
+// Flush charges any accumulated bytes to the wrapped limiter, e.g. at the end of a transfer.
+// If waiting fails, the bytes are returned to the batch.
+func (b *BatchingLimiter) Flush(ctx context.Context) error {
+	pending := b.pending.Swap(0)
+	if pending <= 0 {
+		return nil
+	}
+	if b.interval > 0 {
+		b.last.Store(b.w.now().UnixNano())
+	}
+
+	err := b.lim.Wait(ctx, int(pending))
+	if err != nil {
+		b.pending.Add(pending)
+		return err
+	}
+	return nil
+}
+
+// Pending returns the number of bytes accumulated but not yet charged.
+func (b *BatchingLimiter) Pending() int64 {
+	return b.pending.Load()
+}
+
+var _ Limiter = (*BatchingLimiter)(nil)
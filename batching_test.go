@@ -0,0 +1,70 @@
+package throughput
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBatchingLimiter(t *testing.T) {
+	var inner countingLimiter
+	lim := NewBatchingLimiter(&inner, 100, 0)
+
+	for i := 0; i < 9; i++ {
+		_ = lim.Wait(context.Background(), 10)
+	}
+	if inner.n.Load() != 0 || lim.Pending() != 90 {
+		t.Fatalf("after 90 bytes, charged %d and pending %d; want 0 and 90", inner.n.Load(), lim.Pending())
+	}
+
+	_ = lim.Wait(context.Background(), 10)
+	_ = lim.Wait(context.Background(), 5)
+	if err := lim.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %s", err)
+	}
+	if inner.n.Load() != 105 || lim.Pending() != 0 {
+		t.Errorf("after flush, charged %d and pending %d; want 105 and 0", inner.n.Load(), lim.Pending())
+	}
+}
+
+func TestBatchingLimiterInterval(t *testing.T) {
+	clock := NewVirtualClock(time.Unix(0, 0))
+	var inner countingLimiter
+	lim := NewBatchingLimiter(&inner, 1000, 100*time.Millisecond, WithClock(clock))
+
+	_ = lim.Wait(context.Background(), 10)
+	clock.Advance(100 * time.Millisecond)
+	_ = lim.Wait(context.Background(), 10)
+	if inner.n.Load() != 20 {
+		t.Errorf("after interval elapsed, charged %d, want 20", inner.n.Load())
+	}
+}
+
+func TestBatchingLimiterRate(t *testing.T) {
+	clock := NewVirtualClock(time.Unix(0, 0))
+	lim := NewBatchingLimiter(NewTokenBucket(1000, 0, WithClock(clock)), 100, 0)
+
+	for i := 0; i < 200; i++ {
+		_ = lim.Wait(context.Background(), 10)
+	}
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 2*time.Second {
+		t.Errorf("2000 bytes at 1000 bytes/sec took %s, want 2s", elapsed)
+	}
+}
+
+func BenchmarkBatchingLimiter(b *testing.B) {
+	b.Run("Unbatched", func(b *testing.B) {
+		benchmarkSmallWaits(b, NewTokenBucket(1<<40, 1<<40))
+	})
+	b.Run("Batched", func(b *testing.B) {
+		benchmarkSmallWaits(b, NewBatchingLimiter(NewTokenBucket(1<<40, 1<<40), 64*1024, 0))
+	})
+}
+
+func benchmarkSmallWaits(b *testing.B, lim Limiter) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = lim.Wait(context.Background(), 16)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package throughput
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// BoostLimiter implements ISP-style two-phase shaping: a stream is allowed a high rate for its first N bytes
+// and/or first D of time, then falls back to a sustained rate.
+//
+// A BoostLimiter holds per-stream state, so a new one should be created for each stream. The boost and
+// sustained limiters it delegates to may themselves be shared.
+type BoostLimiter struct {
+	boost, sustained Limiter
+	bytes            int64
+	dur              time.Duration
+
+	charged atomic.Int64
+	start   atomic.Pointer[time.Time]
+	w       waiter
+}
+
+// NewBoostLimiter returns a limiter that delegates to boost for the first bytes of the stream, or until dur has
+// elapsed since the first call to Wait -- whichever comes first -- then to sustained.
+// A zero bytes or dur disables that condition.
+func NewBoostLimiter(boost, sustained Limiter, bytes int64, dur time.Duration, opts ...Option) *BoostLimiter {
+	return &BoostLimiter{
+		boost:     boost,
+		sustained: sustained,
+		bytes:     bytes,
+		dur:       dur,
+		w:         newWaiter(opts),
+	}
+}
+
+// Reset starts a new boost period, e.g. when the stream is reused for a new transfer.
+func (b *BoostLimiter) Reset() {
+	b.charged.Store(0)
+	b.start.Store(nil)
+}
+
+// Boosting reports whether the boost period is still active.
+func (b *BoostLimiter) Boosting() bool {
+	if b.bytes > 0 && b.charged.Load() >= b.bytes {
+		return false
+	}
+	if b.dur > 0 {
+		if start := b.start.Load(); start != nil && b.w.now().Sub(*start) >= b.dur {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *BoostLimiter) Wait(ctx context.Context, n int) error {
+	if b.dur > 0 && b.start.Load() == nil {
+		now := b.w.now()
+		b.start.CompareAndSwap(nil, &now)
+	}
+
+	if !b.Boosting() {
+		return b.sustained.Wait(ctx, n)
+	}
+
+	boosted := n
+	if b.bytes > 0 {
+		// Split n across the phase boundary, so the boost allowance is exact.
+		over := b.charged.Add(int64(n)) - b.bytes
+		if over > 0 {
+			boosted = n - int(min(over, int64(n)))
+		}
+	}
+
+	err := b.boost.Wait(ctx, boosted)
+	if err != nil || boosted == n {
+		return err
+	}
+	return b.sustained.Wait(ctx, n-boosted)
+}
+
+var _ Limiter = (*BoostLimiter)(nil)
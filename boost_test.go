@@ -0,0 +1,42 @@
+package throughput
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBoostLimiterBytes(t *testing.T) {
+	var boost, sustained countingLimiter
+	lim := NewBoostLimiter(&boost, &sustained, 100, 0)
+
+	for i := 0; i < 4; i++ {
+		_ = lim.Wait(context.Background(), 30)
+	}
+
+	if got := boost.n.Load(); got != 100 {
+		t.Errorf("boost charged %d bytes, want 100", got)
+	}
+	if got := sustained.n.Load(); got != 20 {
+		t.Errorf("sustained charged %d bytes, want 20", got)
+	}
+
+	lim.Reset()
+	if !lim.Boosting() {
+		t.Error("expected boost to be active after Reset")
+	}
+}
+
+func TestBoostLimiterDuration(t *testing.T) {
+	var boost, sustained countingLimiter
+	clock := &stepClock{now: time.Unix(0, 0)}
+	lim := NewBoostLimiter(&boost, &sustained, 0, 50*time.Millisecond, WithClock(clock))
+
+	_ = lim.Wait(context.Background(), 10)
+	clock.NewTimer(60 * time.Millisecond)
+	_ = lim.Wait(context.Background(), 10)
+
+	if boost.n.Load() != 10 || sustained.n.Load() != 10 {
+		t.Errorf("boost charged %d, sustained charged %d, want 10 each", boost.n.Load(), sustained.n.Load())
+	}
+}
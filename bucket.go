@@ -0,0 +1,68 @@
+package throughput
+
+import (
+	"math"
+	"time"
+)
+
+// bucket is the arithmetic of a token bucket, without any locking or waiting.
+//
+// Tokens may go negative, which represents debt: bytes that have been charged but not yet paid for with time.
+// This allows n to be unbounded, rather than capped at the burst capacity like rate.Limiter.
+type bucket struct {
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(bytesPerSec, burst int64, now time.Time) bucket {
+	return bucket{
+		rate:   float64(bytesPerSec),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+// advance refills the bucket with tokens accrued since it was last advanced.
+func (b *bucket) advance(now time.Time) {
+	if !now.After(b.last) {
+		return
+	}
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// take removes n tokens, possibly leaving the bucket in debt.
+func (b *bucket) take(n float64) {
+	b.tokens -= n
+}
+
+// refund returns n tokens, e.g. when a wait is cancelled.
+func (b *bucket) refund(n float64) {
+	b.tokens = min(b.burst, b.tokens+n)
+}
+
+// delayUntil returns how long until the bucket holds at least n tokens.
+func (b *bucket) delayUntil(n float64) time.Duration {
+	if b.tokens >= n {
+		return 0
+	}
+	if b.rate <= 0 {
+		return math.MaxInt64
+	}
+	return secondsToDuration((n - b.tokens) / b.rate)
+}
+
+// debtDelay returns how long until the bucket is out of debt.
+func (b *bucket) debtDelay() time.Duration {
+	return b.delayUntil(0)
+}
+
+func secondsToDuration(s float64) time.Duration {
+	if s >= math.MaxInt64/float64(time.Second) {
+		return math.MaxInt64
+	}
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,117 @@
+package throughput
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// BufferedWriter is like bufio.Writer, but writes its buffer to dst in chunks paced by a limiter. Applications
+// doing many tiny writes charge the limiter once per chunk rather than once per write, and dst sees efficient,
+// evenly-spaced segments.
+//
+// A BufferedWriter is safe for concurrent use. Once a write to dst or a wait on the limiter fails, the error is
+// returned by all subsequent calls.
+type BufferedWriter struct {
+	ctx      context.Context
+	dst      io.Writer
+	lim      Limiter
+	interval time.Duration
+
+	mu    sync.Mutex
+	buf   []byte
+	err   error
+	timer *time.Timer // flushes after interval, whilst bytes are buffered
+}
+
+// NewBufferedWriter returns a writer which buffers up to size bytes, writing them to dst and charging lim once
+// the buffer fills. A size of zero sizes the buffer to what lim allows per interval, if lim is an
+// AdjustableLimiter.
+//
+// If interval is positive, buffered bytes are also flushed once they have waited that long, so a trickle of
+// writes isn't held indefinitely. The context is used to unblock writes when rate-limited.
+func NewBufferedWriter(ctx context.Context, dst io.Writer, lim Limiter, size int, interval time.Duration) *BufferedWriter {
+	if size <= 0 {
+		size = 4096
+		if a, ok := lim.(AdjustableLimiter); ok && interval > 0 {
+			size = int(min(max(a.Limit()*int64(interval)/int64(time.Second), 512), 1<<20))
+		}
+	}
+	return &BufferedWriter{
+		ctx:      ctx,
+		dst:      dst,
+		lim:      lim,
+		interval: interval,
+		buf:      make([]byte, 0, size),
+	}
+}
+
+func (b *BufferedWriter) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var written int
+	for len(p) > 0 {
+		if b.err != nil {
+			return written, b.err
+		}
+
+		n := copy(b.buf[len(b.buf):cap(b.buf)], p)
+		b.buf = b.buf[:len(b.buf)+n]
+		p = p[n:]
+		written += n
+
+		if len(b.buf) == cap(b.buf) {
+			b.flush()
+		}
+	}
+
+	if len(b.buf) > 0 && b.timer == nil && b.interval > 0 {
+		b.timer = time.AfterFunc(b.interval, func() { _ = b.Flush() })
+	}
+	return written, b.err
+}
+
+// Flush writes any buffered bytes to dst, waiting on the limiter.
+func (b *BufferedWriter) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flush()
+	return b.err
+}
+
+// flush implements Flush. Must be called with mu held.
+func (b *BufferedWriter) flush() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if b.err != nil || len(b.buf) == 0 {
+		return
+	}
+
+	n, err := b.dst.Write(b.buf)
+	if err == nil && n < len(b.buf) {
+		err = io.ErrShortWrite
+	}
+	if n > 0 {
+		if werr := b.lim.Wait(b.ctx, n); err == nil {
+			err = werr
+		}
+	}
+	b.buf = b.buf[:copy(b.buf, b.buf[n:])]
+	b.err = err
+}
+
+// Buffered returns the number of bytes buffered but not yet written to dst.
+func (b *BufferedWriter) Buffered() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.buf)
+}
+
+// Close flushes any buffered bytes. It doesn't close dst.
+func (b *BufferedWriter) Close() error {
+	return b.Flush()
+}
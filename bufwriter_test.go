@@ -0,0 +1,84 @@
+package throughput
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBufferedWriter(t *testing.T) {
+	var dst chunkRecorder
+	var lim countingLimiter
+	w := NewBufferedWriter(context.Background(), &dst, &lim, 100, 0)
+
+	for i := 0; i < 250; i++ {
+		_, _ = w.Write([]byte{byte(i)})
+	}
+	if got := dst.sizes(); len(got) != 2 || got[0] != 100 || got[1] != 100 {
+		t.Errorf("chunks written %v, want [100 100]", got)
+	}
+	if n := lim.n.Load(); n != 200 {
+		t.Errorf("limiter charged %d bytes, want 200", n)
+	}
+	if n := w.Buffered(); n != 50 {
+		t.Errorf("%d bytes buffered, want 50", n)
+	}
+
+	_ = w.Close()
+	if n := lim.n.Load(); n != 250 {
+		t.Errorf("limiter charged %d bytes after Close, want 250", n)
+	}
+	want := make([]byte, 250)
+	for i := range want {
+		want[i] = byte(i)
+	}
+	if !bytes.Equal(dst.bytes(), want) {
+		t.Error("bytes written out of order")
+	}
+}
+
+func TestBufferedWriterInterval(t *testing.T) {
+	var dst chunkRecorder
+	w := NewBufferedWriter(context.Background(), &dst, NewTokenBucket(1<<20, 1<<20), 0, 20*time.Millisecond)
+	_, _ = w.Write([]byte("hello"))
+
+	// A trickle of writes is flushed on schedule
+	deadline := time.Now().Add(time.Second)
+	for w.Buffered() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := string(dst.bytes()); got != "hello" {
+		t.Errorf("%q written after the interval, want hello", got)
+	}
+}
+
+// chunkRecorder records the writes made to it.
+type chunkRecorder struct {
+	mu     sync.Mutex
+	chunks [][]byte
+}
+
+func (c *chunkRecorder) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.chunks = append(c.chunks, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (c *chunkRecorder) sizes() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var sizes []int
+	for _, chunk := range c.chunks {
+		sizes = append(sizes, len(chunk))
+	}
+	return sizes
+}
+
+func (c *chunkRecorder) bytes() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return bytes.Join(c.chunks, nil)
+}
@@ -0,0 +1,73 @@
+package throughput
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// CappedGroup holds transfers to a per-member rate, and all of them together to a global rate -- wiring up the
+// limiters PerConnAndGlobal composes for each reader or writer joining the group.
+type CappedGroup struct {
+	global  *TokenBucket
+	newLim  func() Limiter
+	members atomic.Int64
+}
+
+// NewCappedGroup returns a group limited to globalRate in total, and memberRate per member, in bytes per second.
+func NewCappedGroup(globalRate, memberRate int64) *CappedGroup {
+	global := NewTokenBucket(globalRate, globalRate)
+	return &CappedGroup{global: global, newLim: PerConnAndGlobal(memberRate, global)}
+}
+
+// NewMemberReader returns src limited as a member of the group, until it's closed.
+func (g *CappedGroup) NewMemberReader(ctx context.Context, src io.Reader, opts ...StreamOption) *MemberReader {
+	g.members.Add(1)
+	return &MemberReader{Reader: NewReader(ctx, src, g.newLim(), opts...), g: g}
+}
+
+// NewMemberWriter returns dst limited as a member of the group, until it's closed.
+func (g *CappedGroup) NewMemberWriter(ctx context.Context, dst io.Writer, opts ...StreamOption) *MemberWriter {
+	g.members.Add(1)
+	return &MemberWriter{Writer: NewWriter(ctx, dst, g.newLim(), opts...), g: g}
+}
+
+// Members returns the number of readers and writers in the group which have not been closed.
+func (g *CappedGroup) Members() int {
+	return int(g.members.Load())
+}
+
+// SetLimit changes the group's global rate to bytesPerSec. Member rates are unchanged.
+func (g *CappedGroup) SetLimit(bytesPerSec int64) {
+	g.global.SetLimit(bytesPerSec)
+}
+
+// MemberReader is a Reader in a CappedGroup.
+type MemberReader struct {
+	*Reader
+	g      *CappedGroup
+	closed atomic.Bool
+}
+
+// Close leaves the group, then closes the Reader.
+func (r *MemberReader) Close() error {
+	if !r.closed.Swap(true) {
+		r.g.members.Add(-1)
+	}
+	return r.Reader.Close()
+}
+
+// MemberWriter is a Writer in a CappedGroup.
+type MemberWriter struct {
+	*Writer
+	g      *CappedGroup
+	closed atomic.Bool
+}
+
+// Close leaves the group, then closes the Writer.
+func (w *MemberWriter) Close() error {
+	if !w.closed.Swap(true) {
+		w.g.members.Add(-1)
+	}
+	return w.Writer.Close()
+}
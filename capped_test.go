@@ -0,0 +1,35 @@
+package throughput
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCappedGroup(t *testing.T) {
+	g := NewCappedGroup(100_000, 1000)
+	r := g.NewMemberReader(context.Background(), bytes.NewReader(make([]byte, 1200)))
+	w := g.NewMemberWriter(context.Background(), io.Discard)
+	if g.Members() != 2 {
+		t.Errorf("got %d members, want 2", g.Members())
+	}
+
+	// Each member's rate applies, despite the global capacity: 200ms over the burst to read, and again to write
+	start := time.Now()
+	if _, err := io.Copy(w, r); err != nil {
+		t.Fatal(err)
+	}
+	err := verifyWithSlop(time.Since(start), 400*time.Millisecond, 100*time.Millisecond)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	_ = r.Close()
+	_ = r.Close()
+	_ = w.Close()
+	if g.Members() != 0 {
+		t.Errorf("got %d members after close, want 0", g.Members())
+	}
+}
@@ -0,0 +1,146 @@
+package throughput
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CgroupIOLimit is the bandwidth limit of a block device, from a cgroup v2 io.max file.
+type CgroupIOLimit struct {
+	// Device is the device's "major:minor" numbers.
+	Device string
+
+	// ReadBPS and WriteBPS are the limits in bytes per second, or zero if unlimited.
+	ReadBPS, WriteBPS int64
+}
+
+// CgroupIOLimits returns the IO bandwidth limits which apply to the current process, from the io.max files of
+// its cgroup v2 and the cgroup's ancestors. Where several cgroups limit a device, the most restrictive limits are
+// returned. It is only supported on Linux.
+//
+// cgroup v2 has no equivalent controller for network bandwidth, which is usually shaped outside the container.
+func CgroupIOLimits() ([]CgroupIOLimit, error) {
+	return cgroupIOLimits()
+}
+
+// TrackCgroupIO keeps lim's rate at the current process's cgroup v2 IO limit for writes (or reads, if write is
+// false), bounded by ceiling, so the process never paces faster than its container allows. If there are limits for
+// several devices, the most restrictive is used. A ceiling of zero means the rate is taken from the cgroup alone,
+// and whilst the cgroup is unlimited, lim is left at the rate it had when tracking began.
+//
+// The cgroup's limits are checked every interval, so the rate follows changes. TrackCgroupIO blocks until ctx is
+// done, or returns an error if the limits can't be read on the first check.
+func TrackCgroupIO(ctx context.Context, lim AdjustableLimiter, ceiling int64, write bool, interval time.Duration) error {
+	return trackRate(ctx, lim, interval, cgroupRate(CgroupIOLimits, lim.Limit(), ceiling, write))
+}
+
+// cgroupRate returns a func for trackRate, which reads limits and bounds them by ceiling. If they're unlimited,
+// it returns initial, so a rate which had been lowered by the cgroup is restored when the limit is lifted.
+func cgroupRate(limits func() ([]CgroupIOLimit, error), initial, ceiling int64, write bool) func() (int64, error) {
+	return func() (int64, error) {
+		l, err := limits()
+		if err != nil {
+			return 0, err
+		}
+		bytesPerSec := boundByCgroup(l, ceiling, write)
+		if bytesPerSec == 0 {
+			return initial, nil
+		}
+		return bytesPerSec, nil
+	}
+}
+
+// boundByCgroup returns the lesser of ceiling and the most restrictive limit in limits, where zero is unlimited.
+func boundByCgroup(limits []CgroupIOLimit, ceiling int64, write bool) int64 {
+	bound := ceiling
+	for _, l := range limits {
+		bps := l.ReadBPS
+		if write {
+			bps = l.WriteBPS
+		}
+		if bps > 0 && (bound == 0 || bps < bound) {
+			bound = bps
+		}
+	}
+	return bound
+}
+
+// parseCgroupPath finds the cgroup v2 path in the format of /proc/self/cgroup.
+func parseCgroupPath(r io.Reader) (string, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		path, ok := strings.CutPrefix(s.Text(), "0::")
+		if ok {
+			return path, nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("not in a cgroup v2")
+}
+
+// parseIOMax parses an io.max file, e.g. "8:0 rbps=max wbps=1048576 riops=max wiops=max".
+func parseIOMax(r io.Reader) ([]CgroupIOLimit, error) {
+	var limits []CgroupIOLimit
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		l := CgroupIOLimit{Device: fields[0]}
+		for _, f := range fields[1:] {
+			key, value, _ := strings.Cut(f, "=")
+			var dst *int64
+			switch key {
+			case "rbps":
+				dst = &l.ReadBPS
+			case "wbps":
+				dst = &l.WriteBPS
+			default:
+				continue
+			}
+			if value == "max" {
+				continue
+			}
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing io.max: %q: %w", f, err)
+			}
+			*dst = n
+		}
+		limits = append(limits, l)
+	}
+	return limits, s.Err()
+}
+
+// mergeIOLimits combines the limits of nested cgroups, keeping the most restrictive limits for each device.
+func mergeIOLimits(limits []CgroupIOLimit) []CgroupIOLimit {
+	var merged []CgroupIOLimit
+	index := make(map[string]int)
+	restrict := func(a, b int64) int64 {
+		if a == 0 || (b > 0 && b < a) {
+			return b
+		}
+		return a
+	}
+
+	for _, l := range limits {
+		i, ok := index[l.Device]
+		if !ok {
+			index[l.Device] = len(merged)
+			merged = append(merged, l)
+			continue
+		}
+		merged[i].ReadBPS = restrict(merged[i].ReadBPS, l.ReadBPS)
+		merged[i].WriteBPS = restrict(merged[i].WriteBPS, l.WriteBPS)
+	}
+	return merged
+}
@@ -0,0 +1,42 @@
+package throughput
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+)
+
+func cgroupIOLimits() ([]CgroupIOLimit, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return nil, fmt.Errorf("reading cgroup: %w", err)
+	}
+	cgroup, err := parseCgroupPath(f)
+	_ = f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading cgroup: %w", err)
+	}
+
+	// Limits of every ancestor apply too. The root cgroup has no io.max.
+	var limits []CgroupIOLimit
+	for dir := path.Clean(cgroup); dir != "/" && dir != "."; dir = path.Dir(dir) {
+		f, err := os.Open(path.Join("/sys/fs/cgroup", dir, "io.max"))
+		if errors.Is(err, fs.ErrNotExist) {
+			// The io controller isn't enabled for this cgroup
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading io.max: %w", err)
+		}
+
+		l, err := parseIOMax(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+		limits = append(limits, l...)
+	}
+	return mergeIOLimits(limits), nil
+}
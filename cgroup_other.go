@@ -0,0 +1,12 @@
+//go:build !linux
+
+package throughput
+
+import (
+	"errors"
+	"fmt"
+)
+
+func cgroupIOLimits() ([]CgroupIOLimit, error) {
+	return nil, fmt.Errorf("throughput: cgroups: %w", errors.ErrUnsupported)
+}
@@ -0,0 +1,69 @@
+package throughput
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseIOMax(t *testing.T) {
+	ioMax := "8:0 rbps=max wbps=1048576 riops=max wiops=max\n8:16 rbps=2097152 wbps=max riops=100 wiops=max\n"
+	limits, err := parseIOMax(strings.NewReader(ioMax))
+	if err != nil {
+		t.Fatalf("parseIOMax: %v", err)
+	}
+
+	want := []CgroupIOLimit{
+		{Device: "8:0", WriteBPS: 1048576},
+		{Device: "8:16", ReadBPS: 2097152},
+	}
+	if len(limits) != len(want) || limits[0] != want[0] || limits[1] != want[1] {
+		t.Errorf("parseIOMax() = %+v, want %+v", limits, want)
+	}
+
+	if got := boundByCgroup(limits, 0, true); got != 1048576 {
+		t.Errorf("write bound = %d, want 1048576", got)
+	}
+	if got := boundByCgroup(limits, 1000, false); got != 1000 {
+		t.Errorf("read bound with ceiling = %d, want 1000", got)
+	}
+	if got := boundByCgroup(nil, 0, false); got != 0 {
+		t.Errorf("bound without limits = %d, want 0", got)
+	}
+}
+
+func TestMergeIOLimits(t *testing.T) {
+	merged := mergeIOLimits([]CgroupIOLimit{
+		{Device: "8:0", WriteBPS: 1000},
+		{Device: "8:0", ReadBPS: 500, WriteBPS: 2000},
+		{Device: "8:16", ReadBPS: 10},
+	})
+	want := []CgroupIOLimit{
+		{Device: "8:0", ReadBPS: 500, WriteBPS: 1000},
+		{Device: "8:16", ReadBPS: 10},
+	}
+	if len(merged) != len(want) || merged[0] != want[0] || merged[1] != want[1] {
+		t.Errorf("mergeIOLimits() = %+v, want %+v", merged, want)
+	}
+}
+
+func TestParseCgroupPath(t *testing.T) {
+	path, err := parseCgroupPath(strings.NewReader("1:cpu:/\n0::/system.slice/app.service\n"))
+	if err != nil || path != "/system.slice/app.service" {
+		t.Errorf("parseCgroupPath() = %q, %v", path, err)
+	}
+}
+
+func TestCgroupRateRestoresInitial(t *testing.T) {
+	limits := []CgroupIOLimit{{Device: "8:0", WriteBPS: 1000}}
+	rate := cgroupRate(func() ([]CgroupIOLimit, error) { return limits, nil }, 5000, 0, true)
+
+	if got, _ := rate(); got != 1000 {
+		t.Errorf("rate() = %d whilst limited, want 1000", got)
+	}
+
+	// Lifting the cgroup's limit restores the rate from before tracking began
+	limits = []CgroupIOLimit{{Device: "8:0"}}
+	if got, _ := rate(); got != 5000 {
+		t.Errorf("rate() = %d once unlimited, want 5000", got)
+	}
+}
@@ -0,0 +1,36 @@
+package throughput
+
+import "context"
+
+// Chain returns a limiter which charges each of lims in order, waiting on each in turn before moving on to the
+// next. This is useful when some limiters are accounting-only (e.g. metrics) and others enforce, or when a
+// limiter should only be charged once another has admitted the bytes.
+//
+// Because the waits are sequential, delays add up -- use AllLimiter or MostRestrictiveLimiter to wait for the
+// slowest of several limiters instead.
+//
+// If a limiter fails, the chain stops and returns its error. Limiters earlier in the chain have already been
+// charged, and are refunded if they implement Refunder. Later limiters are never charged.
+func Chain(lims ...Limiter) Limiter {
+	if len(lims) == 1 {
+		return lims[0]
+	}
+	return chain(lims)
+}
+
+type chain []Limiter
+
+func (c chain) Wait(ctx context.Context, n int) error {
+	for i, lim := range c {
+		err := lim.Wait(ctx, n)
+		if err != nil {
+			for _, charged := range c[:i] {
+				if r, ok := charged.(Refunder); ok {
+					r.Refund(n)
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	var metrics countingLimiter
+	enforce := NewTokenBucket(1024, 1024)
+
+	lim := Chain(&metrics, enforce)
+	_ = lim.Wait(context.Background(), 100)
+
+	if metrics.n.Load() != 100 {
+		t.Errorf("metrics counted %d bytes, want 100", metrics.n.Load())
+	}
+	if tokens := enforce.Tokens(); tokens > 1000 {
+		t.Errorf("enforcing limiter has %.0f tokens, want ~924", tokens)
+	}
+}
+
+func TestChainStopsAndRefunds(t *testing.T) {
+	first := NewTokenBucket(1024, 1024)
+	var last countingLimiter
+
+	lim := Chain(first, NewThreeColorLimiter(1, 0, 1, 0, ColorPolicy{Red: Drop}), &last)
+	if err := lim.Wait(context.Background(), 100); !errors.Is(err, ErrDropped) {
+		t.Fatalf("got %v, want ErrDropped", err)
+	}
+	if tokens := first.Tokens(); tokens < 1024 {
+		t.Errorf("first limiter has %.0f tokens, want refund to 1024", tokens)
+	}
+	if last.n.Load() != 0 {
+		t.Error("limiter after the failure should not have been charged")
+	}
+}
@@ -0,0 +1,96 @@
+package throughput
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a source of time for limiters, allowing tests and simulations to avoid depending on wall time.
+// Use WithClock to supply one to a limiter. By default, limiters use the standard library's clock.
+type Clock interface {
+	Now() time.Time
+
+	// NewTimer returns a Timer which fires once d has elapsed.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is the subset of time.Timer used by limiters.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// SystemClock is the standard library's clock.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// VirtualClock is a Clock where time only passes when something waits: timers fire immediately, advancing the
+// clock to their deadline. Limiters using a VirtualClock never block, so tests and simulations of long transfers
+// run in milliseconds.
+//
+// The clock never moves backwards, so when several goroutines wait concurrently, it ends up at the latest of
+// their deadlines. Goroutines aren't scheduled in virtual time though, so one may run ahead of the others:
+// results are only exact for sequential waits.
+type VirtualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewVirtualClock returns a clock starting at start.
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the virtual time elapsed since t.
+func (c *VirtualClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Advance moves the clock forward by d, e.g. to simulate idling.
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(max(d, 0))
+}
+
+// NewTimer returns a Timer which has already fired, having advanced the clock by d.
+func (c *VirtualClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if at := c.now.Add(d); at.After(c.now) {
+		c.now = at
+	}
+
+	ch := make(firedTimer, 1)
+	ch <- c.now
+	return ch
+}
+
+// firedTimer is a Timer which has already fired.
+type firedTimer chan time.Time
+
+func (t firedTimer) C() <-chan time.Time { return t }
+func (t firedTimer) Stop() bool          { return false }
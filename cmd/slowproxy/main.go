@@ -0,0 +1,137 @@
+// Command slowproxy is a TCP proxy which shapes the traffic it forwards, for testing how applications behave on
+// slow links without needing tc or netem.
+//
+// Usage:
+//
+//	slowproxy [flags] listen-addr target-addr
+//
+// For example, to reach a local server as if over a 3G connection:
+//
+//	slowproxy --up 48K --down 256K --latency 100ms :8081 localhost:8080
+//
+// Rate limits are shared by all connections, as they would be on a real link. Latency is added in each
+// direction, so the round-trip time increases by twice the latency.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/iamcalledrob/throughput"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "slowproxy: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	upFlag := flag.String("up", "", "upstream rate, from clients to the target, e.g. 48K (default unlimited)")
+	downFlag := flag.String("down", "", "downstream rate, from the target to clients, e.g. 256K (default unlimited)")
+	latency := flag.Duration("latency", 0, "delay added in each direction")
+	jitter := flag.Duration("jitter", 0, "maximum random variation in latency")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: slowproxy [flags] listen-addr target-addr\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	up, err := limiter(*upFlag)
+	if err != nil {
+		return fmt.Errorf("parsing upstream rate: %w", err)
+	}
+	down, err := limiter(*downFlag)
+	if err != nil {
+		return fmt.Errorf("parsing downstream rate: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ln, err := net.Listen("tcp", flag.Arg(0))
+	if err != nil {
+		return err
+	}
+	context.AfterFunc(ctx, func() { _ = ln.Close() })
+	log.Printf("forwarding %s to %s", ln.Addr(), flag.Arg(1))
+
+	p := &proxy{
+		target:  flag.Arg(1),
+		up:      up,
+		down:    down,
+		profile: throughput.LinkProfile{Latency: *latency, Jitter: *jitter},
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.forward(ctx, conn)
+		}()
+	}
+}
+
+// limiter returns a token bucket for rate, or a limiter which never delays if rate is empty.
+func limiter(rate string) (throughput.Limiter, error) {
+	if rate == "" {
+		return throughput.NewMeter(time.Second), nil
+	}
+	bytesPerSec, err := throughput.ParseRate(rate)
+	if err != nil {
+		return nil, err
+	}
+	return throughput.NewTokenBucket(bytesPerSec, 0), nil
+}
+
+type proxy struct {
+	target   string
+	up, down throughput.Limiter
+	profile  throughput.LinkProfile
+}
+
+func (p *proxy) forward(ctx context.Context, client net.Conn) {
+	defer client.Close()
+
+	var d net.Dialer
+	target, err := d.DialContext(ctx, "tcp", p.target)
+	if err != nil {
+		log.Printf("%s: dialing target: %s", client.RemoteAddr(), err)
+		return
+	}
+	defer target.Close()
+
+	// Latency is added on the client's side, so it applies to both directions.
+	link := throughput.NewEmulatedConn(client, p.profile)
+	start := time.Now()
+
+	stats, err := throughput.Relay(ctx, link, target, p.up, p.down)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("%s: %s", client.RemoteAddr(), err)
+	}
+	log.Printf("%s: closed after %s, %s up, %s down", client.RemoteAddr(), time.Since(start).Round(time.Millisecond),
+		throughput.FormatBytes(float64(stats.Up)), throughput.FormatBytes(float64(stats.Down)))
+}
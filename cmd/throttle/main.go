@@ -0,0 +1,145 @@
+// Command throttle copies its input to its output at a limited rate, displaying progress like pv.
+//
+// Usage:
+//
+//	throttle [flags] [src [dst]]
+//
+// src and dst default to stdin and stdout, and may also be "-". For example, to upload a file at 512 KiB/s:
+//
+//	throttle --rate 512K backup.tar | ssh host 'cat > backup.tar'
+//
+// Rates accept binary byte units (512K, 1.5MiB/s) or decimal bit units (100Mbit). Progress is written to stderr.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/iamcalledrob/throughput"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "throttle: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	rateFlag := flag.String("rate", "", "maximum rate, e.g. 512K or 100Mbit (default unlimited)")
+	burstFlag := flag.String("burst", "0", "bytes which may be copied without delay, e.g. 1M")
+	quiet := flag.Bool("q", false, "don't display progress")
+	interval := flag.Duration("interval", time.Second, "how often to update progress")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: throttle [flags] [src [dst]]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	src, dst, err := open(flag.Arg(0), flag.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// Without a rate, the meter is the only limiter. With one, the meter measures what is written, so that Copy
+	// sees the token bucket's rate and sizes its reads to suit.
+	meter := throughput.NewMeter(3 * time.Second)
+	var lim throughput.Limiter = meter
+	var out io.Writer = dst
+	if *rateFlag != "" {
+		rate, err := throughput.ParseRate(*rateFlag)
+		if err != nil {
+			return err
+		}
+		burst, err := throughput.ParseRate(*burstFlag)
+		if err != nil {
+			return fmt.Errorf("parsing burst: %w", err)
+		}
+		lim = throughput.NewTokenBucket(rate, burst)
+		out = throughput.NewWriter(ctx, dst, meter)
+	}
+
+	start := time.Now()
+	if !*quiet {
+		done := make(chan struct{})
+		defer func() {
+			close(done)
+			progress(meter, start, true)
+		}()
+		go func() {
+			t := time.NewTicker(*interval)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					progress(meter, start, false)
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	_, err = throughput.Copy(ctx, out, src, lim)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if errors.Is(err, context.Canceled) {
+		return errors.New("interrupted")
+	}
+	return err
+}
+
+// open returns the source and destination named by the arguments, defaulting to stdin and stdout.
+func open(srcName, dstName string) (io.ReadCloser, io.WriteCloser, error) {
+	var src io.ReadCloser = os.Stdin
+	if srcName != "" && srcName != "-" {
+		f, err := os.Open(srcName)
+		if err != nil {
+			return nil, nil, err
+		}
+		src = f
+	}
+
+	var dst io.WriteCloser = os.Stdout
+	if dstName != "" && dstName != "-" {
+		f, err := os.Create(dstName)
+		if err != nil {
+			_ = src.Close()
+			return nil, nil, err
+		}
+		dst = f
+	}
+	return src, dst, nil
+}
+
+// progress writes the bytes copied, current rate and elapsed time to stderr, overwriting the previous line.
+func progress(meter *throughput.Meter, start time.Time, final bool) {
+	elapsed := time.Since(start).Truncate(time.Second)
+	rate := meter.Rate()
+	if final && elapsed > 0 {
+		// Once done, the average is more useful than the current rate.
+		rate = float64(meter.Total()) / time.Since(start).Seconds()
+	}
+
+	end := ""
+	if final {
+		end = "\n"
+	}
+	fmt.Fprintf(os.Stderr, "\r%12s  %14s  %8s\x1b[K%s",
+		throughput.FormatBytes(float64(meter.Total())), throughput.FormatRate(rate), elapsed, end)
+}
@@ -0,0 +1,120 @@
+package throughput
+
+import (
+	"sync"
+	"time"
+)
+
+// CoalescingClock is a Clock which coalesces timers with nearby deadlines, so that they share a single underlying
+// timer. Supply it to limiters with WithClock when many goroutines share a limiter at low rates, e.g. hundreds of
+// streams: rather than each waking on its own timer, waiters due in the same slot wake together, in the order
+// they began waiting.
+//
+// Deadlines are rounded up to a multiple of the resolution, so timers fire up to one resolution late, but never
+// early. A resolution of a few milliseconds is usually imperceptible to throughput.
+type CoalescingClock struct {
+	resolution time.Duration
+
+	mu    sync.Mutex
+	slots map[int64]*coalescedSlot // by deadline, in units of resolution
+}
+
+// coalescedSlot is a set of timers sharing a deadline, and the underlying timer that fires them.
+type coalescedSlot struct {
+	timer  *time.Timer
+	timers []*coalescedTimer // in the order they were created
+	live   int               // timers not yet stopped
+}
+
+// NewCoalescingClock returns a clock coalescing timers whose deadlines fall within the same resolution.
+func NewCoalescingClock(resolution time.Duration) *CoalescingClock {
+	return &CoalescingClock{
+		resolution: max(resolution, 1),
+		slots:      make(map[int64]*coalescedSlot),
+	}
+}
+
+func (c *CoalescingClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *CoalescingClock) NewTimer(d time.Duration) Timer {
+	now := time.Now()
+	deadline := now.Add(d).UnixNano()
+	key := deadline / int64(c.resolution)
+	if deadline%int64(c.resolution) != 0 {
+		key++
+	}
+
+	t := &coalescedTimer{c: make(chan time.Time, 1), clock: c, key: key}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.slots[key]
+	if !ok {
+		s = &coalescedSlot{}
+		at := time.Unix(0, key*int64(c.resolution))
+		s.timer = time.AfterFunc(at.Sub(now), func() { c.fire(key) })
+		c.slots[key] = s
+	}
+	s.timers = append(s.timers, t)
+	s.live++
+	return t
+}
+
+// fire wakes the timers in the slot for key.
+func (c *CoalescingClock) fire(key int64) {
+	c.mu.Lock()
+	s := c.slots[key]
+	delete(c.slots, key)
+	if s != nil {
+		for _, t := range s.timers {
+			t.fired = true
+		}
+	}
+	c.mu.Unlock()
+
+	if s == nil {
+		return
+	}
+	now := time.Now()
+	for _, t := range s.timers {
+		if !t.stopped {
+			t.c <- now
+		}
+	}
+}
+
+// coalescedTimer is a Timer created by a CoalescingClock. fired and stopped are guarded by the clock's lock,
+// but once fired is set, stopped no longer changes.
+type coalescedTimer struct {
+	c     chan time.Time
+	clock *CoalescingClock
+	key   int64
+
+	fired, stopped bool
+}
+
+func (t *coalescedTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *coalescedTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.fired || t.stopped {
+		return false
+	}
+
+	t.stopped = true
+	s := c.slots[t.key]
+	s.live--
+	if s.live == 0 {
+		s.timer.Stop()
+		delete(c.slots, t.key)
+	}
+	return true
+}
+
+var _ Clock = (*CoalescingClock)(nil)
@@ -0,0 +1,71 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCoalescingClock(t *testing.T) {
+	clock := NewCoalescingClock(50 * time.Millisecond)
+
+	start := time.Now()
+	var timers []Timer
+	for i := 0; i < 10; i++ {
+		timers = append(timers, clock.NewTimer(time.Duration(i)*time.Millisecond+time.Millisecond))
+	}
+
+	clock.mu.Lock()
+	slots := len(clock.slots)
+	clock.mu.Unlock()
+	if slots > 2 {
+		t.Errorf("10 timers within 10ms used %d slots, want at most 2", slots)
+	}
+
+	// A stopped timer never fires, and the rest fire no earlier than their deadline.
+	if !timers[9].Stop() {
+		t.Error("Stop before firing returned false")
+	}
+	for i, timer := range timers[:9] {
+		<-timer.C()
+		if elapsed := time.Since(start); elapsed < time.Duration(i+1)*time.Millisecond {
+			t.Errorf("timer %d fired after %s, before its deadline", i, elapsed)
+		}
+	}
+	select {
+	case <-timers[9].C():
+		t.Error("stopped timer fired")
+	case <-time.After(60 * time.Millisecond):
+	}
+
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	if len(clock.slots) != 0 {
+		t.Errorf("%d slots remain once all timers have fired or stopped", len(clock.slots))
+	}
+}
+
+func TestCoalescingClockSharedLimiter(t *testing.T) {
+	// 100 streams sharing a limiter still receive the limiter's rate in aggregate.
+	lim := NewTokenBucket(100*1024, 0, WithClock(NewCoalescingClock(5*time.Millisecond)))
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				_ = lim.Wait(context.Background(), 50)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 50KB at 100KB/sec, rounded up to the resolution.
+	err := verifyWithSlop(time.Since(start), 500*time.Millisecond, 50*time.Millisecond)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,58 @@
+package throughput
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CoarseClock is a Clock whose time is updated by a background ticker, rather than read on every call to Now.
+// At millions of Waits per second, time.Now becomes measurable, whereas CoarseClock's Now is a single atomic load.
+// Supply it to limiters with WithClock when sub-millisecond precision isn't needed.
+//
+// Now lags real time by up to the resolution, so limiters see slightly less time pass than really has: they may
+// undershoot their rate by a little, but never overshoot it. Timers are the standard library's.
+type CoarseClock struct {
+	start   time.Time
+	elapsed atomic.Int64 // since start, as of the last tick
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewCoarseClock returns a clock updated every resolution, e.g. 1ms. Stop it once it is no longer needed.
+func NewCoarseClock(resolution time.Duration) *CoarseClock {
+	c := &CoarseClock{start: time.Now(), stop: make(chan struct{}), done: make(chan struct{})}
+	go c.run(max(resolution, time.Microsecond))
+	return c
+}
+
+func (c *CoarseClock) run(resolution time.Duration) {
+	defer close(c.done)
+	t := time.NewTicker(resolution)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			c.elapsed.Store(int64(time.Since(c.start)))
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *CoarseClock) Now() time.Time {
+	return c.start.Add(time.Duration(c.elapsed.Load()))
+}
+
+func (c *CoarseClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+// Stop stops updating the clock's time. Once it returns, Now no longer changes.
+func (c *CoarseClock) Stop() {
+	c.once.Do(func() { close(c.stop) })
+	<-c.done
+}
+
+var _ Clock = (*CoarseClock)(nil)
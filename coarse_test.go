@@ -0,0 +1,38 @@
+package throughput
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestCoarseClock(t *testing.T) {
+	clock := NewCoarseClock(time.Millisecond)
+	defer clock.Stop()
+
+	start := clock.Now()
+	time.Sleep(20 * time.Millisecond)
+	if elapsed := clock.Now().Sub(start); elapsed < 15*time.Millisecond || elapsed > 40*time.Millisecond {
+		t.Errorf("after sleeping 20ms, clock advanced %s", elapsed)
+	}
+	if lag := time.Since(clock.Now()); lag < 0 {
+		t.Errorf("clock is %s ahead of real time, want it never ahead", -lag)
+	}
+
+	clock.Stop()
+	stopped := clock.Now()
+	time.Sleep(5 * time.Millisecond)
+	if !clock.Now().Equal(stopped) {
+		t.Error("clock advanced after Stop")
+	}
+}
+
+func TestCoarseClockAdapter(t *testing.T) {
+	clock := NewCoarseClock(time.Millisecond)
+	defer clock.Stop()
+
+	lim := rate.NewLimiter(1024, 1024)
+	lim.AllowN(clock.Now(), 1024)
+	testReadWithLimiter(t, NewRateLimiterAdapter(lim, WithClock(clock)), SystemClock, time.Second, 64, 1024)
+}
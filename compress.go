@@ -0,0 +1,76 @@
+package throughput
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+)
+
+// Accounting selects which bytes of a compressed stream are charged to a limiter. The two can differ by 10x, so
+// this makes the choice explicit rather than an accident of the order readers and writers are wrapped in.
+type Accounting int
+
+const (
+	// WireBytes charges compressed bytes, as transferred. This suits limits protecting a network link.
+	WireBytes Accounting = iota
+
+	// LogicalBytes charges uncompressed bytes, as produced or consumed by the application. This suits limits on
+	// how fast data is processed, e.g. to protect a database being restored.
+	LogicalBytes
+)
+
+// NewCompressingWriter returns a writer which compresses into dst, using newWriter (e.g. a zstd encoder), and
+// charges lim for the bytes acct selects. Closing it closes the compressor, but not dst.
+func NewCompressingWriter(
+	ctx context.Context,
+	dst io.Writer,
+	lim Limiter,
+	acct Accounting,
+	newWriter func(io.Writer) io.WriteCloser,
+) io.WriteCloser {
+	if acct == WireBytes {
+		return newWriter(NewWriter(ctx, dst, lim))
+	}
+
+	c := newWriter(dst)
+	return struct {
+		io.Writer
+		io.Closer
+	}{NewWriter(ctx, c, lim), c}
+}
+
+// NewDecompressingReader returns a reader which decompresses src, using newReader (e.g. a zstd decoder), and
+// charges lim for the bytes acct selects. Errors from newReader, e.g. a malformed header, are returned as-is.
+//
+// With WireBytes, bytes which the decompressor reads ahead are charged even if they aren't consumed.
+func NewDecompressingReader(
+	ctx context.Context,
+	src io.Reader,
+	lim Limiter,
+	acct Accounting,
+	newReader func(io.Reader) (io.Reader, error),
+) (io.Reader, error) {
+	if acct == WireBytes {
+		return newReader(NewReader(ctx, src, lim))
+	}
+
+	d, err := newReader(src)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(ctx, d, lim), nil
+}
+
+// NewGzipWriter is NewCompressingWriter for gzip, at the default compression level.
+func NewGzipWriter(ctx context.Context, dst io.Writer, lim Limiter, acct Accounting) io.WriteCloser {
+	return NewCompressingWriter(ctx, dst, lim, acct, func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	})
+}
+
+// NewGzipReader is NewDecompressingReader for gzip.
+func NewGzipReader(ctx context.Context, src io.Reader, lim Limiter, acct Accounting) (io.Reader, error) {
+	return NewDecompressingReader(ctx, src, lim, acct, func(r io.Reader) (io.Reader, error) {
+		return gzip.NewReader(r)
+	})
+}
@@ -0,0 +1,43 @@
+package throughput
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+func TestGzipAccounting(t *testing.T) {
+	data := bytes.Repeat([]byte("compressible "), 10_000)
+
+	for _, acct := range []Accounting{WireBytes, LogicalBytes} {
+		var compressed bytes.Buffer
+		var written countingLimiter
+		w := NewGzipWriter(context.Background(), &compressed, &written, acct)
+		_, _ = w.Write(data)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		var read countingLimiter
+		r, err := NewGzipReader(context.Background(), bytes.NewReader(compressed.Bytes()), &read, acct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(r)
+		if !bytes.Equal(got, data) {
+			t.Fatal("round trip corrupted the data")
+		}
+
+		want := int64(compressed.Len())
+		if acct == LogicalBytes {
+			want = int64(len(data))
+		}
+		if n := written.n.Load(); n != want {
+			t.Errorf("accounting %d: writer charged %d bytes, want %d", acct, n, want)
+		}
+		if n := read.n.Load(); n != want {
+			t.Errorf("accounting %d: reader charged %d bytes, want %d", acct, n, want)
+		}
+	}
+}
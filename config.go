@@ -0,0 +1,143 @@
+package throughput
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ManagerConfig describes the groups of a Manager, and can be loaded from JSON, e.g.
+//
+//	{"groups": {"backup": {"limit": 1048576}, "api": {"limit": 10485760, "enabled": false}}}
+type ManagerConfig struct {
+	Groups map[string]GroupConfig `json:"groups"`
+}
+
+// GroupConfig describes one group of a Manager.
+type GroupConfig struct {
+	// Limit is the group's limit in bytes per second.
+	Limit int64 `json:"limit"`
+
+	// Enabled is whether the limit is applied. If unset, it is.
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+func (c ManagerConfig) validate() error {
+	for name, g := range c.Groups {
+		if g.Limit < 0 {
+			return fmt.Errorf("group %q: limit must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// Apply reconfigures the manager to match cfg: groups are created or updated, and groups missing from cfg are
+// removed (their existing members continue to share its limit). The whole config is applied under the manager's
+// lock, so Groups never observes a partially applied config.
+func (m *Manager) Apply(cfg ManagerConfig) error {
+	err := cfg.validate()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name := range m.groups {
+		if _, ok := cfg.Groups[name]; !ok {
+			delete(m.groups, name)
+		}
+	}
+	for name, gc := range cfg.Groups {
+		g := m.setGroup(name, gc.Limit)
+		g.SetEnabled(gc.Enabled == nil || *gc.Enabled)
+	}
+	return nil
+}
+
+// ConfigWatcher reloads a Manager's config from a JSON file (see ManagerConfig) on demand, or whenever the file
+// changes, so operators can tune limits in production by editing the file.
+type ConfigWatcher struct {
+	m    *Manager
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// NewConfigWatcher returns a watcher which applies the config at path to m.
+func NewConfigWatcher(m *Manager, path string) *ConfigWatcher {
+	return &ConfigWatcher{m: m, path: path}
+}
+
+// Reload reads and applies the config. If the file can't be read or is invalid, the manager is left unchanged.
+func (w *ConfigWatcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	return w.reload(info)
+}
+
+// reload applies the config, recording info to detect future changes. Must be called with mu held.
+func (w *ConfigWatcher) reload(info os.FileInfo) error {
+	w.modTime, w.size = info.ModTime(), info.Size()
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	var cfg ManagerConfig
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return fmt.Errorf("parsing config %s: %w", w.path, err)
+	}
+
+	err = w.m.Apply(cfg)
+	if err != nil {
+		return fmt.Errorf("applying config %s: %w", w.path, err)
+	}
+	return nil
+}
+
+// Run checks the file every interval until ctx is done, reloading the config when the file's modification time
+// or size changes. Errors are passed to onError, if set, and the previous config remains in place.
+func (w *ConfigWatcher) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		err := w.reloadIfChanged()
+		if err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (w *ConfigWatcher) reloadIfChanged() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return nil
+	}
+	return w.reload(info)
+}
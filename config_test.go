@@ -0,0 +1,64 @@
+package throughput
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	write := func(s string) {
+		err := os.WriteFile(path, []byte(s), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := NewManager()
+	m.SetGroup("stale", 1)
+	w := NewConfigWatcher(m, path)
+
+	write(`{"groups": {"backup": {"limit": 1000}, "api": {"limit": 2000, "enabled": false}}}`)
+	err := w.Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if m.Group("stale") != nil {
+		t.Error("group missing from config was not removed")
+	}
+	backup := m.Group("backup")
+	if backup == nil || backup.Limit() != 1000 || !backup.Enabled() {
+		t.Fatalf("backup group not configured")
+	}
+	if api := m.Group("api"); api == nil || api.Limit() != 2000 || api.Enabled() {
+		t.Fatalf("api group not configured")
+	}
+
+	// Invalid configs are rejected whole
+	write(`{"groups": {"backup": {"limit": 5}, "api": {"limit": -1}}}`)
+	if err = w.Reload(); err == nil {
+		t.Error("expected error for negative limit")
+	}
+	if backup.Limit() != 1000 {
+		t.Error("invalid config was partially applied")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = w.Run(ctx, 5*time.Millisecond, nil) }()
+
+	write(`{"groups": {"backup": {"limit": 3000}}}`)
+	deadline := time.Now().Add(time.Second)
+	for backup.Limit() != 3000 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := backup.Limit(); got != 3000 {
+		t.Errorf("after editing the file, Limit() = %d, want 3000", got)
+	}
+	if m.Group("backup") != backup {
+		t.Error("reloading replaced an existing group")
+	}
+}
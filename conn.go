@@ -0,0 +1,179 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Conn is a net.Conn whose reads and writes are rate-limited.
+//
+// Throttle waits respect the connection's deadlines as well as its context: a wait that outlives the read or write
+// deadline fails with a timeout net.Error, as the underlying connection's I/O would. Setting a deadline in the past
+// interrupts waits in progress, so code which relies on deadlines keeps working under throttling.
+type Conn struct {
+	net.Conn
+	r *Reader
+	w *Writer
+
+	readDeadline, writeDeadline connDeadline
+}
+
+// NewConn returns conn with reads rate-limited by readLim and writes by writeLim, which may be the same limiter.
+// The context is used to unblock throttle waits, alongside the connection's deadlines.
+func NewConn(ctx context.Context, conn net.Conn, readLim, writeLim Limiter, opts ...StreamOption) *Conn {
+	c := &Conn{
+		Conn: conn,
+		r:    NewReader(ctx, conn, readLim, opts...),
+		w:    NewWriter(ctx, conn, writeLim, opts...),
+	}
+	c.readDeadline = newConnDeadline(c.r.ctx)
+	c.writeDeadline = newConnDeadline(c.w.ctx)
+	return c
+}
+
+func (c *Conn) Read(p []byte) (int, error) {
+	ctx := c.readDeadline.context()
+	n, err := c.r.read(ctx, p)
+	return n, c.timeout("read", ctx, err)
+}
+
+func (c *Conn) Write(p []byte) (int, error) {
+	ctx := c.writeDeadline.context()
+	n, err := c.w.write(ctx, p)
+	return n, c.timeout("write", ctx, err)
+}
+
+// WriteBuffers writes bufs with a single writev syscall where the underlying connection supports it, charging
+// the write limiter once for the total. See Writer.WriteBuffers.
+func (c *Conn) WriteBuffers(bufs *net.Buffers) (int64, error) {
+	ctx := c.writeDeadline.context()
+	n, err := c.w.writeBuffers(ctx, bufs)
+	return n, c.timeout("write", ctx, err)
+}
+
+// Close unblocks any reads or writes waiting on their limiters, then closes the underlying connection.
+func (c *Conn) Close() error {
+	_ = c.r.close(nil)
+	_ = c.w.close(nil)
+	c.readDeadline.stop()
+	c.writeDeadline.stop()
+	return c.Conn.Close()
+}
+
+// CloseRead shuts down the reading side of the underlying connection, for protocols relying on half-close.
+// It returns errors.ErrUnsupported if the connection can't be half-closed, unlike *net.TCPConn.
+func (c *Conn) CloseRead() error {
+	if hc, ok := c.Conn.(interface{ CloseRead() error }); ok {
+		return hc.CloseRead()
+	}
+	return errors.ErrUnsupported
+}
+
+// CloseWrite shuts down the writing side of the underlying connection. See CloseRead.
+func (c *Conn) CloseWrite() error {
+	if hc, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return hc.CloseWrite()
+	}
+	return errors.ErrUnsupported
+}
+
+// timeout converts err into a timeout *net.OpError if ctx was canceled by a deadline.
+func (c *Conn) timeout(op string, ctx context.Context, err error) error {
+	if err == nil || !errors.Is(context.Cause(ctx), os.ErrDeadlineExceeded) {
+		return err
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		// Already from the underlying connection
+		return err
+	}
+	return &net.OpError{
+		Op:     op,
+		Net:    c.LocalAddr().Network(),
+		Source: c.LocalAddr(),
+		Addr:   c.RemoteAddr(),
+		Err:    os.ErrDeadlineExceeded,
+	}
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	c.writeDeadline.set(t)
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline.set(t)
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// connDeadline is a context which is canceled with os.ErrDeadlineExceeded once a deadline passes. Changing the
+// deadline doesn't replace the context unless it has already expired, so waits in progress observe the change.
+type connDeadline struct {
+	mu      sync.Mutex
+	parent  context.Context
+	ctx     context.Context
+	cancel  context.CancelCauseFunc // nil while ctx is parent
+	timer   *time.Timer
+	expired bool
+}
+
+func newConnDeadline(parent context.Context) connDeadline {
+	return connDeadline{parent: parent, ctx: parent}
+}
+
+func (d *connDeadline) context() context.Context {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.ctx
+}
+
+// set changes the deadline. A zero t means no deadline.
+func (d *connDeadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil && !d.timer.Stop() {
+		// The previous deadline has passed
+		d.expired = true
+	}
+	d.timer = nil
+	if d.expired {
+		d.ctx, d.cancel, d.expired = d.parent, nil, false
+	}
+
+	if t.IsZero() {
+		return
+	}
+	if d.cancel == nil {
+		d.ctx, d.cancel = context.WithCancelCause(d.parent)
+	}
+	if dur := time.Until(t); dur > 0 {
+		cancel := d.cancel
+		d.timer = time.AfterFunc(dur, func() { cancel(os.ErrDeadlineExceeded) })
+	} else {
+		d.cancel(os.ErrDeadlineExceeded)
+		d.expired = true
+	}
+}
+
+// stop releases the deadline's timer and context, once the connection is closed.
+func (d *connDeadline) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	if d.cancel != nil {
+		d.cancel(net.ErrClosed)
+	}
+}
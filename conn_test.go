@@ -0,0 +1,176 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestConnDeadline(t *testing.T) {
+	a, b := net.Pipe()
+	defer b.Close()
+	go func() { _, _ = io.Copy(io.Discard, b) }()
+
+	c := NewConn(context.Background(), a, NewTokenBucket(1000, 0), NewTokenBucket(1000, 0))
+	defer c.Close()
+
+	_ = c.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+	start := time.Now()
+	_, err := c.Write(make([]byte, 10_000))
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("Write returned %v, want a timeout net.Error", err)
+	}
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Write returned %v, want os.ErrDeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Write took %s, want it bounded by the deadline", elapsed)
+	}
+
+	// Clearing the deadline allows writes again
+	_ = c.SetWriteDeadline(time.Time{})
+	if _, err := c.Write(make([]byte, 10)); err != nil {
+		t.Errorf("Write after clearing the deadline returned %v", err)
+	}
+}
+
+func TestConnDeadlineInterrupts(t *testing.T) {
+	a, b := net.Pipe()
+	defer b.Close()
+	go func() { _, _ = b.Write(make([]byte, 10_000)) }()
+
+	c := NewConn(context.Background(), a, NewTokenBucket(1000, 0), NewTokenBucket(1000, 0))
+	defer c.Close()
+
+	// A deadline far in the future is brought forward whilst Read is throttled
+	_ = c.SetReadDeadline(time.Now().Add(time.Hour))
+	time.AfterFunc(50*time.Millisecond, func() { _ = c.SetReadDeadline(time.Now()) })
+
+	start := time.Now()
+	n, err := c.Read(make([]byte, 10_000))
+	if n == 0 || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Read returned %d, %v, want bytes read and os.ErrDeadlineExceeded", n, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Read took %s, want it interrupted by the deadline", elapsed)
+	}
+}
+
+func TestConnClose(t *testing.T) {
+	a, b := net.Pipe()
+	go func() { _, _ = b.Write(make([]byte, 10_000)) }()
+
+	c := NewConn(context.Background(), a, NewTokenBucket(1000, 0), NewTokenBucket(1000, 0))
+	_ = c.SetReadDeadline(time.Now().Add(time.Hour))
+	time.AfterFunc(50*time.Millisecond, func() { _ = c.Close() })
+
+	start := time.Now()
+	if _, err := c.Read(make([]byte, 10_000)); !errors.Is(err, ErrClosed) {
+		t.Errorf("Read returned %v, want ErrClosed", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Read took %s, want it unblocked by Close", elapsed)
+	}
+}
+
+func TestConnCloseWrite(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte)
+	go func() {
+		peer, err := ln.Accept()
+		if err != nil {
+			close(received)
+			return
+		}
+		defer peer.Close()
+		b, _ := io.ReadAll(peer) // returns once the other side half-closes
+		received <- b
+		_, _ = peer.Write([]byte("ok"))
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConn(context.Background(), conn, NewTokenBucket(1<<20, 1<<20), NewTokenBucket(1<<20, 1<<20))
+	defer c.Close()
+
+	_, _ = c.Write([]byte("hello"))
+	if err := c.CloseWrite(); err != nil {
+		t.Fatalf("CloseWrite returned %v", err)
+	}
+	if got := string(<-received); got != "hello" {
+		t.Errorf("peer received %q, want hello", got)
+	}
+
+	// The read side is still open
+	if b, err := io.ReadAll(c); string(b) != "ok" || err != nil {
+		t.Errorf("ReadAll after CloseWrite returned %q, %v, want ok", b, err)
+	}
+
+	a, _ := net.Pipe()
+	if err := NewConn(context.Background(), a, nil, nil).CloseWrite(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("CloseWrite on a pipe returned %v, want errors.ErrUnsupported", err)
+	}
+}
+
+func TestConnWriteBuffers(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte)
+	go func() {
+		peer, err := ln.Accept()
+		if err != nil {
+			close(received)
+			return
+		}
+		defer peer.Close()
+		b, _ := io.ReadAll(peer)
+		received <- b
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lim waitRecorder
+	c := NewConn(context.Background(), conn, &lim, &lim)
+
+	bufs := net.Buffers{[]byte("hello, "), []byte("vectored "), []byte("world")}
+	if n, err := c.WriteBuffers(&bufs); n != 21 || err != nil {
+		t.Errorf("WriteBuffers returned %d, %v, want 21, nil", n, err)
+	}
+	_ = c.Close()
+
+	if got := string(<-received); got != "hello, vectored world" {
+		t.Errorf("peer received %q", got)
+	}
+	if len(lim.waits) != 1 || lim.waits[0] != 21 {
+		t.Errorf("limiter waits %v, want a single wait for 21 bytes", lim.waits)
+	}
+}
+
+// waitRecorder is a Limiter recording the n of each Wait.
+type waitRecorder struct {
+	waits []int
+}
+
+func (w *waitRecorder) Wait(_ context.Context, n int) error {
+	w.waits = append(w.waits, n)
+	return nil
+}
@@ -0,0 +1,17 @@
+package throughput
+
+import "context"
+
+type limiterKey struct{}
+
+// NewContext returns a copy of ctx carrying lim, e.g. so middleware can attach a per-request or per-tenant limiter
+// which deeper layers -- storage clients, proxies -- retrieve with FromContext.
+func NewContext(ctx context.Context, lim Limiter) context.Context {
+	return context.WithValue(ctx, limiterKey{}, lim)
+}
+
+// FromContext returns the limiter carried by ctx, if any.
+func FromContext(ctx context.Context) (Limiter, bool) {
+	lim, ok := ctx.Value(limiterKey{}).(Limiter)
+	return lim, ok
+}
@@ -0,0 +1,18 @@
+package throughput
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("FromContext found a limiter in an empty context")
+	}
+
+	lim := NewTokenBucket(1000, 1000)
+	ctx := NewContext(context.Background(), lim)
+	if got, ok := FromContext(ctx); !ok || got != lim {
+		t.Errorf("FromContext returned %v, %v, want the attached limiter", got, ok)
+	}
+}
@@ -0,0 +1,128 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrInvariant is wrapped by the errors a DebugLimiter reports.
+var ErrInvariant = errors.New("throughput: invariant violated")
+
+// DebugLimiter wraps a limiter, checking at runtime that it is used correctly. It is intended to catch
+// integration bugs during development and testing, e.g. in a build with a debug tag, rather than for production.
+//
+// It checks for negative n, waits after Close, refunds of more than was charged, and -- if the application
+// reports transfers via Transferred -- a mismatch between the bytes charged and the bytes transferred by Close.
+type DebugLimiter struct {
+	lim    Limiter
+	report func(error)
+
+	mu          sync.Mutex
+	charged     int64
+	transferred int64
+	closed      bool
+	tracked     bool
+}
+
+// NewDebugLimiter returns a limiter that checks usage of lim, passing any violations to report.
+// A nil report panics instead.
+func NewDebugLimiter(lim Limiter, report func(error)) *DebugLimiter {
+	if report == nil {
+		report = func(err error) { panic(err) }
+	}
+	return &DebugLimiter{lim: lim, report: report}
+}
+
+func (d *DebugLimiter) violation(format string, args ...any) error {
+	err := fmt.Errorf("%w: "+format, append([]any{ErrInvariant}, args...)...)
+	d.report(err)
+	return err
+}
+
+func (d *DebugLimiter) Wait(ctx context.Context, n int) error {
+	if n < 0 {
+		return d.violation("Wait called with negative n (%d)", n)
+	}
+
+	d.mu.Lock()
+	closed := d.closed
+	d.mu.Unlock()
+	if closed {
+		d.violation("Wait called after Close")
+		return ErrClosed
+	}
+
+	err := d.lim.Wait(ctx, n)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.charged += int64(n)
+	d.mu.Unlock()
+	return nil
+}
+
+// Refund returns n bytes to the wrapped limiter, if it is a Refunder.
+func (d *DebugLimiter) Refund(n int) {
+	d.mu.Lock()
+	if n < 0 || int64(n) > d.charged {
+		charged := d.charged
+		d.mu.Unlock()
+		d.violation("Refund of %d bytes, with %d charged", n, charged)
+		return
+	}
+	d.charged -= int64(n)
+	d.mu.Unlock()
+
+	if r, ok := d.lim.(Refunder); ok {
+		r.Refund(n)
+	}
+}
+
+func (d *DebugLimiter) refunds() bool {
+	return refunds(d.lim)
+}
+
+// Transferred records that n bytes were actually transferred, to be compared with the bytes charged on Close.
+func (d *DebugLimiter) Transferred(n int) {
+	if n < 0 {
+		d.violation("Transferred called with negative n (%d)", n)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tracked = true
+	d.transferred += int64(n)
+}
+
+// Charged returns the bytes charged through the limiter, less refunds.
+func (d *DebugLimiter) Charged() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.charged
+}
+
+// Close marks the limiter as no longer in use, so subsequent waits are reported. If Transferred was called, the
+// bytes transferred must match the bytes charged, otherwise the mismatch is reported and returned.
+func (d *DebugLimiter) Close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	tracked, charged, transferred := d.tracked, d.charged, d.transferred
+	d.mu.Unlock()
+
+	if tracked && charged != transferred {
+		return d.violation("%d bytes charged, but %d transferred", charged, transferred)
+	}
+	return nil
+}
+
+var _ Limiter = (*DebugLimiter)(nil)
+var _ Refunder = (*DebugLimiter)(nil)
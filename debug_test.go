@@ -0,0 +1,52 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDebugLimiter(t *testing.T) {
+	var violations []error
+	var inner countingLimiter
+	lim := NewDebugLimiter(&inner, func(err error) { violations = append(violations, err) })
+
+	_ = lim.Wait(context.Background(), 100)
+	lim.Transferred(100)
+	if err := lim.Wait(context.Background(), -1); !errors.Is(err, ErrInvariant) {
+		t.Errorf("Wait(-1) returned %v, want ErrInvariant", err)
+	}
+	lim.Refund(200)
+	if len(violations) != 2 {
+		t.Fatalf("got %d violations, want 2: %v", len(violations), violations)
+	}
+
+	if err := lim.Close(); err != nil {
+		t.Errorf("Close with matching totals returned %v", err)
+	}
+	if err := lim.Wait(context.Background(), 10); !errors.Is(err, ErrClosed) {
+		t.Errorf("Wait after Close returned %v, want ErrClosed", err)
+	}
+	if len(violations) != 3 || inner.n.Load() != 100 {
+		t.Errorf("got %d violations and %d bytes charged, want 3 and 100", len(violations), inner.n.Load())
+	}
+}
+
+func TestDebugLimiterMismatch(t *testing.T) {
+	lim := NewDebugLimiter(&countingLimiter{}, func(error) {})
+	_ = lim.Wait(context.Background(), 100)
+	lim.Transferred(60)
+
+	if err := lim.Close(); !errors.Is(err, ErrInvariant) {
+		t.Errorf("Close with 100 charged and 60 transferred returned %v, want ErrInvariant", err)
+	}
+}
+
+func TestDebugLimiterPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a violation with a nil report to panic")
+		}
+	}()
+	_ = NewDebugLimiter(&countingLimiter{}, nil).Wait(context.Background(), -1)
+}
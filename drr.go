@@ -0,0 +1,169 @@
+package throughput
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// DRRScheduler divides a rate between registered streams using deficit round-robin.
+//
+// Time is divided into rounds, and in each round every active stream may transfer its quantum of bytes. A stream
+// which overshoots its quantum (e.g. with a large read) carries the deficit into the following rounds, so
+// unfairness is bounded regardless of the size of individual reads and writes. Rounds last as long as it takes
+// to transfer the quanta of all active streams at the configured rate, so idle streams' bandwidth is shared
+// amongst the rest.
+//
+// Smaller quanta give smoother per-stream pacing, at the cost of more frequent waits.
+type DRRScheduler struct {
+	w waiter
+
+	mu         sync.Mutex
+	rate       float64 // bytes per second
+	round      int64
+	roundStart time.Time
+	streams    map[*DRRStream]struct{}
+}
+
+// NewDRRScheduler returns a scheduler dividing bytesPerSec between its streams.
+// If bytesPerSec is not positive, no bytes are ever allowed, and streams wait until their context is done.
+func NewDRRScheduler(bytesPerSec int64, opts ...Option) *DRRScheduler {
+	d := &DRRScheduler{
+		w:       newWaiter(opts),
+		rate:    float64(bytesPerSec),
+		streams: make(map[*DRRStream]struct{}),
+	}
+	d.roundStart = d.w.now()
+	return d
+}
+
+// Register adds a stream which may transfer quantum bytes per round, and returns its limiter.
+// The stream should be closed once it is no longer needed.
+func (d *DRRScheduler) Register(quantum int64) *DRRStream {
+	s := &DRRStream{
+		d:       d,
+		quantum: max(quantum, 1),
+		round:   math.MinInt64 / 2, // idle
+	}
+
+	d.mu.Lock()
+	d.streams[s] = struct{}{}
+	d.mu.Unlock()
+	return s
+}
+
+// roundLength returns how long it takes to transfer the quanta of all streams active in the current or
+// previous round. Must be called with mu held.
+func (d *DRRScheduler) roundLength() time.Duration {
+	var quanta int64
+	for s := range d.streams {
+		if s.round >= d.round-1 {
+			quanta += s.quantum
+		}
+	}
+	if d.rate <= 0 {
+		return math.MaxInt64
+	}
+	return secondsToDuration(float64(quanta) / d.rate)
+}
+
+// advance moves the current round forward to now. Must be called with mu held.
+func (d *DRRScheduler) advance(now time.Time) (length time.Duration) {
+	length = d.roundLength()
+	if length <= 0 {
+		// Nobody is active, so rounds aren't progressing
+		d.roundStart = now
+		return length
+	}
+
+	if elapsed := now.Sub(d.roundStart); elapsed >= length {
+		k := elapsed / length
+		d.round += int64(k)
+		d.roundStart = d.roundStart.Add(k * length)
+	}
+	return length
+}
+
+// DRRStream is a limiter for one stream of a DRRScheduler.
+type DRRStream struct {
+	d       *DRRScheduler
+	quantum int64
+
+	// Guarded by d.mu
+	round   int64 // the round the stream is transferring in
+	deficit int64 // bytes remaining in the stream's allowance for its round
+	closed  bool
+}
+
+func (s *DRRStream) Wait(ctx context.Context, n int) error {
+	d := s.d
+
+	d.mu.Lock()
+	if s.closed {
+		d.mu.Unlock()
+		return ErrClosed
+	}
+	if d.rate <= 0 && n > 0 {
+		// Rounds never end
+		d.mu.Unlock()
+		return d.w.sleep(ctx, math.MaxInt64)
+	}
+
+	d.advance(d.w.now())
+	if s.round < d.round {
+		// Idle streams join the current round, without banking allowance from the rounds they missed.
+		s.round = d.round
+		s.deficit = s.quantum
+	}
+
+	s.deficit -= int64(n)
+	for s.deficit < 0 {
+		s.round++
+		s.deficit += s.quantum
+	}
+	target := s.round
+	d.mu.Unlock()
+
+	for {
+		d.mu.Lock()
+		now := d.w.now()
+		length := d.advance(now)
+		if d.round >= target {
+			d.mu.Unlock()
+			return nil
+		}
+		// The round length may change as streams come and go, so re-check on waking.
+		delay := d.roundStart.Add(time.Duration(target-d.round) * length).Sub(now)
+		d.mu.Unlock()
+
+		err := d.w.sleep(ctx, max(delay, time.Microsecond))
+		if err != nil {
+			s.refund(int64(n))
+			return err
+		}
+	}
+}
+
+// refund returns n bytes to the stream's allowance, moving it back to earlier rounds where possible.
+func (s *DRRStream) refund(n int64) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	s.deficit += n
+	for s.deficit > s.quantum && s.round > s.d.round {
+		s.round--
+		s.deficit -= s.quantum
+	}
+}
+
+// Close unregisters the stream, so its share is divided amongst the others.
+func (s *DRRStream) Close() error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.closed = true
+	delete(s.d.streams, s)
+	return nil
+}
+
+var _ Limiter = (*DRRStream)(nil)
@@ -0,0 +1,102 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDRRScheduler(t *testing.T) {
+	d := NewDRRScheduler(64 * 1024)
+
+	tests := []struct {
+		name            string
+		quantumA, sizeA int
+		quantumB, sizeB int
+		wantRatio       float64
+	}{
+		// Equal quanta give equal bandwidth, regardless of read size
+		{"EqualQuanta", 1024, 128, 1024, 2048, 1},
+		// Bandwidth is proportional to quantum
+		{"DoubleQuantum", 2048, 256, 1024, 256, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := d.Register(int64(tt.quantumA))
+			b := d.Register(int64(tt.quantumB))
+			defer a.Close()
+			defer b.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+			defer cancel()
+
+			var wg sync.WaitGroup
+			var bytesA, bytesB int
+			run := func(s *DRRStream, n int, total *int) {
+				defer wg.Done()
+				for s.Wait(ctx, n) == nil {
+					*total += n
+				}
+			}
+			wg.Add(2)
+			go run(a, tt.sizeA, &bytesA)
+			go run(b, tt.sizeB, &bytesB)
+			wg.Wait()
+
+			ratio := float64(bytesA) / float64(bytesB)
+			if ratio < tt.wantRatio*0.7 || ratio > tt.wantRatio*1.3 {
+				t.Errorf("a:b = %d:%d (%.2f), want ~%.0f", bytesA, bytesB, ratio, tt.wantRatio)
+			}
+
+			// Together, the streams should use roughly the whole rate
+			total := bytesA + bytesB
+			if total < 14*1024 || total > 26*1024 {
+				t.Errorf("streams transferred %d bytes in 300ms, want ~19KB", total)
+			}
+		})
+	}
+}
+
+func TestDRRScheduler_ZeroRate(t *testing.T) {
+	clock := &timerRecorder{stoppedClock: stoppedClock{time.Unix(0, 0)}}
+	s := NewDRRScheduler(0, WithClock(clock)).Register(1024)
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Wait(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() = %v, want context.DeadlineExceeded", err)
+	}
+
+	// Waiting for a round which never ends mustn't poll
+	for _, d := range clock.durations() {
+		if d < time.Hour {
+			t.Errorf("slept for %v, want to wait for the context", d)
+		}
+	}
+}
+
+// timerRecorder is a stoppedClock which records the duration of every timer it creates.
+type timerRecorder struct {
+	stoppedClock
+
+	mu     sync.Mutex
+	timers []time.Duration
+}
+
+func (c *timerRecorder) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	c.timers = append(c.timers, d)
+	c.mu.Unlock()
+	return c.stoppedClock.NewTimer(d)
+}
+
+func (c *timerRecorder) durations() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.timers)
+}
@@ -0,0 +1,78 @@
+package throughput
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/time/rate"
+)
+
+// DualRateLimiter is a committed-rate + peak-rate shaper, as used in carrier-grade traffic shaping.
+//
+// Traffic must conform to two token buckets: the committed bucket (CIR, with a large burst, CBS) bounds the
+// sustained rate, and the peak bucket (PIR, with a small burst, PBS) bounds how fast the committed burst can be
+// spent. This allows traffic to exceed the committed rate briefly, up to the peak rate -- which a single
+// rate.Limiter cannot express.
+type DualRateLimiter struct {
+	committed *rate.Limiter
+	peak      *rate.Limiter
+	w         waiter
+}
+
+// NewDualRateLimiter returns a limiter with a committed rate and burst, and a peak rate and burst, all in bytes.
+// Both buckets begin full.
+func NewDualRateLimiter(committedRate, committedBurst, peakRate, peakBurst int64, opts ...Option) *DualRateLimiter {
+	return &DualRateLimiter{
+		committed: rate.NewLimiter(rate.Limit(committedRate), int(committedBurst)),
+		peak:      rate.NewLimiter(rate.Limit(peakRate), int(peakBurst)),
+		w:         newWaiter(opts),
+	}
+}
+
+// Committed returns the committed-rate bucket, e.g. to adjust it at runtime.
+func (d *DualRateLimiter) Committed() *rate.Limiter {
+	return d.committed
+}
+
+// Peak returns the peak-rate bucket, e.g. to adjust it at runtime.
+func (d *DualRateLimiter) Peak() *rate.Limiter {
+	return d.peak
+}
+
+func (d *DualRateLimiter) Wait(ctx context.Context, n int) error {
+	// As with RateLimiterAdapter, n may exceed either bucket's burst capacity so may need to be split into
+	// sequential reservations. Both bursts are read upfront here, as there are two buckets to satisfy.
+	burst := max(min(d.committed.Burst(), d.peak.Burst()), 1)
+
+	for n > 0 {
+		now := d.w.now()
+		nn := min(burst, n)
+
+		c := d.committed.ReserveN(now, nn)
+		p := d.peak.ReserveN(now, nn)
+		if !c.OK() || !p.OK() {
+			// Burst capacity was reduced concurrently
+			c.CancelAt(now)
+			p.CancelAt(now)
+			reduced := min(d.committed.Burst(), d.peak.Burst())
+			if reduced >= nn || reduced <= 0 {
+				return fmt.Errorf("reserving %d bytes: exceeds burst capacity", nn)
+			}
+			burst = reduced
+			continue
+		}
+
+		// Conforming to both buckets means waiting out the longer of the two delays.
+		err := d.w.sleep(ctx, max(c.DelayFrom(now), p.DelayFrom(now)))
+		if err != nil {
+			at := d.w.now()
+			c.CancelAt(at)
+			p.CancelAt(at)
+			return err
+		}
+
+		n -= nn
+	}
+	return nil
+}
+
+var _ Limiter = (*DualRateLimiter)(nil)
@@ -0,0 +1,58 @@
+package throughput
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDualRateLimiter(t *testing.T) {
+	// Committed: 1KB/sec with a 4KB burst. Peak: 16KB/sec with a 1KB burst.
+	// The 4KB committed burst should drain at the peak rate (~190ms), rather than instantly.
+	lim := NewDualRateLimiter(1024, 4*1024, 16*1024, 1024)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := lim.Wait(context.Background(), 1024); err != nil {
+			t.Fatalf("wait: %s", err)
+		}
+	}
+	elapsed := time.Since(start)
+
+	err := verifyWithSlop(elapsed, 3*time.Second/16, 50*time.Millisecond)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// With the committed burst spent, further bytes are limited to the committed rate
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := lim.Wait(ctx, 512); err == nil {
+		t.Error("expected wait beyond committed burst to be delayed")
+	}
+}
+
+func TestDualRateLimiter_CancelWithClock(t *testing.T) {
+	clock := stoppedClock{time.Unix(0, 0)}
+	lim := NewDualRateLimiter(1024, 1024, 1024, 1024, WithClock(clock))
+	_ = lim.Wait(context.Background(), 1024)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := lim.Wait(ctx, 1024); err == nil {
+		t.Fatal("expected wait with an empty bucket to time out")
+	}
+
+	// The cancelled bytes are returned as of the limiter's clock, not the wall clock
+	if tokens := lim.Committed().TokensAt(clock.now); tokens != 0 {
+		t.Errorf("committed bucket has %v tokens after cancelling, want 0", tokens)
+	}
+}
+
+// stoppedClock is a Clock which never moves, and whose timers never fire.
+type stoppedClock struct {
+	now time.Time
+}
+
+func (c stoppedClock) Now() time.Time               { return c.now }
+func (c stoppedClock) NewTimer(time.Duration) Timer { return firedTimer(nil) }
@@ -0,0 +1,314 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LinkProfile describes an emulated network link. The zero value adds no impairment.
+type LinkProfile struct {
+	// Rate is the bandwidth in each direction, in bytes per second. Zero is unlimited.
+	Rate int64
+
+	// Latency is the one-way delay added to data in each direction.
+	Latency time.Duration
+
+	// Jitter is the maximum random variation in latency, either side of Latency. Data is never reordered.
+	Jitter time.Duration
+
+	// Loss is the probability, from 0 to 1, that a chunk of data (a Write, or a Read from the underlying
+	// connection) is dropped.
+	Loss float64
+}
+
+// EmulatedConn wraps a net.Conn, impairing it as a LinkProfile describes: a lightweight in-process toxiproxy for
+// integration tests. Impairments apply to both directions, so only one end of a connection needs wrapping.
+//
+// Dropped chunks are lost silently, as on a real lossy link -- so over a stream protocol such as TCP, loss
+// corrupts the stream. It suits message-oriented protocols, and testing how applications cope with bad data.
+//
+// Deadlines are those of the wrapped connection, so they apply before added latency.
+type EmulatedConn struct {
+	net.Conn
+	profile           LinkProfile
+	readLim, writeLim Limiter // nil if unlimited
+	ctx               context.Context
+	cancel            context.CancelFunc
+
+	reads    chan emulatedChunk
+	lastRead time.Time // when the last chunk read is delivered, to prevent reordering
+
+	readMu  sync.Mutex
+	pending []byte // the rest of a chunk partially returned by Read
+	readErr error
+
+	deadlineGen atomic.Int64  // incremented whenever the read deadline changes
+	deadlineSet chan struct{} // signalled whenever the read deadline changes
+
+	sendMu     sync.Mutex // serializes sends on writes, and guards them against Close
+	closed     bool
+	closing    chan struct{} // closed when Close begins, unblocking a Write waiting for space in writes
+	writes     chan emulatedChunk
+	lastSent   time.Time // when the last chunk written is delivered, to prevent reordering
+	writerDone chan struct{}
+	closeOnce  sync.Once
+	linger     time.Duration // how long Close waits for delivery beyond the last chunk's latency
+
+	errMu    sync.Mutex
+	writeErr error
+}
+
+type emulatedChunk struct {
+	data []byte
+	at   time.Time // when the chunk is delivered
+	err  error
+	gen  int64 // the deadlineGen the chunk was read under
+}
+
+// emulatedLinger bounds how long Close waits for written data to be delivered, in case the peer isn't reading.
+const emulatedLinger = 5 * time.Second
+
+// NewEmulatedConn returns conn impaired as described by profile.
+func NewEmulatedConn(conn net.Conn, profile LinkProfile) *EmulatedConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &EmulatedConn{
+		Conn:        conn,
+		profile:     profile,
+		ctx:         ctx,
+		cancel:      cancel,
+		reads:       make(chan emulatedChunk, 64),
+		writes:      make(chan emulatedChunk, 64),
+		writerDone:  make(chan struct{}),
+		closing:     make(chan struct{}),
+		deadlineSet: make(chan struct{}, 1),
+		linger:      emulatedLinger,
+	}
+	if profile.Rate > 0 {
+		c.readLim = NewPacer(profile.Rate)
+		c.writeLim = NewPacer(profile.Rate)
+	}
+
+	go c.readLoop()
+	go c.writeLoop()
+	return c
+}
+
+// delay returns a latency for a chunk, including jitter.
+func (c *EmulatedConn) delay() time.Duration {
+	d := c.profile.Latency
+	if j := c.profile.Jitter; j > 0 {
+		d += time.Duration(rand.Int64N(int64(2*j+1))) - j
+	}
+	return max(d, 0)
+}
+
+// deliverAt returns when a chunk sent now should be delivered, no earlier than the previous chunk.
+func (c *EmulatedConn) deliverAt(last *time.Time) time.Time {
+	at := time.Now().Add(c.delay())
+	if at.Before(*last) {
+		at = *last
+	}
+	*last = at
+	return at
+}
+
+func (c *EmulatedConn) lost() bool {
+	return c.profile.Loss > 0 && rand.Float64() < c.profile.Loss
+}
+
+// readLoop reads from the underlying connection, queueing chunks for delivery to Read. After the read deadline
+// is exceeded, it waits for the deadline to be changed before reading again.
+func (c *EmulatedConn) readLoop() {
+	buf := make([]byte, 32*1024)
+	for {
+		gen := c.deadlineGen.Load()
+		n, err := c.Conn.Read(buf)
+		timeout := errors.Is(err, os.ErrDeadlineExceeded)
+
+		ch := emulatedChunk{err: err, gen: gen}
+		if n > 0 && !c.lost() {
+			ch.data = append([]byte(nil), buf[:n]...)
+		}
+		if ch.data != nil || ch.err != nil {
+			ch.at = c.deliverAt(&c.lastRead)
+			select {
+			case c.reads <- ch:
+			case <-c.ctx.Done():
+				return
+			}
+		}
+
+		if timeout {
+			select {
+			case <-c.deadlineSet:
+			case <-c.ctx.Done():
+				return
+			}
+		} else if err != nil {
+			return
+		}
+	}
+}
+
+func (c *EmulatedConn) Read(p []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	for len(c.pending) == 0 {
+		if c.readErr != nil {
+			return 0, c.readErr
+		}
+
+		var ch emulatedChunk
+		select {
+		case ch = <-c.reads:
+		case <-c.ctx.Done():
+			return 0, net.ErrClosed
+		}
+		if err := sleep(c.ctx, time.Until(ch.at)); err != nil {
+			return 0, net.ErrClosed
+		}
+		c.pending = ch.data
+
+		// A timeout isn't the end of the connection, as the deadline can be changed -- which makes timeouts
+		// from before the change stale.
+		if errors.Is(ch.err, os.ErrDeadlineExceeded) {
+			if len(c.pending) == 0 && ch.gen == c.deadlineGen.Load() {
+				return 0, ch.err
+			}
+			continue
+		}
+		c.readErr = ch.err
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	if c.readLim != nil {
+		if err := c.readLim.Wait(c.ctx, n); err != nil {
+			return n, net.ErrClosed
+		}
+	}
+	return n, nil
+}
+
+// SetDeadline sets the underlying connection's deadlines. See SetReadDeadline.
+func (c *EmulatedConn) SetDeadline(t time.Time) error {
+	defer c.deadlineChanged()
+	return c.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the underlying connection's read deadline. Once exceeded, Read returns
+// os.ErrDeadlineExceeded until the deadline is changed, as with a net.Conn.
+func (c *EmulatedConn) SetReadDeadline(t time.Time) error {
+	defer c.deadlineChanged()
+	return c.Conn.SetReadDeadline(t)
+}
+
+// deadlineChanged resumes readLoop, if it stopped at the previous deadline.
+func (c *EmulatedConn) deadlineChanged() {
+	c.deadlineGen.Add(1)
+	select {
+	case c.deadlineSet <- struct{}{}:
+	default:
+	}
+}
+
+func (c *EmulatedConn) Write(p []byte) (int, error) {
+	c.errMu.Lock()
+	err := c.writeErr
+	c.errMu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	// The time taken to send the bytes at the link's rate, before they set off.
+	if c.writeLim != nil {
+		if err := c.writeLim.Wait(c.ctx, len(p)); err != nil {
+			return 0, net.ErrClosed
+		}
+	}
+	if c.lost() {
+		return len(p), nil
+	}
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+
+	ch := emulatedChunk{data: append([]byte(nil), p...), at: c.deliverAt(&c.lastSent)}
+	select {
+	case c.writes <- ch:
+		return len(p), nil
+	case <-c.writerDone:
+		c.errMu.Lock()
+		defer c.errMu.Unlock()
+		return 0, c.writeErr
+	case <-c.closing:
+		return 0, net.ErrClosed
+	}
+}
+
+// writeLoop delivers written chunks to the underlying connection once their latency has elapsed.
+func (c *EmulatedConn) writeLoop() {
+	defer close(c.writerDone)
+	for ch := range c.writes {
+		if err := sleep(c.ctx, time.Until(ch.at)); err != nil {
+			return
+		}
+		_, err := c.Conn.Write(ch.data)
+		if err != nil {
+			c.errMu.Lock()
+			c.writeErr = err
+			c.errMu.Unlock()
+			return
+		}
+	}
+}
+
+// Close waits for data already written to be delivered, then closes the underlying connection. Like a socket's
+// linger timeout, it gives up waiting five seconds after the last chunk was due, e.g. if the peer isn't reading,
+// and closes the connection regardless.
+func (c *EmulatedConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		// A Write blocked on a full queue holds sendMu, so is released first
+		close(c.closing)
+		c.sendMu.Lock()
+		c.closed = true
+		close(c.writes)
+		due := c.lastSent
+		c.sendMu.Unlock()
+
+		t := time.NewTimer(max(time.Until(due), 0) + c.linger)
+		select {
+		case <-c.writerDone:
+		case <-t.C:
+		}
+		t.Stop()
+
+		// Closing the connection unblocks a write the peer isn't reading
+		c.cancel()
+		err = c.Conn.Close()
+		<-c.writerDone
+	})
+	return err
+}
+
+var _ net.Conn = (*EmulatedConn)(nil)
+
+// NetPipe returns an in-memory, full duplex connection pair like net.Pipe, with data written in either direction
+// charged to lim -- for exercising protocols over a slow link without binding sockets. For independent limits per
+// direction, wrap the ends of a net.Pipe with NewConn instead.
+func NetPipe(lim Limiter) (net.Conn, net.Conn) {
+	a, b := net.Pipe()
+	ctx := context.Background()
+	return NewConn(ctx, a, unlimited{}, lim), NewConn(ctx, b, unlimited{}, lim)
+}
@@ -0,0 +1,192 @@
+package throughput
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEmulatedConn(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile LinkProfile
+		size    int
+		minTime time.Duration
+		maxTime time.Duration
+	}{
+		{"Latency", LinkProfile{Latency: 50 * time.Millisecond}, 100, 50 * time.Millisecond, 150 * time.Millisecond},
+		{"Jitter", LinkProfile{Latency: 50 * time.Millisecond, Jitter: 20 * time.Millisecond}, 100, 30 * time.Millisecond, 150 * time.Millisecond},
+		{"Rate", LinkProfile{Rate: 50 * 1024}, 10 * 1024, 150 * time.Millisecond, 350 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			conn := NewEmulatedConn(client, tt.profile)
+			defer server.Close()
+
+			data := bytes.Repeat([]byte("x"), tt.size)
+			start := time.Now()
+			go func() {
+				for i := 0; i < len(data); i += 1024 {
+					_, _ = conn.Write(data[i:min(i+1024, len(data))])
+				}
+				_ = conn.Close()
+			}()
+
+			got, err := io.ReadAll(server)
+			elapsed := time.Since(start)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("received %d bytes, want %d", len(got), len(data))
+			}
+			if elapsed < tt.minTime || elapsed > tt.maxTime {
+				t.Errorf("took %v, want %v-%v", elapsed, tt.minTime, tt.maxTime)
+			}
+		})
+	}
+}
+
+func TestEmulatedConn_Read(t *testing.T) {
+	client, server := net.Pipe()
+	conn := NewEmulatedConn(client, LinkProfile{Latency: 50 * time.Millisecond})
+	defer conn.Close()
+
+	start := time.Now()
+	go func() {
+		_, _ = server.Write([]byte("hello"))
+		_ = server.Close()
+	}()
+
+	got, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("read %q, want hello", got)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("read took %v, want at least the latency", elapsed)
+	}
+}
+
+func TestEmulatedConn_Loss(t *testing.T) {
+	client, server := net.Pipe()
+	conn := NewEmulatedConn(client, LinkProfile{Loss: 1})
+
+	go func() {
+		n, err := conn.Write([]byte("lost"))
+		if n != 4 || err != nil {
+			t.Errorf("Write() = %d, %v, want lost chunks to appear sent", n, err)
+		}
+		_ = conn.Close()
+	}()
+
+	got, _ := io.ReadAll(server)
+	if len(got) != 0 {
+		t.Errorf("received %q, want nothing", got)
+	}
+}
+
+func TestNetPipe(t *testing.T) {
+	var lim countingLimiter
+	a, b := NetPipe(&lim)
+	defer a.Close()
+	defer b.Close()
+
+	// Writes are charged once the peer has read them
+	done := make(chan struct{})
+	go func() {
+		_, _ = a.Write(make([]byte, 100))
+		_, _ = b.Write(make([]byte, 50))
+		close(done)
+	}()
+	if _, err := io.ReadFull(b, make([]byte, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(a, make([]byte, 50)); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	if n := lim.n.Load(); n != 150 {
+		t.Errorf("limiter was charged %d bytes, want 150", n)
+	}
+}
+
+func TestEmulatedConn_CloseUnread(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := NewEmulatedConn(client, LinkProfile{})
+	conn.linger = 50 * time.Millisecond
+
+	// Nothing reads from server, so the write to the underlying connection blocks
+	_, _ = conn.Write([]byte("hello"))
+
+	done := make(chan struct{})
+	go func() {
+		_ = conn.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close didn't return whilst the peer wasn't reading")
+	}
+}
+
+func TestEmulatedConn_CloseQueueFull(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := NewEmulatedConn(client, LinkProfile{})
+	conn.linger = 50 * time.Millisecond
+
+	// Nothing reads from server, so writes fill the queue and then block
+	go func() {
+		for {
+			if _, err := conn.Write([]byte("hello")); err != nil {
+				return
+			}
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		_ = conn.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close didn't return whilst a Write was blocked on a full queue")
+	}
+}
+
+func TestEmulatedConn_ReadDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := NewEmulatedConn(client, LinkProfile{})
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	if _, err := conn.Read(make([]byte, 5)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Read past the deadline returned %v, want os.ErrDeadlineExceeded", err)
+	}
+
+	// Clearing the deadline makes the connection usable again
+	_ = conn.SetReadDeadline(time.Time{})
+	go func() {
+		_, _ = server.Write([]byte("hello"))
+	}()
+	buf := make([]byte, 5)
+	if n, err := conn.Read(buf); err != nil || string(buf[:n]) != "hello" {
+		t.Errorf("Read after clearing the deadline returned %q, %v, want hello", buf[:n], err)
+	}
+}
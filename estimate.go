@@ -0,0 +1,74 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// Estimate is the throughput observed by EstimateBandwidth.
+type Estimate struct {
+	Bytes   int64
+	Elapsed time.Duration
+}
+
+// Rate returns the observed throughput in bytes per second.
+func (e Estimate) Rate() float64 {
+	if e.Elapsed <= 0 {
+		return 0
+	}
+	return float64(e.Bytes) / e.Elapsed.Seconds()
+}
+
+// Recommend returns a limit using fraction of the observed throughput, e.g. 0.8 to leave 20% headroom for other
+// traffic.
+func (e Estimate) Recommend(fraction float64) int64 {
+	return int64(e.Rate() * fraction)
+}
+
+// EstimateBandwidth measures the achievable throughput between src and dst by copying from one to the other for
+// up to dur, or until src is exhausted. This suits "auto" speed settings, e.g. sampling an upload to pick a limit
+// which leaves headroom for other traffic.
+//
+// If lim is non-nil, the copy is throttled by it, so the sample itself doesn't saturate the link. A read blocked
+// beyond dur delays the result until it returns, so src should honour deadlines or ctx where possible.
+func EstimateBandwidth(ctx context.Context, dst io.Writer, src io.Reader, dur time.Duration, lim Limiter) (Estimate, error) {
+	ctx, cancel := context.WithTimeout(ctx, dur)
+	defer cancel()
+
+	if lim != nil {
+		src = NewReader(ctx, src, lim)
+	}
+
+	buf := make([]byte, 32*1024)
+	start := time.Now()
+	var e Estimate
+	for ctx.Err() == nil {
+		n, err := src.Read(buf)
+		if errors.Is(err, context.DeadlineExceeded) {
+			// The sampling period ended whilst waiting on lim, so these bytes weren't achieved within it.
+			break
+		}
+		if n > 0 {
+			_, werr := dst.Write(buf[:n])
+			if werr != nil {
+				return e, werr
+			}
+			e.Bytes += int64(n)
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return e, err
+		}
+	}
+	e.Elapsed = time.Since(start)
+
+	// The sampling period ending isn't an error, but the caller's context being done is.
+	if cerr := context.Cause(ctx); cerr != nil && !errors.Is(cerr, context.DeadlineExceeded) {
+		return e, cerr
+	}
+	return e, nil
+}
@@ -0,0 +1,45 @@
+package throughput
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestEstimateBandwidth(t *testing.T) {
+	lim := NewTokenBucket(512*1024, 0)
+	e, err := EstimateBandwidth(context.Background(), io.Discard, zeroReader{}, 200*time.Millisecond, lim)
+	if err != nil {
+		t.Fatalf("EstimateBandwidth: %v", err)
+	}
+
+	if rate := e.Rate(); rate < 400*1024 || rate > 600*1024 {
+		t.Errorf("Rate() = %.0f, want ~512 KiB/s", rate)
+	}
+	if got := e.Recommend(0.5); got < 200*1024 || got > 300*1024 {
+		t.Errorf("Recommend(0.5) = %d, want ~256 KiB/s", got)
+	}
+}
+
+func TestEstimateBandwidth_EOF(t *testing.T) {
+	var dst bytes.Buffer
+	e, err := EstimateBandwidth(context.Background(), &dst, bytes.NewReader(make([]byte, 1000)), time.Second, nil)
+	if err != nil {
+		t.Fatalf("EstimateBandwidth: %v", err)
+	}
+	if e.Bytes != 1000 || dst.Len() != 1000 {
+		t.Errorf("copied %d bytes (%d written), want 1000", e.Bytes, dst.Len())
+	}
+	if e.Elapsed >= time.Second {
+		t.Errorf("Elapsed = %v, want to stop at EOF", e.Elapsed)
+	}
+}
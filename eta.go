@@ -0,0 +1,140 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ETA estimates the time remaining for a transfer of a known size, for progress UIs.
+//
+// The rate is averaged over a sliding window rather than taken instantaneously, so the estimate is steady
+// despite bursty reads, but tracks a rate change -- a limit being raised, or the link slowing -- once it has
+// persisted for the window. A stall lengthens the estimate as it goes on.
+//
+// Like Meter, ETA implements Limiter without ever delaying, so it can be fed by chaining it with the transfer's
+// limiter, e.g. Chain(lim, eta).
+type ETA struct {
+	total  int64
+	window time.Duration
+	w      waiter
+	start  time.Time
+
+	mu      sync.Mutex
+	bytes   int64
+	samples []etaSample // samples[0] is the last at or before the window's start
+}
+
+type etaSample struct {
+	at    time.Time
+	bytes int64
+}
+
+// NewETA returns an estimator for a transfer of total bytes, averaging the rate over window. A total of zero or
+// less means the size is unknown, so only the rate can be estimated.
+func NewETA(total int64, window time.Duration, opts ...Option) *ETA {
+	e := &ETA{total: total, window: window, w: newWaiter(opts)}
+	e.start = e.w.now()
+	e.samples = []etaSample{{at: e.start}}
+	return e
+}
+
+// Wait records n bytes, and returns immediately.
+func (e *ETA) Wait(_ context.Context, n int) error {
+	e.Add(int64(n))
+	return nil
+}
+
+// Add records n bytes of progress.
+func (e *ETA) Add(n int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.w.now()
+	e.bytes += n
+	e.prune(now)
+
+	// Samples closer together than a 20th of the window are merged, bounding their number.
+	if last := len(e.samples) - 1; last > 0 && now.Sub(e.samples[last-1].at) < e.window/20 {
+		e.samples[last] = etaSample{at: now, bytes: e.bytes}
+		return
+	}
+	e.samples = append(e.samples, etaSample{at: now, bytes: e.bytes})
+}
+
+// prune discards samples which are no longer needed to measure the window ending now. Must be called with mu held.
+func (e *ETA) prune(now time.Time) {
+	start := now.Add(-e.window)
+	i := 0
+	for i+1 < len(e.samples) && !e.samples[i+1].at.After(start) {
+		i++
+	}
+	e.samples = e.samples[i:]
+}
+
+// Total returns the size of the transfer, as given to NewETA.
+func (e *ETA) Total() int64 {
+	return e.total
+}
+
+// Elapsed returns the time since the ETA was created.
+func (e *ETA) Elapsed() time.Duration {
+	return e.w.now().Sub(e.start)
+}
+
+// Bytes returns the bytes of progress recorded.
+func (e *ETA) Bytes() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.bytes
+}
+
+// Rate returns the average rate over the window, in bytes per second.
+func (e *ETA) Rate() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.rate(e.w.now())
+}
+
+// rate must be called with mu held.
+func (e *ETA) rate(now time.Time) float64 {
+	e.prune(now)
+	elapsed := min(now.Sub(e.start), e.window)
+	if elapsed <= 0 {
+		return 0
+	}
+	// Bytes recorded after samples[0] were transferred within the window.
+	return float64(e.bytes-e.samples[0].bytes) / elapsed.Seconds()
+}
+
+// Remaining returns the estimated time until the transfer completes. It returns false if no estimate can be
+// made, as the size is unknown or nothing has been transferred in the window.
+func (e *ETA) Remaining() (time.Duration, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.total <= 0 {
+		return 0, false
+	}
+	left := e.total - e.bytes
+	if left <= 0 {
+		return 0, true
+	}
+	rate := e.rate(e.w.now())
+	if rate <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(left) / rate * float64(time.Second)), true
+}
+
+// Fraction returns the fraction of the transfer completed, from 0 to 1, or 0 if the size is unknown.
+func (e *ETA) Fraction() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.total <= 0 {
+		return 0
+	}
+	return min(float64(e.bytes)/float64(e.total), 1)
+}
+
+var _ Limiter = (*ETA)(nil)
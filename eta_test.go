@@ -0,0 +1,42 @@
+package throughput
+
+import (
+	"testing"
+	"time"
+)
+
+func TestETA(t *testing.T) {
+	clock := NewVirtualClock(time.Unix(0, 0))
+	e := NewETA(20_000, 2*time.Second, WithClock(clock))
+	if _, ok := e.Remaining(); ok {
+		t.Error("Remaining returned an estimate before any progress")
+	}
+
+	// 1000 bytes/sec, in bursts
+	for range 4 {
+		clock.Advance(500 * time.Millisecond)
+		e.Add(1000)
+		clock.Advance(500 * time.Millisecond)
+	}
+	if d, ok := e.Remaining(); !ok || d != 16*time.Second {
+		t.Errorf("Remaining returned %s, %t, want 16s", d, ok)
+	}
+
+	// Once the rate has doubled for the window, so has the estimate's
+	for range 4 {
+		clock.Advance(500 * time.Millisecond)
+		e.Add(1000)
+	}
+	if d, ok := e.Remaining(); !ok || d != 6*time.Second {
+		t.Errorf("Remaining returned %s, %t, want 6s", d, ok)
+	}
+	if f := e.Fraction(); f != 0.4 {
+		t.Errorf("Fraction returned %v, want 0.4", f)
+	}
+
+	// A stall lengthens the estimate
+	clock.Advance(time.Second)
+	if d, _ := e.Remaining(); d <= 6*time.Second {
+		t.Errorf("Remaining returned %s during a stall, want more than 6s", d)
+	}
+}
@@ -0,0 +1,128 @@
+package throughput
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// idleGrace is how long a stream remains active after its last wait ends, covering the I/O between waits.
+const idleGrace = 100 * time.Millisecond
+
+// FairShare divides a rate between registered streams in proportion to their weights, regardless of the size of
+// their reads or writes.
+//
+// Left to itself, how a shared limiter divides bandwidth between concurrent waiters is generally unspecified.
+// FairShare instead uses the Virtual Clock algorithm: each active stream is paced at rate * weight / (the sum of
+// active streams' weights). A stream is active whilst it is waiting or has recently waited, so the bandwidth of
+// idle streams is shared amongst the rest.
+type FairShare struct {
+	w waiter
+
+	mu      sync.Mutex
+	rate    float64 // bytes per second
+	streams map[*FairStream]struct{}
+}
+
+// NewFairShare returns a FairShare dividing bytesPerSec between its streams.
+func NewFairShare(bytesPerSec int64, opts ...Option) *FairShare {
+	return &FairShare{
+		w:       newWaiter(opts),
+		rate:    float64(bytesPerSec),
+		streams: make(map[*FairStream]struct{}),
+	}
+}
+
+// NewStream registers a stream with the given weight, which must be positive, and returns its limiter.
+// The stream should be closed once it is no longer needed.
+func (f *FairShare) NewStream(weight float64) *FairStream {
+	s := &FairStream{f: f, weight: weight}
+
+	f.mu.Lock()
+	f.streams[s] = struct{}{}
+	f.mu.Unlock()
+	return s
+}
+
+// Limit returns the rate shared between streams, in bytes per second.
+func (f *FairShare) Limit() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return int64(f.rate)
+}
+
+// SetLimit changes the rate shared between streams to bytesPerSec.
+func (f *FairShare) SetLimit(bytesPerSec int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rate = float64(bytesPerSec)
+}
+
+// activeWeight returns the sum of the weights of active streams. Must be called with mu held.
+func (f *FairShare) activeWeight(now time.Time) float64 {
+	var total float64
+	for s := range f.streams {
+		if now.Before(s.vc.Add(idleGrace)) {
+			total += s.weight
+		}
+	}
+	return total
+}
+
+// FairStream is a limiter for one stream of a FairShare.
+type FairStream struct {
+	f      *FairShare
+	weight float64
+
+	// Guarded by f.mu
+	vc     time.Time // the stream's virtual clock: when the bytes charged so far have been paid for
+	closed bool
+}
+
+func (s *FairStream) Wait(ctx context.Context, n int) error {
+	f := s.f
+
+	f.mu.Lock()
+	if s.closed {
+		f.mu.Unlock()
+		return ErrClosed
+	}
+
+	// Bringing an idle stream's clock up to now also makes it count as active.
+	now := f.w.now()
+	if s.vc.Before(now) {
+		s.vc = now
+	}
+	weight := f.activeWeight(now)
+
+	var cost time.Duration
+	if f.rate <= 0 {
+		cost = math.MaxInt64
+	} else {
+		cost = secondsToDuration(float64(n) * weight / (s.weight * f.rate))
+	}
+	s.vc = s.vc.Add(cost)
+	delay := s.vc.Sub(now)
+	f.mu.Unlock()
+
+	err := f.w.sleep(ctx, delay)
+	if err != nil {
+		f.mu.Lock()
+		s.vc = s.vc.Add(-cost)
+		f.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Close unregisters the stream, so its share is divided amongst the others.
+func (s *FairStream) Close() error {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	s.closed = true
+	delete(s.f.streams, s)
+	return nil
+}
+
+var _ Limiter = (*FairStream)(nil)
@@ -0,0 +1,58 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFairShareWeights(t *testing.T) {
+	f := NewFairShare(64 * 1024)
+	heavy := f.NewStream(2)
+	light := f.NewStream(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	// The light stream uses larger waits, which would favour it if the limiter were raced for.
+	var wg sync.WaitGroup
+	var heavyBytes, lightBytes int
+	run := func(s *FairStream, n int, total *int) {
+		defer wg.Done()
+		for s.Wait(ctx, n) == nil {
+			*total += n
+		}
+	}
+	wg.Add(2)
+	go run(heavy, 256, &heavyBytes)
+	go run(light, 1024, &lightBytes)
+	wg.Wait()
+
+	ratio := float64(heavyBytes) / float64(lightBytes)
+	if ratio < 1.5 || ratio > 2.5 {
+		t.Errorf("heavy:light = %d:%d (%.2f), want ~2", heavyBytes, lightBytes, ratio)
+	}
+
+	// Together, the streams should use the whole rate
+	total := heavyBytes + lightBytes
+	if total < 16*1024 || total > 24*1024 {
+		t.Errorf("streams transferred %d bytes in 300ms, want ~19KB", total)
+	}
+}
+
+func TestFairShareIdleStream(t *testing.T) {
+	f := NewFairShare(10 * 1024)
+	a := f.NewStream(1)
+	_ = f.NewStream(1) // registered, but idle
+
+	// An idle stream's share is available to the active one
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		_ = a.Wait(context.Background(), 100)
+	}
+	err := verifyWithSlop(time.Since(start), 100*time.Millisecond, 20*time.Millisecond)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
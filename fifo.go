@@ -0,0 +1,28 @@
+package throughput
+
+import "context"
+
+// FIFOLimiter wraps a shared limiter so that waiters are admitted in the order they arrived.
+//
+// Some limiters admit whichever waiter happens to fit when capacity frees up, which allows a goroutine doing
+// large reads to be starved by goroutines doing small reads, or vice versa. FIFOLimiter queues waits, and waits
+// on the wrapped limiter for one at a time, so no waiter can be overtaken.
+type FIFOLimiter struct {
+	s scheduler
+}
+
+// NewFIFOLimiter returns a limiter which admits waiters to lim in FIFO order.
+func NewFIFOLimiter(lim Limiter) *FIFOLimiter {
+	return &FIFOLimiter{s: scheduler{lim: lim}}
+}
+
+func (f *FIFOLimiter) Wait(ctx context.Context, n int) error {
+	return f.s.wait(ctx, n, fifoKey)
+}
+
+// fifoKey gives every waiter the same key, so they are ordered by arrival alone.
+func fifoKey() float64 {
+	return 0
+}
+
+var _ Limiter = (*FIFOLimiter)(nil)
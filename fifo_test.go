@@ -0,0 +1,93 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFIFOLimiterOrder(t *testing.T) {
+	lim := NewFIFOLimiter(NewPacer(100 * 1024))
+
+	var mu sync.Mutex
+	var order []int
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = lim.Wait(context.Background(), 1024)
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		}()
+		// Ensure goroutines queue in order
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("admitted in order %v, want FIFO", order)
+		}
+	}
+}
+
+func TestFIFOLimiterStarvation(t *testing.T) {
+	// HTB classes admit whichever waiter fits when tokens become available, so a large wait can be starved by a
+	// steady stream of small ones.
+	htb := NewHTB(10*1024, 2048)
+	lim := NewFIFOLimiter(htb.NewClass("shared", 10*1024, 10*1024, 2048))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for lim.Wait(ctx, 64) == nil {
+			}
+		}()
+	}
+
+	// Let the small waiters drain the burst capacity, then 2KB at 10KB/sec, plus a turn for each small waiter
+	// queued ahead.
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	_ = lim.Wait(context.Background(), 2048)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("large wait took %s, expected no starvation", elapsed)
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestFIFOLimiterCancelWhileQueued(t *testing.T) {
+	lim := NewFIFOLimiter(NewPacer(1024))
+
+	go func() { _ = lim.Wait(context.Background(), 100) }()
+	time.Sleep(5 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := lim.Wait(ctx, 1); err == nil {
+		t.Error("expected queued wait to be cancelled")
+	}
+
+	// The queue must not be left stuck by the cancelled waiter
+	done := make(chan struct{})
+	go func() {
+		_ = lim.Wait(context.Background(), 1)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("queue stuck after cancellation")
+	}
+}
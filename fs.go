@@ -0,0 +1,78 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// NewFS returns a file system whose files are rate-limited by lim when read, e.g. to serve an embedded or on-disk
+// tree at a bounded rate with http.FileServerFS. Every file opened shares lim.
+//
+// Files support Seek, ReadAt and ReadDir if the underlying file does, and otherwise return errors.ErrUnsupported.
+func NewFS(ctx context.Context, fsys fs.FS, lim Limiter) fs.FS {
+	return &limitedFS{ctx: ctx, fsys: fsys, lim: lim}
+}
+
+type limitedFS struct {
+	ctx  context.Context
+	fsys fs.FS
+	lim  Limiter
+}
+
+func (l *limitedFS) Open(name string) (fs.File, error) {
+	f, err := l.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedFile{File: f, r: NewReader(l.ctx, f, l.lim)}, nil
+}
+
+type limitedFile struct {
+	fs.File
+	r *Reader
+}
+
+func (f *limitedFile) Read(p []byte) (int, error) {
+	return f.r.Read(p)
+}
+
+func (f *limitedFile) ReadAt(p []byte, off int64) (int, error) {
+	ra, ok := f.File.(io.ReaderAt)
+	if !ok {
+		return 0, errors.ErrUnsupported
+	}
+	n, err := ra.ReadAt(p, off)
+	if n > 0 {
+		if werr := f.r.wait(f.r.ctx, "read", n); err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+func (f *limitedFile) Seek(offset int64, whence int) (int64, error) {
+	s, ok := f.File.(io.Seeker)
+	if !ok {
+		return 0, errors.ErrUnsupported
+	}
+	return s.Seek(offset, whence)
+}
+
+func (f *limitedFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	d, ok := f.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return d.ReadDir(n)
+}
+
+// Close unblocks any reads waiting on the limiter, and closes the file.
+func (f *limitedFile) Close() error {
+	_ = f.r.close(nil)
+	return f.File.Close()
+}
+
+var _ fs.ReadDirFile = (*limitedFile)(nil)
+var _ io.ReadSeeker = (*limitedFile)(nil)
@@ -0,0 +1,41 @@
+package throughput
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFS(t *testing.T) {
+	files := fstest.MapFS{
+		"a.txt":     {Data: make([]byte, 1000)},
+		"dir/b.txt": {Data: make([]byte, 1000)},
+	}
+	if err := fstest.TestFS(NewFS(context.Background(), files, NewTokenBucket(1<<40, 1<<40)), "a.txt", "dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files share the limiter
+	clock := NewVirtualClock(time.Unix(0, 0))
+	fsys := NewFS(context.Background(), files, NewTokenBucket(1000, 0, WithClock(clock)))
+	for _, name := range []string{"a.txt", "dir/b.txt"} {
+		if _, err := fs.ReadFile(fsys, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 2*time.Second {
+		t.Errorf("2000 bytes at 1000 bytes/sec took %s, want 2s", elapsed)
+	}
+
+	// Works with http.FileServerFS, which needs Seek
+	rec := httptest.NewRecorder()
+	http.FileServerFS(fsys).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/a.txt", nil))
+	if body, _ := io.ReadAll(rec.Body); rec.Code != http.StatusOK || len(body) != 1000 {
+		t.Errorf("FileServerFS returned %d with %d bytes, want 200 with 1000", rec.Code, len(body))
+	}
+}
@@ -0,0 +1,78 @@
+package throughput
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Gate packages the two controls transfer services need together: a cap on concurrent transfers, and a shared
+// limiter their bytes are charged to. Starting a transfer takes a slot, and the bytes it moves are charged to the
+// limiter through the Transfer returned, until it's done.
+type Gate struct {
+	adm *Admission
+	lim Limiter
+}
+
+// NewGate returns a gate admitting at most maxTransfers at once, all charged to lim.
+func NewGate(maxTransfers int, lim Limiter) *Gate {
+	return &Gate{adm: NewAdmission(maxTransfers), lim: lim}
+}
+
+// Start waits for a slot, and returns the transfer which holds it. Done must be called once the transfer is
+// complete.
+func (g *Gate) Start(ctx context.Context) (*Transfer, error) {
+	release, err := g.adm.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Transfer{lim: g.lim, release: release}, nil
+}
+
+// TryStart is Start, returning ErrTooManyTransfers rather than waiting if no slot is free.
+func (g *Gate) TryStart() (*Transfer, error) {
+	release, err := g.adm.TryAcquire()
+	if err != nil {
+		return nil, err
+	}
+	return &Transfer{lim: g.lim, release: release}, nil
+}
+
+// Do starts a transfer, calls f with it, and then releases the transfer's slot.
+func (g *Gate) Do(ctx context.Context, f func(t *Transfer) error) error {
+	t, err := g.Start(ctx)
+	if err != nil {
+		return err
+	}
+	defer t.Done()
+	return f(t)
+}
+
+// Admission returns the gate's Admission, e.g. to report the transfers active and queued.
+func (g *Gate) Admission() *Admission {
+	return g.adm
+}
+
+// Transfer is a limiter charging the shared limiter of the Gate it was started from, whilst holding one of its
+// slots. Use it with NewReader, NewWriter or Copy.
+type Transfer struct {
+	lim     Limiter
+	release func()
+	done    atomic.Bool
+}
+
+// Wait charges n bytes to the gate's limiter. It returns ErrClosed once the transfer is done.
+func (t *Transfer) Wait(ctx context.Context, n int) error {
+	if t.done.Load() {
+		return ErrClosed
+	}
+	return t.lim.Wait(ctx, n)
+}
+
+// Done releases the transfer's slot. Subsequent calls to Wait return ErrClosed.
+func (t *Transfer) Done() {
+	if !t.done.Swap(true) {
+		t.release()
+	}
+}
+
+var _ Limiter = (*Transfer)(nil)
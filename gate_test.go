@@ -0,0 +1,44 @@
+package throughput
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestGate(t *testing.T) {
+	var lim countingLimiter
+	g := NewGate(1, &lim)
+
+	err := g.Do(context.Background(), func(tr *Transfer) error {
+		if g.Admission().Active() != 1 {
+			t.Errorf("got %d active during the transfer, want 1", g.Admission().Active())
+		}
+		if _, err := g.TryStart(); !errors.Is(err, ErrTooManyTransfers) {
+			t.Errorf("TryStart returned %v, want ErrTooManyTransfers", err)
+		}
+		_, err := Copy(context.Background(), io.Discard, bytes.NewReader(make([]byte, 100)), tr)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lim.n.Load() != 100 {
+		t.Errorf("limiter was charged %d bytes, want 100", lim.n.Load())
+	}
+	if g.Admission().Active() != 0 {
+		t.Errorf("got %d active after the transfer, want 0", g.Admission().Active())
+	}
+
+	tr, err := g.TryStart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.Done()
+	if err := tr.Wait(context.Background(), 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("Wait after Done returned %v, want ErrClosed", err)
+	}
+}
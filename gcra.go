@@ -0,0 +1,108 @@
+package throughput
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// GCRALimiter implements the Generic Cell Rate Algorithm (virtual scheduling), an alternative to the token bucket.
+//
+// Rather than counting tokens, GCRA tracks a single theoretical arrival time (TAT): the time at which the
+// limiter would be idle if all bytes so far had been sent at exactly the configured rate. Each Wait is constant
+// time, n is unbounded, and as the TAT is absolute there is no accumulation of timer drift -- which makes for
+// smoother pacing at very high rates.
+type GCRALimiter struct {
+	mu       sync.Mutex
+	interval float64 // nanoseconds per byte
+	burst    int64
+	tat      time.Time
+	w        waiter
+}
+
+// NewGCRALimiter returns a limiter allowing bytesPerSec, with a burst tolerance of burst bytes.
+// Like NewBytesPerSecLimiter, the burst is available immediately.
+func NewGCRALimiter(bytesPerSec int64, burst int64, opts ...Option) *GCRALimiter {
+	g := &GCRALimiter{burst: burst, w: newWaiter(opts)}
+	g.SetLimit(bytesPerSec)
+	return g
+}
+
+// NewPacer returns a limiter that spaces bytes evenly at bytesPerSec with no burst tolerance: the gap after each
+// Wait is proportional to n. This suits latency-sensitive streaming, where even a 1-second burst is unacceptable.
+//
+// Pacing is relative to the previous Wait rather than wall time, so an idle pacer doesn't accumulate allowance.
+func NewPacer(bytesPerSec int64, opts ...Option) *GCRALimiter {
+	return NewGCRALimiter(bytesPerSec, 0, opts...)
+}
+
+func (g *GCRALimiter) Wait(ctx context.Context, n int) error {
+	err := g.w.sleep(ctx, g.reserve(n))
+	if err != nil {
+		g.Refund(n)
+		return err
+	}
+	return nil
+}
+
+// Reserve charges n bytes without waiting, and returns how long the caller must wait before proceeding.
+func (g *GCRALimiter) Reserve(n int) (delay time.Duration, cancel func()) {
+	return g.reserve(n), func() { g.Refund(n) }
+}
+
+func (g *GCRALimiter) reserve(n int) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if math.IsInf(g.interval, 1) {
+		// A zero rate never allows any bytes
+		return math.MaxInt64
+	}
+
+	now := g.w.now()
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	g.tat = tat.Add(time.Duration(float64(n) * g.interval))
+
+	// Bytes may proceed once the TAT is within the burst tolerance of now.
+	tolerance := time.Duration(float64(g.burst) * g.interval)
+	return g.tat.Sub(now) - tolerance
+}
+
+// Refund returns n bytes of capacity, e.g. when a transfer they were charged for didn't happen.
+func (g *GCRALimiter) Refund(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !math.IsInf(g.interval, 1) {
+		g.tat = g.tat.Add(-time.Duration(float64(n) * g.interval))
+	}
+}
+
+// Limit returns the current rate in bytes per second.
+func (g *GCRALimiter) Limit() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if math.IsInf(g.interval, 1) {
+		return 0
+	}
+	return int64(float64(time.Second) / g.interval)
+}
+
+// SetLimit changes the rate to bytesPerSec. The burst tolerance, in bytes, is unchanged.
+// A rate of zero blocks all calls to Wait until their context is done.
+func (g *GCRALimiter) SetLimit(bytesPerSec int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if bytesPerSec <= 0 {
+		g.interval = math.Inf(1)
+		return
+	}
+	g.interval = float64(time.Second) / float64(bytesPerSec)
+}
+
+var _ AdjustableLimiter = (*GCRALimiter)(nil)
+var _ Refunder = (*GCRALimiter)(nil)
+var _ Reserver = (*GCRALimiter)(nil)
@@ -0,0 +1,80 @@
+package throughput
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGCRALimiter(t *testing.T) {
+	// Burst is available immediately, then bytes are paced at the configured rate.
+	lim := NewGCRALimiter(10*1024, 1024)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := lim.Wait(context.Background(), 512); err != nil {
+			t.Fatalf("wait: %s", err)
+		}
+	}
+
+	// 2.5KB at 10KB/sec, less the 1KB burst
+	err := verifyWithSlop(time.Since(start), 150*time.Millisecond, 30*time.Millisecond)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGCRALimiterClock(t *testing.T) {
+	clock := &stepClock{now: time.Unix(0, 0)}
+	lim := NewGCRALimiter(1000, 100, WithClock(clock))
+
+	for i := 0; i < 5; i++ {
+		_ = lim.Wait(context.Background(), 100)
+	}
+
+	// 500 bytes at 1000 bytes/sec, less the 100 byte burst tolerance
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 400*time.Millisecond {
+		t.Errorf("took %s, want 400ms", elapsed)
+	}
+}
+
+func TestGCRALimiterCancelRefunds(t *testing.T) {
+	lim := NewGCRALimiter(1024, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := lim.Wait(ctx, 10*1024); err == nil {
+		t.Fatal("expected wait to be cancelled")
+	}
+
+	// The cancelled 10KB should have been refunded, so a small wait is not delayed by it.
+	start := time.Now()
+	_ = lim.Wait(context.Background(), 10)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("wait after cancel took %s, expected refund", elapsed)
+	}
+}
+
+func TestGCRALimiterSetLimit(t *testing.T) {
+	lim := NewGCRALimiter(1024, 0)
+	lim.SetLimit(4096)
+	if got := lim.Limit(); got != 4096 {
+		t.Errorf("Limit() = %d, want 4096", got)
+	}
+}
+
+func TestPacer(t *testing.T) {
+	lim := NewPacer(1000)
+
+	// Gaps are proportional to n, with no burst allowance even after idling.
+	time.Sleep(50 * time.Millisecond)
+	for _, n := range []int{10, 50, 20} {
+		start := time.Now()
+		_ = lim.Wait(context.Background(), n)
+		want := time.Duration(n) * time.Millisecond
+		err := verifyWithSlop(time.Since(start), want, 10*time.Millisecond)
+		if err != nil {
+			t.Errorf("n=%d: %s", n, err)
+		}
+	}
+}
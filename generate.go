@@ -0,0 +1,97 @@
+package throughput
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math/rand/v2"
+)
+
+// GeneratorFill fills p with generated data. offset is the position of p[0] in the generated stream.
+type GeneratorFill func(p []byte, offset int64)
+
+// ZeroFill generates zero bytes.
+func ZeroFill(p []byte, _ int64) {
+	clear(p)
+}
+
+// RandomFill generates pseudo-random bytes, which are incompressible -- so links which compress data, such as
+// some VPNs, can't inflate the apparent throughput.
+func RandomFill(p []byte, _ int64) {
+	for len(p) >= 8 {
+		binary.LittleEndian.PutUint64(p, rand.Uint64())
+		p = p[8:]
+	}
+	for i := range p {
+		p[i] = byte(rand.Uint32())
+	}
+}
+
+// PatternFill generates pattern repeated, e.g. so a receiver can verify the data it was sent.
+func PatternFill(pattern []byte) GeneratorFill {
+	return func(p []byte, offset int64) {
+		if len(pattern) == 0 {
+			clear(p)
+			return
+		}
+		for i := range p {
+			p[i] = pattern[(offset+int64(i))%int64(len(pattern))]
+		}
+	}
+}
+
+// Generator is an io.Reader producing data at a fixed rate, e.g. to load test a server or to check that shaping
+// downstream is working. Bytes are paced evenly, with no initial burst.
+type Generator struct {
+	ctx    context.Context
+	lim    Limiter
+	fill   GeneratorFill
+	chunk  int
+	size   int64 // negative if unbounded
+	offset int64
+}
+
+// NewGenerator returns a reader producing size bytes at bytesPerSec, filled by fill, then io.EOF.
+// A size of zero or less is unbounded. A nil fill is treated as ZeroFill.
+// Reads return ctx.Err() once ctx is done.
+func NewGenerator(ctx context.Context, bytesPerSec, size int64, fill GeneratorFill, opts ...Option) *Generator {
+	if fill == nil {
+		fill = ZeroFill
+	}
+	if size <= 0 {
+		size = -1
+	}
+	return &Generator{
+		ctx:  ctx,
+		lim:  NewPacer(bytesPerSec, opts...),
+		fill: fill,
+		// Reads are capped at 20ms worth of data, so large buffers don't make the output bursty.
+		chunk: int(max(bytesPerSec/50, 1)),
+		size:  size,
+	}
+}
+
+func (g *Generator) Read(p []byte) (int, error) {
+	if g.size >= 0 && g.offset >= g.size {
+		return 0, io.EOF
+	}
+
+	n := min(len(p), g.chunk)
+	if g.size >= 0 {
+		n = int(min(int64(n), g.size-g.offset))
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	err := g.lim.Wait(g.ctx, n)
+	if err != nil {
+		return 0, err
+	}
+
+	g.fill(p[:n], g.offset)
+	g.offset += int64(n)
+	return n, nil
+}
+
+var _ io.Reader = (*Generator)(nil)
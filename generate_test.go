@@ -0,0 +1,44 @@
+package throughput
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestGenerator(t *testing.T) {
+	clock := NewVirtualClock(time.Unix(0, 0))
+	g := NewGenerator(context.Background(), 1000, 5000, PatternFill([]byte("abc")), WithClock(clock))
+
+	got, err := io.ReadAll(g)
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	if want := bytes.Repeat([]byte("abc"), 2000)[:5000]; !bytes.Equal(got, want) {
+		t.Error("generated data doesn't repeat the pattern")
+	}
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 5*time.Second {
+		t.Errorf("5000 bytes at 1000 bytes/sec took %s, want 5s", elapsed)
+	}
+}
+
+func TestGeneratorUnbounded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := NewGenerator(ctx, 64*1024, 0, RandomFill)
+
+	p := make([]byte, 4096)
+	n, err := g.Read(p)
+	if err != nil || n != 64*1024/50 {
+		t.Fatalf("Read returned %d, %v; want a 20ms chunk", n, err)
+	}
+	if bytes.Count(p[:n], []byte{0}) == n {
+		t.Error("RandomFill produced only zeros")
+	}
+
+	cancel()
+	if _, err := g.Read(p); err != context.Canceled {
+		t.Errorf("Read after cancel returned %v, want context.Canceled", err)
+	}
+}
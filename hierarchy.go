@@ -0,0 +1,109 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned when waiting on a limiter (or stream) which has been closed.
+var ErrClosed = errors.New("throughput: closed")
+
+// Hierarchy is a parent budget that child limiters draw from, e.g. 10 MiB/s in total, with at most 2 MiB/s per
+// tenant. Children can be created and closed at runtime, and the bytes charged through them are rolled up to
+// the parent.
+type Hierarchy struct {
+	parent Limiter
+	bytes  atomic.Int64
+
+	mu       sync.Mutex
+	children map[*HierarchyChild]struct{}
+}
+
+// NewHierarchy returns a hierarchy where every child also draws from parent.
+func NewHierarchy(parent Limiter) *Hierarchy {
+	return &Hierarchy{
+		parent:   parent,
+		children: make(map[*HierarchyChild]struct{}),
+	}
+}
+
+// NewChild returns a limiter which charges lim and then the parent. A nil lim means the child is only
+// limited by the parent. The child should be closed once it is no longer needed.
+func (h *Hierarchy) NewChild(name string, lim Limiter) *HierarchyChild {
+	c := &HierarchyChild{h: h, name: name, lim: h.parent}
+	if lim != nil {
+		c.lim = Chain(lim, h.parent)
+	}
+
+	h.mu.Lock()
+	h.children[c] = struct{}{}
+	h.mu.Unlock()
+	return c
+}
+
+// Children returns the children which have not been closed, in no particular order.
+func (h *Hierarchy) Children() []*HierarchyChild {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	children := make([]*HierarchyChild, 0, len(h.children))
+	for c := range h.children {
+		children = append(children, c)
+	}
+	return children
+}
+
+// Bytes returns the total bytes charged through all children, including those since closed.
+func (h *Hierarchy) Bytes() int64 {
+	return h.bytes.Load()
+}
+
+// HierarchyChild is a limiter drawing from a Hierarchy's parent budget.
+type HierarchyChild struct {
+	h      *Hierarchy
+	name   string
+	lim    Limiter
+	bytes  atomic.Int64
+	closed atomic.Bool
+}
+
+func (c *HierarchyChild) Wait(ctx context.Context, n int) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	err := c.lim.Wait(ctx, n)
+	if err != nil {
+		return err
+	}
+
+	c.bytes.Add(int64(n))
+	c.h.bytes.Add(int64(n))
+	return nil
+}
+
+// Name returns the name the child was created with.
+func (c *HierarchyChild) Name() string {
+	return c.name
+}
+
+// Bytes returns the total bytes charged through the child.
+func (c *HierarchyChild) Bytes() int64 {
+	return c.bytes.Load()
+}
+
+// Close removes the child from the hierarchy. Subsequent calls to Wait return ErrClosed.
+func (c *HierarchyChild) Close() error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+
+	c.h.mu.Lock()
+	delete(c.h.children, c)
+	c.h.mu.Unlock()
+	return nil
+}
+
+var _ Limiter = (*HierarchyChild)(nil)
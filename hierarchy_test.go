@@ -0,0 +1,34 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestHierarchy(t *testing.T) {
+	var parent countingLimiter
+	h := NewHierarchy(&parent)
+
+	var tenantLim countingLimiter
+	a := h.NewChild("a", &tenantLim)
+	b := h.NewChild("b", nil)
+
+	_ = a.Wait(context.Background(), 100)
+	_ = b.Wait(context.Background(), 50)
+
+	if parent.n.Load() != 150 || h.Bytes() != 150 {
+		t.Errorf("parent charged %d, rolled up %d, want 150", parent.n.Load(), h.Bytes())
+	}
+	if tenantLim.n.Load() != 100 || a.Bytes() != 100 {
+		t.Errorf("child charged %d, counted %d, want 100", tenantLim.n.Load(), a.Bytes())
+	}
+
+	_ = a.Close()
+	if len(h.Children()) != 1 {
+		t.Errorf("got %d children after close, want 1", len(h.Children()))
+	}
+	if err := a.Wait(context.Background(), 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("wait after close: got %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,166 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HTB is a hierarchy of traffic classes sharing a link, modelled on Linux's Hierarchical Token Bucket.
+//
+// Like Hierarchy, classes draw from a parent (link) budget. Additionally, each class has a guaranteed rate it can
+// always use, and may borrow capacity left unused by its siblings up to a ceiling. This gives multi-tenant servers
+// both isolation and full link utilization.
+//
+// The guaranteed rates of all classes should not exceed the link rate, otherwise guarantees can't be honoured.
+type HTB struct {
+	w waiter
+
+	mu      sync.Mutex
+	link    bucket
+	classes map[*HTBClass]struct{}
+}
+
+// NewHTB returns a hierarchy for a link of linkRate bytes per second, with linkBurst bytes of burst capacity.
+// As bytes are admitted only once they fit within the burst, a burst of less than 1 byte is treated as 1.
+func NewHTB(linkRate, linkBurst int64, opts ...Option) *HTB {
+	h := &HTB{
+		w:       newWaiter(opts),
+		classes: make(map[*HTBClass]struct{}),
+	}
+	h.link = newBucket(linkRate, max(linkBurst, 1), h.w.now())
+	return h
+}
+
+// NewClass returns a limiter for a traffic class which is guaranteed rate bytes per second, and may borrow
+// unused link capacity up to ceil bytes per second. burst is the burst capacity, in bytes, at both rates, and is
+// at least 1 byte as with NewHTB. The class should be closed once it is no longer needed.
+func (h *HTB) NewClass(name string, rate, ceil, burst int64) *HTBClass {
+	now := h.w.now()
+	burst = max(burst, 1)
+	c := &HTBClass{
+		h:       h,
+		name:    name,
+		assured: newBucket(rate, burst, now),
+		ceil:    newBucket(max(ceil, rate), burst, now),
+	}
+
+	h.mu.Lock()
+	h.classes[c] = struct{}{}
+	h.mu.Unlock()
+	return c
+}
+
+// Classes returns the classes which have not been closed, in no particular order.
+func (h *HTB) Classes() []*HTBClass {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	classes := make([]*HTBClass, 0, len(h.classes))
+	for c := range h.classes {
+		classes = append(classes, c)
+	}
+	return classes
+}
+
+// HTBClass is a limiter for a traffic class within an HTB.
+type HTBClass struct {
+	h    *HTB
+	name string
+
+	// Guarded by h.mu
+	assured bucket
+	ceil    bucket
+
+	bytes    atomic.Int64
+	borrowed atomic.Int64
+	closed   atomic.Bool
+}
+
+func (c *HTBClass) Wait(ctx context.Context, n int) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	for remaining := int64(n); remaining > 0; {
+		// Chunks must fit within every bucket's burst capacity, or they would never be admitted.
+		nn := float64(remaining)
+		c.h.mu.Lock()
+		nn = min(nn, c.assured.burst, c.ceil.burst, c.h.link.burst)
+		delay, borrowed := c.admit(nn)
+		c.h.mu.Unlock()
+
+		if delay > 0 {
+			err := c.h.w.sleep(ctx, delay)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		remaining -= int64(nn)
+		c.bytes.Add(int64(nn))
+		if borrowed {
+			c.borrowed.Add(int64(nn))
+		}
+	}
+	return nil
+}
+
+// admit charges n bytes if the class may send them now, or otherwise returns how long until it might.
+// Must be called with c.h.mu held.
+func (c *HTBClass) admit(n float64) (delay time.Duration, borrowed bool) {
+	now := c.h.w.now()
+	c.assured.advance(now)
+	c.ceil.advance(now)
+	c.h.link.advance(now)
+
+	switch {
+	case c.assured.tokens >= n:
+		// Within the guaranteed rate: always allowed, even if that puts the link into debt.
+	case c.ceil.tokens >= n && c.h.link.tokens >= n:
+		// Above the guaranteed rate, but within the ceiling and there is spare link capacity to borrow.
+		borrowed = true
+	default:
+		// Whichever comes first: the guaranteed rate allowing n, or borrowing becoming possible.
+		delay = min(c.assured.delayUntil(n), max(c.ceil.delayUntil(n), c.h.link.delayUntil(n)))
+		return max(delay, time.Microsecond), false
+	}
+
+	// As in Linux HTB, all traffic is charged to the class's buckets and the link, whether borrowed or not.
+	c.assured.take(n)
+	c.ceil.take(n)
+	c.h.link.take(n)
+	return 0, borrowed
+}
+
+// Name returns the name the class was created with.
+func (c *HTBClass) Name() string {
+	return c.name
+}
+
+// Bytes returns the total bytes sent by the class.
+func (c *HTBClass) Bytes() int64 {
+	return c.bytes.Load()
+}
+
+// Borrowed returns the total bytes sent by the class above its guaranteed rate, using capacity borrowed from
+// the link.
+func (c *HTBClass) Borrowed() int64 {
+	return c.borrowed.Load()
+}
+
+// Close removes the class from the HTB. Subsequent calls to Wait return ErrClosed.
+func (c *HTBClass) Close() error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+
+	c.h.mu.Lock()
+	delete(c.h.classes, c)
+	c.h.mu.Unlock()
+	return nil
+}
+
+var _ Limiter = (*HTBClass)(nil)
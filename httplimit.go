@@ -0,0 +1,210 @@
+package throughput
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// HTTPOption configures how the HTTP integrations -- NewPerIPHandler, ShapeReverseProxy and BackoffTransport --
+// account for bytes.
+type HTTPOption func(*httpAccounting)
+
+// WithHeaderBytes charges headers as well as bodies, at their approximate size on the wire in HTTP/1.1.
+// By default, only bodies are charged.
+func WithHeaderBytes() HTTPOption {
+	return func(a *httpAccounting) {
+		a.headers = true
+	}
+}
+
+// WithSmallResponsesExempt exempts responses with a Content-Length below n bytes, such as redirects and errors,
+// so a strict policy for bodies isn't distorted by control responses. Their headers are exempt too.
+func WithSmallResponsesExempt(n int64) HTTPOption {
+	return func(a *httpAccounting) {
+		a.exemptBelow = n
+	}
+}
+
+type httpAccounting struct {
+	headers     bool
+	exemptBelow int64
+}
+
+func newHTTPAccounting(opts []HTTPOption) httpAccounting {
+	var a httpAccounting
+	for _, opt := range opts {
+		opt(&a)
+	}
+	return a
+}
+
+// exempt reports whether a response with the given Content-Length, which is negative if unknown, isn't charged.
+func (a httpAccounting) exempt(contentLength int64) bool {
+	return contentLength >= 0 && contentLength < a.exemptBelow
+}
+
+// chargeHeaders charges startLine and h to lim, if WithHeaderBytes.
+func (a httpAccounting) chargeHeaders(ctx context.Context, lim Limiter, startLine int, h http.Header) error {
+	if !a.headers {
+		return nil
+	}
+	return lim.Wait(ctx, startLine+headerSize(h))
+}
+
+// headerSize returns the approximate size of h on the wire in HTTP/1.1, including the blank line which ends it.
+func headerSize(h http.Header) int {
+	n := len("\r\n")
+	for k, vs := range h {
+		for _, v := range vs {
+			n += len(k) + len(": ") + len(v) + len("\r\n")
+		}
+	}
+	return n
+}
+
+// requestLineSize returns the approximate size of r's request line on the wire in HTTP/1.1.
+func requestLineSize(r *http.Request) int {
+	return len(r.Method) + len(r.URL.RequestURI()) + len(" HTTP/1.1\r\n") + 1
+}
+
+// statusLineSize is the approximate size of a status line on the wire in HTTP/1.1.
+const statusLineSize = len("HTTP/1.1 200 OK\r\n")
+
+// ClientIPFunc identifies the client making a request, for per-client limiting.
+type ClientIPFunc func(r *http.Request) string
+
+// RemoteIP returns the IP address of the peer which made the request, ignoring any forwarding headers.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ForwardedForIP returns a ClientIPFunc for servers behind trustedProxies reverse proxies, each of which appends
+// the address it received the request from to X-Forwarded-For.
+//
+// The client IP is taken trustedProxies entries from the right of X-Forwarded-For, as entries further left can be
+// set by the client. If there are too few entries, the request didn't pass through the proxies, so RemoteIP is
+// used instead. With zero trustedProxies, X-Forwarded-For is ignored.
+func ForwardedForIP(trustedProxies int) ClientIPFunc {
+	return func(r *http.Request) string {
+		if trustedProxies <= 0 {
+			return RemoteIP(r)
+		}
+
+		var hops []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(header, ",") {
+				if hop = strings.TrimSpace(hop); hop != "" {
+					hops = append(hops, hop)
+				}
+			}
+		}
+		if len(hops) < trustedProxies {
+			return RemoteIP(r)
+		}
+		return hops[len(hops)-trustedProxies]
+	}
+}
+
+// NewPerIPHandler returns a handler which throttles request body reads and response writes of next, using a
+// limiter per client from clients, and optionally a global limiter shared by every client.
+//
+// Clients are identified by clientIP, which defaults to RemoteIP. Use ForwardedForIP when behind reverse proxies.
+// Request and response bytes are charged to the same limiters, so a client's uploads and downloads share its rate.
+// The limiter is also attached to the request's context, for retrieval with FromContext.
+func NewPerIPHandler(
+	next http.Handler,
+	clients *Registry[string],
+	global Limiter,
+	clientIP ClientIPFunc,
+	opts ...HTTPOption,
+) http.Handler {
+	if clientIP == nil {
+		clientIP = RemoteIP
+	}
+	acct := newHTTPAccounting(opts)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var lim Limiter = clients.Get(clientIP(r))
+		if global != nil {
+			lim = NewAllLimiter(lim, global)
+		}
+
+		ctx := NewContext(r.Context(), lim)
+		r = r.WithContext(ctx)
+		if err := acct.chargeHeaders(ctx, lim, requestLineSize(r), r.Header); err != nil {
+			return
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &limitedBody{Reader: NewReader(ctx, r.Body, lim), Closer: r.Body}
+		}
+
+		lw := &limitedResponseWriter{ResponseWriter: w, ctx: ctx, lim: lim, acct: acct}
+		lw.w = NewWriter(ctx, w, lim)
+		next.ServeHTTP(lw, r)
+	})
+}
+
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// limitedResponseWriter throttles writes of the response body.
+type limitedResponseWriter struct {
+	http.ResponseWriter
+	w           *Writer
+	ctx         context.Context
+	lim         Limiter
+	acct        httpAccounting
+	wroteHeader bool
+}
+
+func (l *limitedResponseWriter) WriteHeader(code int) {
+	if l.wroteHeader {
+		l.ResponseWriter.WriteHeader(code)
+		return
+	}
+	l.wroteHeader = true
+
+	contentLength := int64(-1)
+	if v := l.Header().Get("Content-Length"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			contentLength = n
+		}
+	}
+	if l.acct.exempt(contentLength) {
+		l.w.SetEnabled(false)
+	} else {
+		_ = l.acct.chargeHeaders(l.ctx, l.lim, statusLineSize, l.Header())
+	}
+	l.ResponseWriter.WriteHeader(code)
+}
+
+func (l *limitedResponseWriter) Write(p []byte) (int, error) {
+	if !l.wroteHeader {
+		l.WriteHeader(http.StatusOK)
+	}
+	return l.w.Write(p)
+}
+
+func (l *limitedResponseWriter) Flush() {
+	if !l.wroteHeader {
+		l.WriteHeader(http.StatusOK)
+	}
+	if f, ok := l.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the underlying ResponseWriter.
+func (l *limitedResponseWriter) Unwrap() http.ResponseWriter {
+	return l.ResponseWriter
+}
@@ -0,0 +1,103 @@
+package throughput
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestForwardedForIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies int
+		xff     []string
+		want    string
+	}{
+		{"NoProxies", 0, []string{"1.1.1.1"}, "10.0.0.1"},
+		{"OneProxy", 1, []string{"6.6.6.6, 1.1.1.1"}, "1.1.1.1"},
+		{"TwoProxies", 2, []string{"6.6.6.6, 1.1.1.1", "2.2.2.2"}, "1.1.1.1"},
+		{"TooFewHops", 2, []string{"1.1.1.1"}, "10.0.0.1"},
+		{"NoHeader", 1, nil, "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "10.0.0.1:1234"
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+
+			if got := ForwardedForIP(tt.proxies)(r); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerIPHandler(t *testing.T) {
+	clients := NewRegistry(func(string) Limiter { return &countingLimiter{} }, 0)
+	var global countingLimiter
+
+	h := NewPerIPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := FromContext(r.Context()); !ok {
+			t.Error("request context doesn't carry the client's limiter")
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(bytes.Repeat(body, 2))
+	}), clients, &global, nil)
+
+	send := func(remoteAddr, body string) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.RemoteAddr = remoteAddr
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	send("10.0.0.1:1000", "hello")
+	send("10.0.0.1:2000", "hi")
+	send("10.0.0.2:1000", "hey")
+
+	// Each client is charged for its request bodies and responses
+	for ip, want := range map[string]int64{"10.0.0.1": 21, "10.0.0.2": 9} {
+		if got := clients.Get(ip).(*registryEntry).Limiter.(*countingLimiter).n.Load(); got != want {
+			t.Errorf("%s charged %d bytes, want %d", ip, got, want)
+		}
+	}
+	if got := global.n.Load(); got != 30 {
+		t.Errorf("global limiter charged %d bytes, want 30", got)
+	}
+}
+
+func TestPerIPHandlerAccounting(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/small" {
+			w.Header().Set("Content-Length", "2")
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+		_, _ = w.Write(make([]byte, 1000))
+	})
+
+	charged := func(path string, opts ...HTTPOption) int64 {
+		var lim countingLimiter
+		clients := NewRegistry(func(string) Limiter { return &lim }, 0)
+		h := NewPerIPHandler(handler, clients, nil, nil, opts...)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		return lim.n.Load()
+	}
+
+	if got := charged("/"); got != 1000 {
+		t.Errorf("charged %d bytes by default, want 1000 for the body alone", got)
+	}
+	if got := charged("/", WithHeaderBytes()); got <= 1000 {
+		t.Errorf("charged %d bytes with WithHeaderBytes, want more than the 1000 byte body", got)
+	}
+	if got := charged("/small", WithHeaderBytes(), WithSmallResponsesExempt(100)); got >= 100 {
+		t.Errorf("charged %d bytes for a small response, want only the request headers", got)
+	}
+	if got := charged("/small"); got != 2 {
+		t.Errorf("charged %d bytes for a small response without exemption, want 2", got)
+	}
+}
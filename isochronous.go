@@ -0,0 +1,81 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// IsochronousLimiter grants a fixed allotment of bytes per tick, e.g. exactly 8 KiB every 50ms, for real-time
+// feeds where a constant cadence matters more than the average rate.
+//
+// Unlike a token bucket, an allotment unused by the end of its tick is lost rather than saved up, so a stream
+// can't burst after being idle: at most one allotment is ever sent per tick. Ticks are aligned to when the limiter
+// was created. A Wait larger than the remaining allotment spills into the following ticks, and waits until the
+// last of them begins -- so streams should read or write in allotment-sized chunks, e.g. with a buffer of
+// Allotment bytes, to send one chunk per tick.
+type IsochronousLimiter struct {
+	allotment int64
+	interval  time.Duration
+	w         waiter
+	start     time.Time
+
+	mu   sync.Mutex
+	tick int64 // the tick being filled
+	used int64 // bytes of tick's allotment used
+}
+
+// NewIsochronousLimiter returns a limiter granting allotment bytes at the start of every interval. An interval of
+// zero or less is treated as 1ns.
+func NewIsochronousLimiter(allotment int64, interval time.Duration, opts ...Option) *IsochronousLimiter {
+	l := &IsochronousLimiter{allotment: max(allotment, 1), interval: max(interval, 1), w: newWaiter(opts)}
+	l.w.exact = true
+	l.start = l.w.now()
+	return l
+}
+
+func (l *IsochronousLimiter) Wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := l.w.now()
+	if tick := int64(now.Sub(l.start) / l.interval); tick > l.tick {
+		// Allotments of ticks which have passed are lost
+		l.tick, l.used = tick, 0
+	}
+	prevTick, prevUsed := l.tick, l.used
+
+	l.used += int64(n)
+	if l.used > l.allotment {
+		spill := (l.used - 1) / l.allotment
+		l.tick += spill
+		l.used -= spill * l.allotment
+	}
+	tick, used := l.tick, l.used
+	delay := l.start.Add(time.Duration(tick) * l.interval).Sub(now)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	err := l.w.sleep(ctx, delay)
+	if err != nil {
+		l.mu.Lock()
+		if l.tick == tick && l.used == used {
+			// Nothing has been charged since, so the charge can be undone
+			l.tick, l.used = prevTick, prevUsed
+		}
+		l.mu.Unlock()
+	}
+	return err
+}
+
+// Allotment returns the bytes granted per tick.
+func (l *IsochronousLimiter) Allotment() int64 {
+	return l.allotment
+}
+
+// Interval returns the length of a tick.
+func (l *IsochronousLimiter) Interval() time.Duration {
+	return l.interval
+}
+
+var _ Limiter = (*IsochronousLimiter)(nil)
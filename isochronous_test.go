@@ -0,0 +1,65 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIsochronousLimiter(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := NewVirtualClock(start)
+	lim := NewIsochronousLimiter(1000, 50*time.Millisecond, WithClock(clock))
+
+	// One allotment per tick, each sent at the start of its tick
+	for i := range 4 {
+		_ = lim.Wait(context.Background(), 1000)
+		if elapsed := clock.Since(start); elapsed != time.Duration(i)*50*time.Millisecond {
+			t.Errorf("allotment %d sent at %s, want %s", i, elapsed, time.Duration(i)*50*time.Millisecond)
+		}
+	}
+
+	// Idle ticks don't accumulate a burst, and ticks stay aligned
+	clock.Advance(time.Second + 10*time.Millisecond)
+	_ = lim.Wait(context.Background(), 1000)
+	_ = lim.Wait(context.Background(), 1000)
+	if elapsed := clock.Since(start); elapsed != 1200*time.Millisecond {
+		t.Errorf("second allotment after idling sent at %s, want 1.2s", elapsed)
+	}
+
+	// A wait larger than the allotment spills into following ticks
+	_ = lim.Wait(context.Background(), 2500)
+	if elapsed := clock.Since(start); elapsed != 1350*time.Millisecond {
+		t.Errorf("2.5 allotments sent at %s, want 1.35s", elapsed)
+	}
+}
+
+func TestIsochronousLimiterCancel(t *testing.T) {
+	lim := NewIsochronousLimiter(1000, time.Hour)
+	_ = lim.Wait(context.Background(), 1000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := lim.Wait(ctx, 1000); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait returned %v, want context.Canceled", err)
+	}
+
+	// The canceled wait's charge was undone, so the next tick's allotment is untouched
+	if lim.tick != 0 || lim.used != 1000 {
+		t.Errorf("limiter is at tick %d with %d used, want tick 0 with 1000", lim.tick, lim.used)
+	}
+}
+
+func TestIsochronousLimiterZeroInterval(t *testing.T) {
+	clock := NewVirtualClock(time.Unix(0, 0))
+	lim := NewIsochronousLimiter(1000, 0, WithClock(clock))
+	clock.Advance(time.Second)
+
+	if err := lim.Wait(context.Background(), 1000); err != nil {
+		t.Errorf("Wait returned %v", err)
+	}
+	if got := lim.Interval(); got != 1 {
+		t.Errorf("Interval() = %s, want 1ns", got)
+	}
+}
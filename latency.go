@@ -0,0 +1,96 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LatencyLimiter wraps an AdjustableLimiter, adapting its rate to keep latency near a target. This lets bulk
+// transfers use spare capacity without inflating latency for interactive traffic sharing the link.
+//
+// The application reports round-trip time samples -- e.g. from pings, request timings or TCP_INFO -- via
+// ReportRTT. At most once per target duration, the rate is scaled by target / smoothed RTT: rising (by up to 25%)
+// whilst latency is below the target, and falling (by up to half) once queues build and latency exceeds it.
+type LatencyLimiter struct {
+	lim      AdjustableLimiter
+	target   time.Duration
+	min, max int64
+	w        waiter
+
+	mu         sync.Mutex
+	rate       int64
+	srtt       time.Duration
+	lastAdjust time.Time
+}
+
+// NewLatencyLimiter returns a limiter adapting lim's rate between minBytesPerSec and maxBytesPerSec to keep RTT
+// near target. The rate begins at lim's current rate.
+func NewLatencyLimiter(
+	lim AdjustableLimiter,
+	target time.Duration,
+	minBytesPerSec, maxBytesPerSec int64,
+	opts ...Option,
+) *LatencyLimiter {
+	l := &LatencyLimiter{
+		lim:    lim,
+		target: target,
+		min:    minBytesPerSec,
+		max:    maxBytesPerSec,
+		w:      newWaiter(opts),
+	}
+	l.rate = min(max(lim.Limit(), l.min), l.max)
+	lim.SetLimit(l.rate)
+	return l
+}
+
+func (l *LatencyLimiter) Wait(ctx context.Context, n int) error {
+	return l.lim.Wait(ctx, n)
+}
+
+// ReportRTT records a round-trip time sample, adjusting the rate if it hasn't been adjusted within the last
+// target duration.
+func (l *LatencyLimiter) ReportRTT(rtt time.Duration) {
+	if rtt <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Smoothed as TCP does (RFC 6298), so a single outlier doesn't swing the rate.
+	if l.srtt == 0 {
+		l.srtt = rtt
+	} else {
+		l.srtt = (7*l.srtt + rtt) / 8
+	}
+
+	now := l.w.now()
+	if !l.lastAdjust.IsZero() && now.Sub(l.lastAdjust) < l.target {
+		return
+	}
+	l.lastAdjust = now
+
+	gain := min(max(float64(l.target)/float64(l.srtt), 0.5), 1.25)
+	rate := min(max(int64(float64(l.rate)*gain), l.min), l.max)
+	if rate != l.rate {
+		l.rate = rate
+		l.lim.SetLimit(rate)
+	}
+}
+
+// SmoothedRTT returns the smoothed round-trip time, or zero if no samples have been reported.
+func (l *LatencyLimiter) SmoothedRTT() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.srtt
+}
+
+// Limit returns the current rate in bytes per second.
+func (l *LatencyLimiter) Limit() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+var _ Limiter = (*LatencyLimiter)(nil)
@@ -0,0 +1,43 @@
+package throughput
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyLimiter(t *testing.T) {
+	c := &stepClock{now: time.Unix(0, 0)}
+	tb := NewTokenBucket(1000, 1000, WithClock(c))
+	l := NewLatencyLimiter(tb, 50*time.Millisecond, 100, 10000, WithClock(c))
+
+	report := func(rtt time.Duration, times int) {
+		for range times {
+			l.ReportRTT(rtt)
+			c.NewTimer(50 * time.Millisecond)
+		}
+	}
+
+	// Latency well below the target lets the rate grow
+	report(10*time.Millisecond, 4)
+	if got := l.Limit(); got <= 1000 || got > 2500 {
+		t.Errorf("after low latency, Limit() = %d, want 1000-2500", got)
+	}
+	if tb.Limit() != l.Limit() {
+		t.Errorf("wrapped limiter's rate = %d, want %d", tb.Limit(), l.Limit())
+	}
+
+	// Latency far above the target reduces it to the minimum
+	report(500*time.Millisecond, 30)
+	if got := l.Limit(); got != 100 {
+		t.Errorf("after high latency, Limit() = %d, want 100", got)
+	}
+
+	// Latency at the target holds the rate steady
+	report(10*time.Millisecond, 30)
+	report(50*time.Millisecond, 60)
+	before := l.Limit()
+	report(50*time.Millisecond, 10)
+	if got := l.Limit(); before <= 100 || float64(got) < 0.98*float64(before) || got > before {
+		t.Errorf("at target latency, Limit() changed from %d to %d", before, got)
+	}
+}
@@ -0,0 +1,106 @@
+package throughput
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// LeakyBucket implements classic leaky-bucket shaping: bytes are queued in a bucket which drains at a fixed
+// rate, and each Wait returns once its bytes have drained. Unlike a token bucket there is no burst allowance,
+// so the output is strictly paced.
+//
+// If the bucket has a capacity and a Wait would overflow it, the bytes are dropped and ErrDropped is returned,
+// as with a policing leaky bucket. A Wait into an empty bucket is always accepted, so n remains unbounded.
+type LeakyBucket struct {
+	mu       sync.Mutex
+	rate     float64 // bytes per second
+	capacity int64
+	drained  time.Time // when the queue will be empty
+	w        waiter
+}
+
+// NewLeakyBucket returns a bucket draining at bytesPerSec, holding at most capacity queued bytes.
+// A capacity of zero means the bucket is unbounded, and never drops.
+func NewLeakyBucket(bytesPerSec int64, capacity int64, opts ...Option) *LeakyBucket {
+	return &LeakyBucket{
+		rate:     float64(bytesPerSec),
+		capacity: capacity,
+		w:        newWaiter(opts),
+	}
+}
+
+func (l *LeakyBucket) Wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return sleep(ctx, math.MaxInt64)
+	}
+
+	now := l.w.now()
+	start := l.drained
+	if start.Before(now) {
+		start = now
+	}
+
+	queued := l.queuedAt(now)
+	if l.capacity > 0 && queued > 0 && queued+int64(n) > l.capacity {
+		l.mu.Unlock()
+		return ErrDropped
+	}
+
+	drain := secondsToDuration(float64(n) / l.rate)
+	l.drained = start.Add(drain)
+	delay := l.drained.Sub(now)
+	l.mu.Unlock()
+
+	err := l.w.sleep(ctx, delay)
+	if err != nil {
+		l.mu.Lock()
+		l.drained = l.drained.Add(-drain)
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Refund removes n bytes from the queue, e.g. when a transfer they were charged for didn't happen.
+func (l *LeakyBucket) Refund(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate > 0 {
+		l.drained = l.drained.Add(-secondsToDuration(float64(n) / l.rate))
+	}
+}
+
+// Queued returns the number of bytes waiting to drain from the bucket.
+func (l *LeakyBucket) Queued() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.queuedAt(l.w.now())
+}
+
+func (l *LeakyBucket) queuedAt(now time.Time) int64 {
+	if !l.drained.After(now) {
+		return 0
+	}
+	return int64(l.drained.Sub(now).Seconds() * l.rate)
+}
+
+// Limit returns the drain rate in bytes per second.
+func (l *LeakyBucket) Limit() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int64(l.rate)
+}
+
+// SetLimit changes the drain rate to bytesPerSec. Bytes already queued drain at the old rate.
+func (l *LeakyBucket) SetLimit(bytesPerSec int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = float64(bytesPerSec)
+}
+
+var _ AdjustableLimiter = (*LeakyBucket)(nil)
+var _ Refunder = (*LeakyBucket)(nil)
@@ -0,0 +1,45 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLeakyBucketPacing(t *testing.T) {
+	lim := NewLeakyBucket(10*1024, 0)
+
+	// No burst: every byte is paced, including the first.
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := lim.Wait(context.Background(), 256); err != nil {
+			t.Fatalf("wait: %s", err)
+		}
+	}
+
+	err := verifyWithSlop(time.Since(start), 100*time.Millisecond, 20*time.Millisecond)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestLeakyBucketOverflow(t *testing.T) {
+	lim := NewLeakyBucket(1024, 1024)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_ = lim.Wait(ctx, 1000)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	if err := lim.Wait(context.Background(), 100); !errors.Is(err, ErrDropped) {
+		t.Errorf("wait into full bucket: got %v, want ErrDropped", err)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,50 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrLinkSpeedUnknown is returned when an interface's link speed can't be determined, e.g. for Wi-Fi and virtual
+// interfaces, or on unsupported platforms.
+var ErrLinkSpeedUnknown = errors.New("throughput: link speed unknown")
+
+// LinkSpeed returns the link speed of the named network interface in bits per second.
+// It is supported on Linux and macOS.
+func LinkSpeed(iface string) (int64, error) {
+	return linkSpeed(iface)
+}
+
+// DefaultInterface returns the name of the interface with the default route.
+// It is supported on Linux and macOS.
+func DefaultInterface() (string, error) {
+	return defaultInterface()
+}
+
+// TrackLinkSpeed keeps lim's rate at fraction of the default interface's link speed, e.g. 0.3 to use at most 30%
+// of the uplink. The interface and its speed are checked every interval, so the rate follows changes of route or
+// renegotiation of the link. TrackLinkSpeed blocks until ctx is done, or returns an error if the link speed can't
+// be determined on the first check. Subsequent failures leave the rate unchanged.
+func TrackLinkSpeed(ctx context.Context, lim AdjustableLimiter, fraction float64, interval time.Duration) error {
+	return trackLinkSpeed(ctx, lim, fraction, interval, func() (int64, error) {
+		iface, err := DefaultInterface()
+		if err != nil {
+			return 0, err
+		}
+		return LinkSpeed(iface)
+	})
+}
+
+func trackLinkSpeed(
+	ctx context.Context,
+	lim AdjustableLimiter,
+	fraction float64,
+	interval time.Duration,
+	speed func() (int64, error),
+) error {
+	return trackRate(ctx, lim, interval, func() (int64, error) {
+		bitsPerSec, err := speed()
+		return int64(float64(bitsPerSec) / 8 * fraction), err
+	})
+}
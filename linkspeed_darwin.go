@@ -0,0 +1,65 @@
+package throughput
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func linkSpeed(iface string) (int64, error) {
+	out, err := exec.Command("ifconfig", iface).Output()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %w", ErrLinkSpeedUnknown, iface, err)
+	}
+	bitsPerSec, ok := parseIfconfigMedia(out)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrLinkSpeedUnknown, iface)
+	}
+	return bitsPerSec, nil
+}
+
+// mediaSpeed matches the speed of the active media in ifconfig output, e.g. "(1000baseT <full-duplex>)" or
+// "(10Gbase-T <full-duplex>)".
+var mediaSpeed = regexp.MustCompile(`\((\d+)([GM]?)base`)
+
+func parseIfconfigMedia(out []byte) (int64, bool) {
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if !strings.HasPrefix(line, "media:") {
+			continue
+		}
+		m := mediaSpeed.FindStringSubmatch(line)
+		if m == nil {
+			return 0, false
+		}
+		n, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		if m[2] == "G" {
+			return n * 1_000_000_000, true
+		}
+		return n * 1_000_000, true
+	}
+	return 0, false
+}
+
+func defaultInterface() (string, error) {
+	out, err := exec.Command("route", "-n", "get", "default").Output()
+	if err != nil {
+		return "", fmt.Errorf("reading routes: %w", err)
+	}
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		name, ok := strings.CutPrefix(strings.TrimSpace(s.Text()), "interface:")
+		if ok {
+			return strings.TrimSpace(name), nil
+		}
+	}
+	return "", fmt.Errorf("no default route")
+}
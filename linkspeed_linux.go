@@ -0,0 +1,49 @@
+package throughput
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func linkSpeed(iface string) (int64, error) {
+	// Reported in Mb/s, or -1 (or an error reading) when unknown
+	data, err := os.ReadFile("/sys/class/net/" + iface + "/speed")
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %w", ErrLinkSpeedUnknown, iface, err)
+	}
+	mbps, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || mbps <= 0 {
+		return 0, fmt.Errorf("%w: %s", ErrLinkSpeedUnknown, iface)
+	}
+	return mbps * 1_000_000, nil
+}
+
+func defaultInterface() (string, error) {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return "", fmt.Errorf("reading routes: %w", err)
+	}
+	defer f.Close()
+	return parseDefaultRoute(f)
+}
+
+// parseDefaultRoute finds the interface of the default route in the format of /proc/net/route.
+func parseDefaultRoute(r io.Reader) (string, error) {
+	s := bufio.NewScanner(r)
+	s.Scan() // header
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		// Iface, Destination, Gateway, Flags, RefCnt, Use, Metric, Mask...
+		if len(fields) >= 8 && fields[1] == "00000000" && fields[7] == "00000000" {
+			return fields[0], nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return "", fmt.Errorf("reading routes: %w", err)
+	}
+	return "", fmt.Errorf("no default route")
+}
@@ -0,0 +1,23 @@
+package throughput
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDefaultRoute(t *testing.T) {
+	routes := `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+docker0	000011AC	00000000	0001	0	0	0	0000FFFF	0	0	0
+eth0	00000000	0102A8C0	0003	0	0	100	00000000	0	0	0
+eth0	0002A8C0	00000000	0001	0	0	100	00FFFFFF	0	0	0
+`
+	iface, err := parseDefaultRoute(strings.NewReader(routes))
+	if err != nil || iface != "eth0" {
+		t.Errorf("parseDefaultRoute() = %q, %v, want eth0", iface, err)
+	}
+
+	_, err = parseDefaultRoute(strings.NewReader("Iface\tDestination\n"))
+	if err == nil {
+		t.Error("expected error without a default route")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package throughput
+
+import "errors"
+
+func linkSpeed(string) (int64, error) {
+	return 0, ErrLinkSpeedUnknown
+}
+
+func defaultInterface() (string, error) {
+	return "", errors.New("throughput: default interface unsupported on this platform")
+}
@@ -0,0 +1,50 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTrackLinkSpeed(t *testing.T) {
+	var bitsPerSec atomic.Int64
+	bitsPerSec.Store(1_000_000_000)
+	speed := func() (int64, error) { return bitsPerSec.Load(), nil }
+
+	lim := NewTokenBucket(0, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- trackLinkSpeed(ctx, lim, 0.3, time.Millisecond, speed) }()
+
+	waitFor := func(want int64) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for lim.Limit() != want && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if got := lim.Limit(); got != want {
+			t.Errorf("Limit() = %d, want %d", got, want)
+		}
+	}
+
+	// 30% of 1 Gb/s
+	waitFor(37_500_000)
+
+	// The link renegotiates
+	bitsPerSec.Store(100_000_000)
+	waitFor(3_750_000)
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+
+	err := trackLinkSpeed(context.Background(), lim, 0.3, time.Millisecond, func() (int64, error) {
+		return 0, ErrLinkSpeedUnknown
+	})
+	if !errors.Is(err, ErrLinkSpeedUnknown) {
+		t.Errorf("err = %v, want ErrLinkSpeedUnknown", err)
+	}
+}
@@ -0,0 +1,308 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// managerRateWindow is the averaging window of the rates reported by a Manager's groups and members.
+const managerRateWindow = 5 * time.Second
+
+// ErrDraining is returned by waits on members which joined a Manager's group after it began draining.
+var ErrDraining = errors.New("throughput: draining")
+
+// Manager holds named throttle groups -- e.g. "backup", "replication" and "api" -- each with its own limit shared
+// by its member streams. Limits can be changed at runtime, and groups and members can be enumerated along with
+// their current rates, for operational tooling.
+type Manager struct {
+	opts   []Option
+	ctx    context.Context // canceled once a drain's deadline passes
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	groups   map[string]*Group
+	active   int // members admitted and not yet closed
+	draining bool
+	drained  chan struct{} // closed once draining and no members are active
+}
+
+// NewManager returns a manager with no groups. Opts are applied to the limiters and meters of every group.
+func NewManager(opts ...Option) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		opts:   opts,
+		ctx:    ctx,
+		cancel: cancel,
+		groups: make(map[string]*Group),
+	}
+}
+
+// Drain shuts the manager down gracefully: members joining from now on aren't admitted, and their waits return
+// ErrDraining, whilst existing members continue until they are closed. If boost is greater than 1, each group's
+// rate is multiplied by it, so in-flight transfers finish sooner. Only the first call to Drain boosts.
+//
+// Drain returns once every existing member has been closed. If ctx is done first, waits still in progress are
+// canceled and further waits return ErrClosed, and Drain returns ctx's error.
+func (m *Manager) Drain(ctx context.Context, boost float64) error {
+	m.mu.Lock()
+	first := !m.draining
+	if first {
+		m.draining = true
+		m.drained = make(chan struct{})
+		if m.active == 0 {
+			close(m.drained)
+		}
+	}
+	drained := m.drained
+	groups := make([]*Group, 0, len(m.groups))
+	for _, g := range m.groups {
+		groups = append(groups, g)
+	}
+	m.mu.Unlock()
+
+	// Only the call which starts draining boosts, so calling Drain again doesn't compound it
+	if first && boost > 1 {
+		for _, g := range groups {
+			g.lim.SetLimit(int64(float64(g.lim.Limit()) * boost))
+		}
+	}
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		m.cancel()
+		return ctx.Err()
+	}
+}
+
+// admit counts a joining member as active, unless the manager is draining.
+func (m *Manager) admit() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.draining {
+		return false
+	}
+	m.active++
+	return true
+}
+
+// release reverses admit, when an admitted member is closed.
+func (m *Manager) release() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active--
+	if m.draining && m.active == 0 {
+		close(m.drained)
+	}
+}
+
+// SetGroup sets the limit of the named group to bytesPerSec, creating the group if necessary, and returns it.
+func (m *Manager) SetGroup(name string, bytesPerSec int64) *Group {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.setGroup(name, bytesPerSec)
+}
+
+// setGroup implements SetGroup. Must be called with mu held.
+func (m *Manager) setGroup(name string, bytesPerSec int64) *Group {
+	g, ok := m.groups[name]
+	if !ok {
+		g = &Group{
+			m:       m,
+			name:    name,
+			lim:     NewTokenBucket(bytesPerSec, bytesPerSec, m.opts...),
+			meter:   NewMeter(managerRateWindow, m.opts...),
+			opts:    m.opts,
+			members: make(map[*GroupMember]struct{}),
+		}
+		m.groups[name] = g
+		return g
+	}
+
+	g.SetLimit(bytesPerSec)
+	return g
+}
+
+// Group returns the named group, or nil if there is no such group.
+func (m *Manager) Group(name string) *Group {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.groups[name]
+}
+
+// Groups returns all groups, ordered by name.
+func (m *Manager) Groups() []*Group {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	groups := make([]*Group, 0, len(m.groups))
+	for _, g := range m.groups {
+		groups = append(groups, g)
+	}
+	slices.SortFunc(groups, func(a, b *Group) int { return strings.Compare(a.name, b.name) })
+	return groups
+}
+
+// RemoveGroup removes the named group from the manager. Existing members continue to share its limit.
+func (m *Manager) RemoveGroup(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.groups, name)
+}
+
+// Group is a named limit shared by member streams.
+type Group struct {
+	m        *Manager
+	name     string
+	lim      *TokenBucket
+	meter    *Meter
+	opts     []Option
+	disabled atomic.Bool
+
+	mu      sync.Mutex
+	members map[*GroupMember]struct{}
+}
+
+// Name returns the name of the group.
+func (g *Group) Name() string {
+	return g.name
+}
+
+// Limit returns the group's limit in bytes per second. Whilst the manager is draining, this includes any boost.
+func (g *Group) Limit() int64 {
+	return g.lim.Limit()
+}
+
+// SetLimit changes the group's limit to bytesPerSec, which also becomes its burst.
+func (g *Group) SetLimit(bytesPerSec int64) {
+	g.lim.SetLimit(bytesPerSec)
+	g.lim.SetBurst(bytesPerSec)
+}
+
+// Enabled returns whether the group's limit is applied.
+func (g *Group) Enabled() bool {
+	return !g.disabled.Load()
+}
+
+// SetEnabled applies or lifts the group's limit. Whilst disabled, members are metered but not limited.
+func (g *Group) SetEnabled(enabled bool) {
+	g.disabled.Store(!enabled)
+}
+
+// Rate returns the group's current throughput in bytes per second, averaged over the last few seconds.
+func (g *Group) Rate() float64 {
+	return g.meter.Rate()
+}
+
+// Join adds a member stream to the group, and returns its limiter. The member should be closed once it is no
+// longer needed.
+func (g *Group) Join(name string) *GroupMember {
+	gm := &GroupMember{g: g, name: name, meter: NewMeter(managerRateWindow, g.opts...)}
+	gm.admitted = g.m.admit()
+
+	g.mu.Lock()
+	g.members[gm] = struct{}{}
+	g.mu.Unlock()
+	return gm
+}
+
+// Members returns the group's members which have not been closed, ordered by name.
+func (g *Group) Members() []*GroupMember {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	members := make([]*GroupMember, 0, len(g.members))
+	for gm := range g.members {
+		members = append(members, gm)
+	}
+	slices.SortFunc(members, func(a, b *GroupMember) int { return strings.Compare(a.name, b.name) })
+	return members
+}
+
+// GroupMember is a limiter for one stream of a Group.
+type GroupMember struct {
+	g        *Group
+	name     string
+	meter    *Meter
+	admitted bool // false if joined whilst the manager was draining
+	closed   atomic.Bool
+}
+
+func (gm *GroupMember) Wait(ctx context.Context, n int) error {
+	if gm.closed.Load() || gm.g.m.ctx.Err() != nil {
+		return ErrClosed
+	}
+	if !gm.admitted {
+		return ErrDraining
+	}
+
+	if gm.g.Enabled() {
+		lim := gm.g.lim
+		delay := lim.reserve(n)
+		if delay > 0 {
+			err := gm.sleep(ctx, delay)
+			if err != nil {
+				lim.Refund(n)
+				return err
+			}
+		}
+	}
+
+	gm.meter.Add(int64(n))
+	gm.g.meter.Add(int64(n))
+	return nil
+}
+
+// sleep waits for delay on the group's limiter, unless ctx is done or a drain's deadline passes first.
+func (gm *GroupMember) sleep(ctx context.Context, delay time.Duration) error {
+	m := gm.g.m
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(m.ctx, cancel)
+	defer stop()
+
+	err := gm.g.lim.w.sleep(ctx, delay)
+	if err != nil && m.ctx.Err() != nil {
+		return ErrClosed
+	}
+	return err
+}
+
+// Name returns the name the member joined with.
+func (gm *GroupMember) Name() string {
+	return gm.name
+}
+
+// Rate returns the member's current throughput in bytes per second, averaged over the last few seconds.
+func (gm *GroupMember) Rate() float64 {
+	return gm.meter.Rate()
+}
+
+// Bytes returns the total bytes charged through the member.
+func (gm *GroupMember) Bytes() int64 {
+	return gm.meter.Total()
+}
+
+// Close removes the member from the group. Subsequent calls to Wait return ErrClosed.
+func (gm *GroupMember) Close() error {
+	if gm.closed.Swap(true) {
+		return nil
+	}
+
+	gm.g.mu.Lock()
+	delete(gm.g.members, gm)
+	gm.g.mu.Unlock()
+
+	if gm.admitted {
+		gm.g.m.release()
+	}
+	return nil
+}
+
+var _ Limiter = (*GroupMember)(nil)
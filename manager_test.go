@@ -0,0 +1,135 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	c := &stepClock{now: time.Unix(0, 0)}
+	m := NewManager(WithClock(c))
+
+	backup := m.SetGroup("backup", 1000)
+	m.SetGroup("api", 5000)
+	if m.SetGroup("backup", 2000) != backup {
+		t.Error("SetGroup created a new group for an existing name")
+	}
+	if got := backup.Limit(); got != 2000 {
+		t.Errorf("Limit() = %d, want 2000", got)
+	}
+
+	var names []string
+	for _, g := range m.Groups() {
+		names = append(names, g.Name())
+	}
+	if len(names) != 2 || names[0] != "api" || names[1] != "backup" {
+		t.Errorf("Groups() = %v, want [api backup]", names)
+	}
+
+	a := backup.Join("a")
+	b := backup.Join("b")
+	ctx := context.Background()
+	_ = a.Wait(ctx, 2000) // within the burst
+	start := c.Now()
+	_ = b.Wait(ctx, 1000)
+	if got := c.Now().Sub(start); got != 500*time.Millisecond {
+		t.Errorf("member waited %v, want 500ms as the group's limit is shared", got)
+	}
+
+	members := backup.Members()
+	if len(members) != 2 || members[0] != a || members[1] != b {
+		t.Errorf("Members() = %v, want [a b]", members)
+	}
+	if a.Rate() <= b.Rate() || backup.Rate() <= a.Rate() {
+		t.Errorf("rates a=%.0f b=%.0f group=%.0f, want group > a > b", a.Rate(), b.Rate(), backup.Rate())
+	}
+	if got := a.Bytes(); got != 2000 {
+		t.Errorf("Bytes() = %d, want 2000", got)
+	}
+
+	_ = a.Close()
+	if err := a.Wait(ctx, 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("Wait after Close: err = %v, want ErrClosed", err)
+	}
+	if got := len(backup.Members()); got != 1 {
+		t.Errorf("%d members after Close, want 1", got)
+	}
+
+	m.RemoveGroup("api")
+	if m.Group("api") != nil {
+		t.Error("removed group still present")
+	}
+}
+
+func TestManagerDrain(t *testing.T) {
+	m := NewManager()
+	g := m.SetGroup("backup", 1000)
+	a := g.Join("a")
+
+	drained := make(chan error)
+	go func() { drained <- m.Drain(context.Background(), 2) }()
+	time.Sleep(10 * time.Millisecond)
+
+	// Members joining whilst draining aren't admitted
+	b := g.Join("b")
+	if err := b.Wait(context.Background(), 1); !errors.Is(err, ErrDraining) {
+		t.Errorf("Wait on a member joining during the drain: err = %v, want ErrDraining", err)
+	}
+	if got := g.Limit(); got != 2000 {
+		t.Errorf("Limit() = %d during the drain, want 2000 with a boost of 2", got)
+	}
+
+	// Draining again doesn't compound the boost
+	drainedAgain := make(chan error)
+	go func() { drainedAgain <- m.Drain(context.Background(), 2) }()
+	time.Sleep(10 * time.Millisecond)
+	if got := g.Limit(); got != 2000 {
+		t.Errorf("Limit() = %d after a second Drain, want 2000", got)
+	}
+
+	// Existing members continue until closed
+	if err := a.Wait(context.Background(), 1); err != nil {
+		t.Errorf("Wait on an existing member during the drain: err = %v", err)
+	}
+	select {
+	case <-drained:
+		t.Fatal("Drain returned with a member still active")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	_ = a.Close()
+	_ = b.Close()
+	if err := <-drained; err != nil {
+		t.Errorf("Drain returned %v once members were closed", err)
+	}
+	if err := <-drainedAgain; err != nil {
+		t.Errorf("second Drain returned %v once members were closed", err)
+	}
+}
+
+func TestManagerDrainDeadline(t *testing.T) {
+	m := NewManager()
+	a := m.SetGroup("backup", 1000).Join("a")
+	_ = a.Wait(context.Background(), 1000) // empty the burst
+
+	waited := make(chan error)
+	go func() { waited <- a.Wait(context.Background(), 10_000) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.Drain(ctx, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Drain returned %v, want context.DeadlineExceeded", err)
+	}
+
+	// The wait still in progress is canceled
+	select {
+	case err := <-waited:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("in-flight Wait returned %v, want ErrClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("in-flight Wait wasn't canceled by the drain deadline")
+	}
+}
@@ -0,0 +1,244 @@
+// Package measure provides an iperf-style client and server for measuring the throughput achieved over TCP,
+// e.g. to validate a limiter's configuration end-to-end.
+//
+// A client connects to a server and either uploads to it or downloads from it for a fixed duration. Either side
+// may be throttled by a limiter. Results are the bytes the receiving side read within the duration.
+package measure
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iamcalledrob/throughput"
+)
+
+// MaxDuration is the longest measurement a server will run.
+const MaxDuration = 5 * time.Minute
+
+// drainTimeout bounds how long a server waits for a client to close after an upload.
+const drainTimeout = 10 * time.Second
+
+const (
+	modeUpload   byte = 'U'
+	modeDownload byte = 'D'
+)
+
+// header is sent by the client to begin a measurement: the mode, then the duration in nanoseconds.
+type header struct {
+	mode byte
+	dur  time.Duration
+}
+
+func (h header) marshal() []byte {
+	b := make([]byte, 9)
+	b[0] = h.mode
+	binary.BigEndian.PutUint64(b[1:], uint64(h.dur))
+	return b
+}
+
+func readHeader(r io.Reader) (header, error) {
+	b := make([]byte, 9)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return header{}, err
+	}
+	h := header{mode: b[0], dur: time.Duration(binary.BigEndian.Uint64(b[1:]))}
+	if h.mode != modeUpload && h.mode != modeDownload {
+		return header{}, fmt.Errorf("unknown mode %q", h.mode)
+	}
+	if h.dur <= 0 || h.dur > MaxDuration {
+		return header{}, fmt.Errorf("duration %s out of range", h.dur)
+	}
+	return h, nil
+}
+
+// Server accepts measurements from clients.
+type Server struct {
+	lim throughput.Limiter
+}
+
+// NewServer returns a server which throttles the data it sends and receives with lim. A nil lim is unlimited.
+// lim is shared by all connections.
+func NewServer(lim throughput.Limiter) *Server {
+	return &Server{lim: lim}
+}
+
+// Serve accepts connections from ln until ctx is done, at which point ln is closed and nil is returned.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	stop := context.AfterFunc(ctx, func() { _ = ln.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			_ = s.handle(ctx, conn)
+		}()
+	}
+}
+
+func (s *Server) handle(ctx context.Context, conn net.Conn) error {
+	h, err := readHeader(conn)
+	if err != nil {
+		return err
+	}
+
+	if h.mode == modeDownload {
+		_, err = send(ctx, conn, h.dur, s.lim)
+		return err
+	}
+
+	e, err := receive(ctx, conn, h.dur, s.lim)
+	if err != nil {
+		return err
+	}
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b, uint64(e.Bytes))
+	binary.BigEndian.PutUint64(b[8:], uint64(e.Elapsed))
+	if _, err = conn.Write(b); err != nil {
+		return err
+	}
+
+	// Drain anything still in flight until the client closes, as closing with unread data would reset the
+	// connection, possibly before the client has read the result.
+	_ = conn.SetReadDeadline(time.Now().Add(drainTimeout))
+	_, err = io.Copy(io.Discard, conn)
+	return err
+}
+
+// Client runs measurements against a server.
+type Client struct {
+	addr  string
+	lim   throughput.Limiter
+	meter *throughput.Meter
+}
+
+// NewClient returns a client for the server at addr, which throttles the data it sends and receives with lim.
+// A nil lim is unlimited.
+func NewClient(addr string, lim throughput.Limiter) *Client {
+	meter := throughput.NewMeter(time.Second)
+	if lim == nil {
+		lim = meter
+	} else {
+		lim = throughput.Chain(lim, meter)
+	}
+	return &Client{addr: addr, lim: lim, meter: meter}
+}
+
+// Meter returns a meter of the bytes the client sends and receives, e.g. to display progress.
+func (c *Client) Meter() *throughput.Meter {
+	return c.meter
+}
+
+// Upload sends data to the server for dur, and returns the throughput the server received.
+func (c *Client) Upload(ctx context.Context, dur time.Duration) (throughput.Estimate, error) {
+	conn, err := c.start(ctx, header{mode: modeUpload, dur: dur})
+	if err != nil {
+		return throughput.Estimate{}, err
+	}
+	defer conn.Close()
+
+	if _, err = send(ctx, conn, dur, c.lim); err != nil {
+		return throughput.Estimate{}, err
+	}
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		if err = cw.CloseWrite(); err != nil {
+			return throughput.Estimate{}, err
+		}
+	}
+
+	// The server replies once it has received everything sent.
+	b := make([]byte, 16)
+	if _, err = io.ReadFull(conn, b); err != nil {
+		return throughput.Estimate{}, fmt.Errorf("reading result: %w", err)
+	}
+	return throughput.Estimate{
+		Bytes:   int64(binary.BigEndian.Uint64(b)),
+		Elapsed: time.Duration(binary.BigEndian.Uint64(b[8:])),
+	}, nil
+}
+
+// Download receives data from the server for dur, and returns the throughput received.
+func (c *Client) Download(ctx context.Context, dur time.Duration) (throughput.Estimate, error) {
+	conn, err := c.start(ctx, header{mode: modeDownload, dur: dur})
+	if err != nil {
+		return throughput.Estimate{}, err
+	}
+	defer conn.Close()
+	return receive(ctx, conn, dur, c.lim)
+}
+
+func (c *Client) start(ctx context.Context, h header) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.Write(h.marshal()); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// send writes to conn for dur, throttled by lim if non-nil.
+func send(ctx context.Context, conn net.Conn, dur time.Duration, lim throughput.Limiter) (throughput.Estimate, error) {
+	// A write blocked by a slow receiver mustn't overrun the measurement.
+	_ = conn.SetWriteDeadline(time.Now().Add(dur))
+	defer conn.SetWriteDeadline(time.Time{})
+
+	e, err := throughput.EstimateBandwidth(ctx, conn, zeros{}, dur, lim)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		err = nil
+	}
+	return e, err
+}
+
+// receive reads from conn for up to dur, or until EOF, throttled by lim if non-nil.
+func receive(ctx context.Context, conn net.Conn, dur time.Duration, lim throughput.Limiter) (throughput.Estimate, error) {
+	start := time.Now()
+	_ = conn.SetReadDeadline(start.Add(dur))
+	defer conn.SetReadDeadline(time.Time{})
+
+	ctx, cancel := context.WithTimeout(ctx, dur)
+	defer cancel()
+
+	var r io.Reader = conn
+	if lim != nil {
+		r = throughput.NewReader(ctx, conn, lim)
+	}
+
+	n, err := io.Copy(io.Discard, r)
+	e := throughput.Estimate{Bytes: n, Elapsed: min(time.Since(start), dur)}
+
+	// The measurement ending isn't an error, but the caller's context being done is.
+	if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) && context.Cause(ctx) == context.DeadlineExceeded {
+		err = nil
+	}
+	return e, err
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
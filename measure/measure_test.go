@@ -0,0 +1,59 @@
+package measure
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/iamcalledrob/throughput"
+)
+
+func TestMeasure(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- NewServer(nil).Serve(ctx, ln) }()
+
+	const limit = 512 * 1024
+	c := NewClient(ln.Addr().String(), throughput.NewTokenBucket(limit, 0))
+
+	up, err := c.Upload(context.Background(), 500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("upload: %s", err)
+	}
+	down, err := c.Download(context.Background(), 500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("download: %s", err)
+	}
+
+	for name, e := range map[string]throughput.Estimate{"upload": up, "download": down} {
+		if rate := e.Rate(); rate < 0.75*limit || rate > 1.25*limit {
+			t.Errorf("%s measured %.0f bytes/sec (%d bytes in %s), want about %d", name, rate, e.Bytes, e.Elapsed, limit)
+		}
+	}
+	// The meter counts bytes as the client's limiter allows them, which may differ from what was received by the
+	// final read or write.
+	if total, want := c.Meter().Total(), up.Bytes+down.Bytes; total < want-64*1024 || total > want+64*1024 {
+		t.Errorf("meter recorded %d bytes, want about %d", total, want)
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned %v after cancel, want nil", err)
+	}
+}
+
+func TestServerRejectsBadHeader(t *testing.T) {
+	if _, err := readHeader(bytes.NewReader(header{mode: 'X', dur: time.Second}.marshal())); err == nil {
+		t.Error("expected unknown mode to be rejected")
+	}
+	if _, err := readHeader(bytes.NewReader(header{mode: modeUpload, dur: time.Hour}.marshal())); err == nil {
+		t.Error("expected duration beyond MaxDuration to be rejected")
+	}
+}
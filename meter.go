@@ -0,0 +1,70 @@
+package throughput
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Meter measures throughput as an exponentially weighted moving average.
+//
+// Meter implements Limiter without ever delaying, so it can be chained with other limiters to measure the rate
+// they allow, e.g. Chain(lim, meter).
+type Meter struct {
+	window time.Duration
+	w      waiter
+	total  atomic.Int64
+
+	mu   sync.Mutex
+	rate float64 // bytes per second, as of last
+	last time.Time
+}
+
+// NewMeter returns a meter averaging over window: bytes recorded window ago carry 1/e of the weight of bytes
+// recorded now. Longer windows give smoother, but slower to react, rates.
+func NewMeter(window time.Duration, opts ...Option) *Meter {
+	m := &Meter{window: window, w: newWaiter(opts)}
+	m.last = m.w.now()
+	return m
+}
+
+// Wait records n bytes, and returns immediately.
+func (m *Meter) Wait(_ context.Context, n int) error {
+	m.Add(int64(n))
+	return nil
+}
+
+// Add records n bytes.
+func (m *Meter) Add(n int64) {
+	m.total.Add(n)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.decay(m.w.now())
+	m.rate += float64(n) / m.window.Seconds()
+}
+
+// Rate returns the average rate in bytes per second.
+func (m *Meter) Rate() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.decay(m.w.now())
+	return m.rate
+}
+
+// Total returns the total bytes recorded.
+func (m *Meter) Total() int64 {
+	return m.total.Load()
+}
+
+// decay ages the rate to now. Must be called with mu held.
+func (m *Meter) decay(now time.Time) {
+	if elapsed := now.Sub(m.last); elapsed > 0 {
+		m.rate *= math.Exp(-elapsed.Seconds() / m.window.Seconds())
+		m.last = now
+	}
+}
+
+var _ Limiter = (*Meter)(nil)
@@ -0,0 +1,30 @@
+package throughput
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMeter(t *testing.T) {
+	c := &stepClock{now: time.Unix(0, 0)}
+	m := NewMeter(time.Second, WithClock(c))
+
+	// 100 bytes every 100ms is 1000 B/s
+	for range 100 {
+		c.NewTimer(100 * time.Millisecond)
+		m.Add(100)
+	}
+	if got := m.Rate(); math.Abs(got-1000) > 100 {
+		t.Errorf("Rate() = %.0f, want ~1000", got)
+	}
+	if got := m.Total(); got != 10000 {
+		t.Errorf("Total() = %d, want 10000", got)
+	}
+
+	// The rate decays once bytes stop
+	c.NewTimer(5 * time.Second)
+	if got := m.Rate(); got > 10 {
+		t.Errorf("Rate() = %.0f after 5s idle, want ~0", got)
+	}
+}
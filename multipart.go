@@ -0,0 +1,135 @@
+package throughput
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// PartProgress reports the progress of UploadParts.
+type PartProgress struct {
+	Part       int   // index of the part which made progress
+	PartBytes  int64 // bytes of the part read so far
+	PartSize   int64
+	TotalBytes int64 // bytes of all parts read so far
+	TotalSize  int64
+}
+
+// SplitParts divides size bytes into parts of partSize bytes, the last of which may be shorter.
+func SplitParts(size, partSize int64) []ByteRange {
+	var parts []ByteRange
+	for off := int64(0); off < size; off += partSize {
+		parts = append(parts, ByteRange{Off: off, Len: min(partSize, size-off)})
+	}
+	return parts
+}
+
+// UploadParts calls upload for each of parts of src concurrently, as in an S3 or GCS multipart upload, with at
+// most concurrency parts in progress at once (or all of them, if concurrency isn't positive). Every part is read
+// through lim, so the aggregate upload rate is lim's however many parts are in flight.
+//
+// upload is passed the part's index and a reader of its bytes, which it should consume before returning. If
+// progress isn't nil, it is called as each part is read, one call at a time.
+//
+// The first error cancels the ctx of the remaining uploads, and is returned.
+func UploadParts(
+	ctx context.Context,
+	src io.ReaderAt,
+	parts []ByteRange,
+	lim Limiter,
+	concurrency int,
+	upload func(ctx context.Context, part int, r io.Reader) error,
+	progress func(PartProgress),
+) error {
+	if concurrency <= 0 {
+		concurrency = len(parts)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	t := &partTracker{progress: progress}
+	for _, p := range parts {
+		t.total += p.Len
+	}
+
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		firstErr error
+		slots    = make(chan struct{}, concurrency)
+	)
+	fail := func(err error) {
+		failOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for i, p := range parts {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			fail(ctx.Err())
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			r := &partReader{
+				r:    NewReader(ctx, io.NewSectionReader(src, p.Off, p.Len), lim),
+				part: i,
+				size: p.Len,
+				t:    t,
+			}
+			if err := upload(ctx, i, r); err != nil {
+				fail(err)
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// partTracker totals the progress of UploadParts, serializing calls to progress.
+type partTracker struct {
+	mu       sync.Mutex
+	progress func(PartProgress)
+	bytes    int64
+	total    int64
+}
+
+// partReader reads one part of UploadParts, reporting progress as it goes.
+type partReader struct {
+	r     io.Reader
+	part  int
+	bytes int64
+	size  int64
+	t     *partTracker
+}
+
+func (r *partReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.bytes += int64(n)
+
+		r.t.mu.Lock()
+		r.t.bytes += int64(n)
+		if r.t.progress != nil {
+			r.t.progress(PartProgress{
+				Part:       r.part,
+				PartBytes:  r.bytes,
+				PartSize:   r.size,
+				TotalBytes: r.t.bytes,
+				TotalSize:  r.t.total,
+			})
+		}
+		r.t.mu.Unlock()
+	}
+	return n, err
+}
@@ -0,0 +1,92 @@
+package throughput
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSplitParts(t *testing.T) {
+	parts := SplitParts(2500, 1000)
+	want := []ByteRange{{0, 1000}, {1000, 1000}, {2000, 500}}
+	if len(parts) != len(want) {
+		t.Fatalf("SplitParts returned %v, want %v", parts, want)
+	}
+	for i := range want {
+		if parts[i] != want[i] {
+			t.Errorf("SplitParts returned %v, want %v", parts, want)
+		}
+	}
+}
+
+func TestUploadParts(t *testing.T) {
+	data := make([]byte, 4000)
+	_, _ = rand.Read(data)
+
+	var (
+		mu       sync.Mutex
+		uploaded = make(map[int][]byte)
+		inFlight atomic.Int32
+		maxSeen  atomic.Int32
+		last     PartProgress
+	)
+	lim := &countingLimiter{}
+	err := UploadParts(context.Background(), bytes.NewReader(data), SplitParts(4000, 1000), lim, 2,
+		func(ctx context.Context, part int, r io.Reader) error {
+			if n := inFlight.Add(1); n > maxSeen.Load() {
+				maxSeen.Store(n)
+			}
+			defer inFlight.Add(-1)
+
+			b, err := io.ReadAll(r)
+			mu.Lock()
+			uploaded[part] = b
+			mu.Unlock()
+			return err
+		},
+		func(p PartProgress) {
+			if p.TotalBytes <= last.TotalBytes || p.TotalSize != 4000 || p.PartBytes > p.PartSize {
+				t.Errorf("unexpected progress %+v after %+v", p, last)
+			}
+			last = p
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 4 {
+		if !bytes.Equal(uploaded[i], data[i*1000:(i+1)*1000]) {
+			t.Errorf("part %d doesn't match the source", i)
+		}
+	}
+	if n := lim.n.Load(); n != 4000 {
+		t.Errorf("limiter was charged %d bytes, want 4000", n)
+	}
+	if n := maxSeen.Load(); n > 2 {
+		t.Errorf("%d parts were uploaded at once, want at most 2", n)
+	}
+	if last.TotalBytes != 4000 {
+		t.Errorf("final progress was %+v, want 4000 total bytes", last)
+	}
+}
+
+func TestUploadPartsError(t *testing.T) {
+	errUpload := errors.New("upload failed")
+	err := UploadParts(context.Background(), bytes.NewReader(make([]byte, 4000)), SplitParts(4000, 1000),
+		&countingLimiter{}, 1,
+		func(ctx context.Context, part int, r io.Reader) error {
+			if part == 1 {
+				return errUpload
+			}
+			_, err := io.Copy(io.Discard, r)
+			return err
+		}, nil)
+	if !errors.Is(err, errUpload) {
+		t.Errorf("UploadParts returned %v, want the upload's error", err)
+	}
+}
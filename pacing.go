@@ -0,0 +1,48 @@
+package throughput
+
+import (
+	"context"
+	"syscall"
+)
+
+// SetPacingRate asks the kernel to pace conn's outgoing traffic at no more than bytesPerSec, via the
+// SO_MAX_PACING_RATE socket option. A non-positive rate removes the limit. conn is typically a *net.TCPConn or
+// *net.UDPConn. It is only supported on Linux, and TCP pacing works best with the fq qdisc.
+func SetPacingRate(conn syscall.Conn, bytesPerSec int64) error {
+	return setPacingRate(conn, bytesPerSec)
+}
+
+// KernelPacedLimiter wraps an AdjustableLimiter, mirroring its rate onto a socket's SO_MAX_PACING_RATE. The kernel
+// then smooths the traffic at packet granularity, whilst the wrapped limiter handles accounting.
+type KernelPacedLimiter struct {
+	lim  AdjustableLimiter
+	conn syscall.Conn
+}
+
+// NewKernelPacedLimiter returns a limiter which waits on lim, and sets conn's pacing rate to match lim's rate,
+// now and whenever it is changed with SetLimit.
+func NewKernelPacedLimiter(conn syscall.Conn, lim AdjustableLimiter) (*KernelPacedLimiter, error) {
+	err := SetPacingRate(conn, lim.Limit())
+	if err != nil {
+		return nil, err
+	}
+	return &KernelPacedLimiter{lim: lim, conn: conn}, nil
+}
+
+func (k *KernelPacedLimiter) Wait(ctx context.Context, n int) error {
+	return k.lim.Wait(ctx, n)
+}
+
+// Limit returns the wrapped limiter's rate in bytes per second.
+func (k *KernelPacedLimiter) Limit() int64 {
+	return k.lim.Limit()
+}
+
+// SetLimit changes the wrapped limiter's rate and the socket's pacing rate to bytesPerSec. If the pacing rate
+// can't be set, the socket keeps its previous pacing rate.
+func (k *KernelPacedLimiter) SetLimit(bytesPerSec int64) {
+	k.lim.SetLimit(bytesPerSec)
+	_ = SetPacingRate(k.conn, bytesPerSec)
+}
+
+var _ AdjustableLimiter = (*KernelPacedLimiter)(nil)
@@ -0,0 +1,35 @@
+package throughput
+
+import (
+	"fmt"
+	"math"
+	"syscall"
+)
+
+// soMaxPacingRate is SO_MAX_PACING_RATE, which the syscall package doesn't define.
+const soMaxPacingRate = 47
+
+func setPacingRate(conn syscall.Conn, bytesPerSec int64) error {
+	// The option is a 32-bit unsigned rate, with all bits set meaning unlimited.
+	rate := uint32(math.MaxUint32)
+	if bytesPerSec > 0 && bytesPerSec < math.MaxUint32 {
+		rate = uint32(bytesPerSec)
+	}
+
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("setting pacing rate: %w", err)
+	}
+
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soMaxPacingRate, int(int32(rate)))
+	})
+	if err == nil {
+		err = sockErr
+	}
+	if err != nil {
+		return fmt.Errorf("setting pacing rate: %w", err)
+	}
+	return nil
+}
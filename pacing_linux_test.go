@@ -0,0 +1,52 @@
+package throughput
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestKernelPacedLimiter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	tcp := conn.(*net.TCPConn)
+
+	pacingRate := func() uint32 {
+		raw, _ := tcp.SyscallConn()
+		var rate int
+		_ = raw.Control(func(fd uintptr) {
+			rate, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, soMaxPacingRate)
+		})
+		if err != nil {
+			t.Fatalf("getsockopt: %v", err)
+		}
+		return uint32(rate)
+	}
+
+	k, err := NewKernelPacedLimiter(tcp, NewTokenBucket(1000, 1000))
+	if err != nil {
+		t.Fatalf("NewKernelPacedLimiter: %v", err)
+	}
+	if got := pacingRate(); got != 1000 {
+		t.Errorf("pacing rate = %d, want 1000", got)
+	}
+
+	k.SetLimit(5000)
+	if got := pacingRate(); got != 5000 || k.Limit() != 5000 {
+		t.Errorf("pacing rate = %d, limit = %d, want 5000", got, k.Limit())
+	}
+
+	k.SetLimit(0)
+	if got := pacingRate(); got != ^uint32(0) {
+		t.Errorf("pacing rate = %d, want unlimited", got)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package throughput
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+func setPacingRate(syscall.Conn, int64) error {
+	return fmt.Errorf("setting pacing rate: %w", errors.ErrUnsupported)
+}
@@ -0,0 +1,95 @@
+package throughput
+
+import "time"
+
+type periodKind int
+
+const (
+	periodDaily periodKind = iota
+	periodWeekly
+	periodMonthly
+)
+
+// QuotaPeriod is a calendar period after which a quota resets, e.g. monthly on the 1st at midnight UTC.
+// Create one with DailyPeriod, WeeklyPeriod or MonthlyPeriod.
+type QuotaPeriod struct {
+	kind periodKind
+	day  int           // weekday for weekly periods, day of month for monthly periods
+	at   time.Duration // offset from midnight
+	loc  *time.Location
+}
+
+// DailyPeriod resets every day, at the given offset from midnight in loc.
+func DailyPeriod(at time.Duration, loc *time.Location) QuotaPeriod {
+	return QuotaPeriod{kind: periodDaily, at: at, loc: loc}
+}
+
+// WeeklyPeriod resets every week on day, at the given offset from midnight in loc.
+func WeeklyPeriod(day time.Weekday, at time.Duration, loc *time.Location) QuotaPeriod {
+	return QuotaPeriod{kind: periodWeekly, day: int(day), at: at, loc: loc}
+}
+
+// MonthlyPeriod resets every month on the given day of the month, at the given offset from midnight in loc.
+// In months with fewer days, it resets on the last day of the month instead.
+func MonthlyPeriod(day int, at time.Duration, loc *time.Location) QuotaPeriod {
+	return QuotaPeriod{kind: periodMonthly, day: max(day, 1), at: at, loc: loc}
+}
+
+// Start returns the start of the period containing t: the most recent reset at or before t.
+func (p QuotaPeriod) Start(t time.Time) time.Time {
+	t = t.In(p.location())
+	y, m, d := t.Date()
+
+	var start time.Time
+	switch p.kind {
+	case periodDaily:
+		start = p.boundary(y, m, d)
+		if start.After(t) {
+			start = p.boundary(y, m, d-1)
+		}
+	case periodWeekly:
+		back := (int(t.Weekday()) - p.day + 7) % 7
+		start = p.boundary(y, m, d-back)
+		if start.After(t) {
+			start = p.boundary(y, m, d-back-7)
+		}
+	case periodMonthly:
+		start = p.monthBoundary(y, m)
+		if start.After(t) {
+			start = p.monthBoundary(y, m-1)
+		}
+	}
+	return start
+}
+
+// Next returns the first reset after t.
+func (p QuotaPeriod) Next(t time.Time) time.Time {
+	y, m, d := p.Start(t).Date()
+	switch p.kind {
+	case periodDaily:
+		return p.boundary(y, m, d+1)
+	case periodWeekly:
+		return p.boundary(y, m, d+7)
+	default:
+		return p.monthBoundary(y, m+1)
+	}
+}
+
+// boundary returns the reset time on the given date, which is normalized as by time.Date.
+func (p QuotaPeriod) boundary(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, p.location()).Add(p.at)
+}
+
+// monthBoundary returns the reset time in the given month, which is normalized as by time.Date.
+func (p QuotaPeriod) monthBoundary(y int, m time.Month) time.Time {
+	first := time.Date(y, m, 1, 0, 0, 0, 0, p.location())
+	last := first.AddDate(0, 1, -1).Day()
+	return p.boundary(first.Year(), first.Month(), min(p.day, last))
+}
+
+func (p QuotaPeriod) location() *time.Location {
+	if p.loc == nil {
+		return time.UTC
+	}
+	return p.loc
+}
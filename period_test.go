@@ -0,0 +1,79 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuotaPeriod(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("loading time zone: %v", err)
+	}
+	at := func(s string) time.Time {
+		tt, err := time.ParseInLocation("2006-01-02 15:04", s, ny)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tt
+	}
+
+	tests := []struct {
+		name        string
+		period      QuotaPeriod
+		t           string
+		start, next string
+	}{
+		{"DailyBeforeReset", DailyPeriod(2*time.Hour, ny), "2024-03-05 01:00", "2024-03-04 02:00", "2024-03-05 02:00"},
+		{"DailyAfterReset", DailyPeriod(2*time.Hour, ny), "2024-03-05 02:00", "2024-03-05 02:00", "2024-03-06 02:00"},
+		{"Weekly", WeeklyPeriod(time.Monday, 0, ny), "2024-03-06 12:00", "2024-03-04 00:00", "2024-03-11 00:00"},
+		{"WeeklyOnResetDay", WeeklyPeriod(time.Wednesday, 9*time.Hour, ny), "2024-03-06 08:00", "2024-02-28 09:00", "2024-03-06 09:00"},
+		{"Monthly", MonthlyPeriod(1, 0, ny), "2024-03-15 12:00", "2024-03-01 00:00", "2024-04-01 00:00"},
+		{"MonthlyShortMonth", MonthlyPeriod(31, 0, ny), "2024-03-15 12:00", "2024-02-29 00:00", "2024-03-31 00:00"},
+		{"MonthlyYearBoundary", MonthlyPeriod(1, 0, ny), "2024-12-31 23:59", "2024-12-01 00:00", "2025-01-01 00:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.period.Start(at(tt.t)); !got.Equal(at(tt.start)) {
+				t.Errorf("Start() = %v, want %s", got, tt.start)
+			}
+			if got := tt.period.Next(at(tt.t)); !got.Equal(at(tt.next)) {
+				t.Errorf("Next() = %v, want %s", got, tt.next)
+			}
+		})
+	}
+}
+
+func TestCalendarQuotaLimiter(t *testing.T) {
+	c := &stepClock{now: time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC)}
+	q := NewCalendarQuotaLimiter(100, MonthlyPeriod(1, 0, time.UTC), nil, WithClock(c))
+	ctx := context.Background()
+
+	_ = q.Wait(ctx, 100)
+	if err := q.Wait(ctx, 1); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Wait beyond quota: err = %v, want ErrQuotaExceeded", err)
+	}
+	if got, want := q.ResetsAt(), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("ResetsAt() = %v, want %v", got, want)
+	}
+	data, _ := q.MarshalBinary()
+
+	// A new month restores the whole quota
+	c.NewTimer(time.Hour)
+	if got := q.Remaining(); got != 100 {
+		t.Errorf("Remaining() = %d in new period, want 100", got)
+	}
+	if err := q.Wait(ctx, 1); err != nil {
+		t.Errorf("Wait in new period: %v", err)
+	}
+
+	// A snapshot from last month doesn't carry over
+	restored := NewCalendarQuotaLimiter(100, MonthlyPeriod(1, 0, time.UTC), nil, WithClock(c))
+	_ = restored.UnmarshalBinary(data)
+	if got := restored.Used(); got != 0 {
+		t.Errorf("Used() = %d after restoring last month's snapshot, want 0", got)
+	}
+}
@@ -0,0 +1,41 @@
+package throughput
+
+import (
+	"context"
+	"time"
+)
+
+// Typical links, for use with NewEmulatedConn -- or with plain limiters via LinkProfile.NewLimiter -- so tests can
+// emulate a link by name rather than with magic numbers. Rates are per direction, and latencies one-way.
+var (
+	// Profile56k is a dial-up modem.
+	Profile56k = LinkProfile{Rate: 56_000 / 8, Latency: 60 * time.Millisecond, Jitter: 10 * time.Millisecond}
+
+	// Profile3G is a typical 3G mobile connection.
+	Profile3G = LinkProfile{Rate: 1_500_000 / 8, Latency: 100 * time.Millisecond, Jitter: 30 * time.Millisecond}
+
+	// ProfileLTE is a typical 4G LTE mobile connection.
+	ProfileLTE = LinkProfile{Rate: 12_000_000 / 8, Latency: 35 * time.Millisecond, Jitter: 10 * time.Millisecond}
+
+	// ProfileSatellite is a geostationary satellite link, with high latency but reasonable bandwidth.
+	ProfileSatellite = LinkProfile{Rate: 10_000_000 / 8, Latency: 300 * time.Millisecond, Jitter: 20 * time.Millisecond}
+
+	// ProfileGigabitLAN is a wired gigabit local network.
+	ProfileGigabitLAN = LinkProfile{Rate: 1_000_000_000 / 8, Latency: 100 * time.Microsecond}
+)
+
+// NewLimiter returns a limiter pacing bytes at the profile's rate, for use with plain readers and writers.
+// Latency, jitter and loss only apply to an EmulatedConn.
+func (p LinkProfile) NewLimiter(opts ...Option) Limiter {
+	if p.Rate <= 0 {
+		return unlimited{}
+	}
+	return NewPacer(p.Rate, opts...)
+}
+
+// unlimited is a Limiter which never waits.
+type unlimited struct{}
+
+func (unlimited) Wait(context.Context, int) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+package throughput
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLinkProfile_NewLimiter(t *testing.T) {
+	c := &stepClock{now: time.Unix(0, 0)}
+	lim := Profile56k.NewLimiter(WithClock(c))
+
+	// 7000 bytes takes 1s at 56 kbit/s
+	_ = lim.Wait(context.Background(), 7000)
+	if got := c.Now().Sub(time.Unix(0, 0)); got != time.Second {
+		t.Errorf("7000 bytes took %v, want 1s", got)
+	}
+
+	if err := (LinkProfile{}).NewLimiter().Wait(context.Background(), 1<<30); err != nil {
+		t.Errorf("unlimited profile: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package throughput
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PressureProbe reports how saturated a resource is, from 0 (idle) to 1 (saturated).
+type PressureProbe func() (float64, error)
+
+// DiskUtilization returns a probe reporting the fraction of time the named block device (e.g. "sda" or
+// "nvme0n1") was busy since the probe was last called, from /proc/diskstats. The first call reports 0.
+// It is only supported on Linux.
+func DiskUtilization(device string) PressureProbe {
+	var mu sync.Mutex
+	var lastTicks int64
+	var lastAt time.Time
+
+	return func() (float64, error) {
+		f, err := os.Open("/proc/diskstats")
+		if err != nil {
+			return 0, fmt.Errorf("reading disk stats: %w", err)
+		}
+		defer f.Close()
+
+		ticks, err := parseDiskIOTicks(f, device)
+		if err != nil {
+			return 0, err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		var util float64
+		if !lastAt.IsZero() {
+			if elapsed := now.Sub(lastAt).Milliseconds(); elapsed > 0 {
+				util = min(float64(ticks-lastTicks)/float64(elapsed), 1)
+			}
+		}
+		lastTicks, lastAt = ticks, now
+		return util, nil
+	}
+}
+
+// IOPressure is a probe reporting the share of time in the last 10 seconds in which some tasks were stalled on
+// IO, from the kernel's pressure stall information in /proc/pressure/io. It is only supported on Linux.
+func IOPressure() (float64, error) {
+	f, err := os.Open("/proc/pressure/io")
+	if err != nil {
+		return 0, fmt.Errorf("reading IO pressure: %w", err)
+	}
+	defer f.Close()
+	return parsePSI(f)
+}
+
+// parseDiskIOTicks returns the milliseconds spent doing IO by device, in the format of /proc/diskstats.
+func parseDiskIOTicks(r io.Reader, device string) (int64, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		// major, minor, name, then 11 or more stats, of which the 10th is the time spent doing IO.
+		fields := strings.Fields(s.Text())
+		if len(fields) < 13 || fields[2] != device {
+			continue
+		}
+		return strconv.ParseInt(fields[12], 10, 64)
+	}
+	if err := s.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no disk stats for %s", device)
+}
+
+// parsePSI returns the "some avg10" figure from a pressure stall information file, as a fraction.
+func parsePSI(r io.Reader) (float64, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || fields[0] != "some" {
+			continue
+		}
+		v, ok := strings.CutPrefix(fields[1], "avg10=")
+		if !ok {
+			break
+		}
+		pct, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing pressure: %w", err)
+		}
+		return pct / 100, nil
+	}
+	if err := s.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("parsing pressure: no avg10")
+}
+
+// PressureLimiter wraps an AdjustableLimiter, throttling harder as a resource becomes saturated -- e.g. so a
+// backup or ingest tool doesn't starve foreground workloads of disk bandwidth.
+//
+// Below the threshold pressure, the wrapped limiter's original rate applies. Above it, the rate falls linearly,
+// reaching the minimum rate at full saturation. Like RampLimiter, the probe is consulted lazily as Wait is called,
+// at most once per interval. If the probe fails, the rate is left unchanged.
+type PressureLimiter struct {
+	lim       AdjustableLimiter
+	probe     PressureProbe
+	threshold float64
+	min, max  int64
+	interval  time.Duration
+	w         waiter
+
+	mu        sync.Mutex
+	lastProbe time.Time
+	pressure  float64
+}
+
+// NewPressureLimiter returns a limiter reducing lim's rate from its current rate towards minBytesPerSec as the
+// pressure reported by probe rises from threshold to 1.
+func NewPressureLimiter(
+	lim AdjustableLimiter,
+	probe PressureProbe,
+	threshold float64,
+	minBytesPerSec int64,
+	interval time.Duration,
+	opts ...Option,
+) *PressureLimiter {
+	return &PressureLimiter{
+		lim:       lim,
+		probe:     probe,
+		threshold: min(max(threshold, 0), 1),
+		min:       minBytesPerSec,
+		max:       lim.Limit(),
+		interval:  interval,
+		w:         newWaiter(opts),
+	}
+}
+
+func (p *PressureLimiter) Wait(ctx context.Context, n int) error {
+	p.adjust()
+	return p.lim.Wait(ctx, n)
+}
+
+func (p *PressureLimiter) adjust() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.w.now()
+	if !p.lastProbe.IsZero() && now.Sub(p.lastProbe) < p.interval {
+		return
+	}
+	p.lastProbe = now
+
+	pressure, err := p.probe()
+	if err != nil {
+		return
+	}
+	p.pressure = min(max(pressure, 0), 1)
+
+	rate := p.max
+	if p.pressure > p.threshold {
+		excess := (p.pressure - p.threshold) / (1 - p.threshold)
+		rate = p.max - int64(excess*float64(p.max-p.min))
+	}
+	if rate != p.lim.Limit() {
+		p.lim.SetLimit(rate)
+	}
+}
+
+// Pressure returns the pressure last reported by the probe.
+func (p *PressureLimiter) Pressure() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pressure
+}
+
+var _ Limiter = (*PressureLimiter)(nil)
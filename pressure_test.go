@@ -0,0 +1,64 @@
+package throughput
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPressureLimiter(t *testing.T) {
+	c := &stepClock{now: time.Unix(0, 0)}
+	tb := NewTokenBucket(1000, 1<<20, WithClock(c))
+
+	var pressure float64
+	probe := func() (float64, error) { return pressure, nil }
+	p := NewPressureLimiter(tb, probe, 0.5, 200, time.Second, WithClock(c))
+	ctx := context.Background()
+
+	tests := []struct {
+		pressure float64
+		want     int64
+	}{
+		{0.2, 1000}, // below threshold
+		{0.75, 600}, // halfway to saturation
+		{1, 200},    // saturated
+		{0.5, 1000}, // recovered
+	}
+	for _, tt := range tests {
+		pressure = tt.pressure
+		c.NewTimer(time.Second)
+		_ = p.Wait(ctx, 1)
+		if got := tb.Limit(); got != tt.want {
+			t.Errorf("at pressure %.2f, rate = %d, want %d", tt.pressure, got, tt.want)
+		}
+	}
+
+	// The probe is consulted at most once per interval
+	pressure = 1
+	_ = p.Wait(ctx, 1)
+	if got := tb.Limit(); got != 1000 {
+		t.Errorf("rate changed to %d within the interval", got)
+	}
+}
+
+func TestParseDiskIOTicks(t *testing.T) {
+	diskstats := `   8       0 sda 1000 20 30000 400 500 60 70000 800 0 12345 1200 0 0 0 0
+   8       1 sda1 900 20 28000 380 480 60 68000 780 0 11111 1160 0 0 0 0
+`
+	ticks, err := parseDiskIOTicks(strings.NewReader(diskstats), "sda")
+	if err != nil || ticks != 12345 {
+		t.Errorf("parseDiskIOTicks() = %d, %v, want 12345", ticks, err)
+	}
+	if _, err = parseDiskIOTicks(strings.NewReader(diskstats), "sdb"); err == nil {
+		t.Error("expected error for missing device")
+	}
+}
+
+func TestParsePSI(t *testing.T) {
+	psi := "some avg10=12.50 avg60=5.00 avg300=1.00 total=123456\nfull avg10=2.00 avg60=1.00 avg300=0.50 total=23456\n"
+	got, err := parsePSI(strings.NewReader(psi))
+	if err != nil || got != 0.125 {
+		t.Errorf("parsePSI() = %v, %v, want 0.125", got, err)
+	}
+}
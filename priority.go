@@ -0,0 +1,38 @@
+package throughput
+
+import "context"
+
+// PriorityLimiter wraps a shared limiter so that higher-priority waiters are admitted before lower-priority ones,
+// so interactive traffic isn't stuck behind bulk transfers.
+//
+// Waits on the wrapped limiter happen one at a time, in priority order. With preemption enabled, a waiter
+// arriving with a higher priority than the one currently waiting on the wrapped limiter cancels that wait,
+// and the lower-priority waiter re-queues. The wrapped limiter should treat a cancelled wait as a refund, as
+// RateLimiterAdapter and TokenBucket do, otherwise the preempted bytes are charged twice.
+type PriorityLimiter struct {
+	s scheduler
+}
+
+// NewPriorityLimiter returns a PriorityLimiter admitting waiters to lim in priority order.
+func NewPriorityLimiter(lim Limiter, preempt bool) *PriorityLimiter {
+	return &PriorityLimiter{s: scheduler{lim: lim, preempt: preempt}}
+}
+
+// Class returns a limiter for waiters of the given priority. Higher priorities are admitted first, and waiters
+// of equal priority are admitted in FIFO order.
+func (p *PriorityLimiter) Class(priority int) Limiter {
+	return &priorityClass{p: p, key: -float64(priority)}
+}
+
+type priorityClass struct {
+	p   *PriorityLimiter
+	key float64
+}
+
+func (c *priorityClass) Wait(ctx context.Context, n int) error {
+	return c.p.s.wait(ctx, n, c.keyFunc)
+}
+
+func (c *priorityClass) keyFunc() float64 {
+	return c.key
+}
@@ -0,0 +1,69 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPriorityLimiterOrder(t *testing.T) {
+	p := NewPriorityLimiter(NewPacer(100*1024), false)
+	low, high := p.Class(0), p.Class(10)
+
+	var mu sync.Mutex
+	var order []string
+	wait := func(lim Limiter, name string) {
+		_ = lim.Wait(context.Background(), 1024)
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for _, w := range []struct {
+		lim  Limiter
+		name string
+	}{{low, "low1"}, {low, "low2"}, {high, "high"}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait(w.lim, w.name)
+		}()
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+
+	// low1 was admitted before the others arrived, but high overtakes low2
+	want := []string{"low1", "high", "low2"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("admitted in order %v, want %v", order, want)
+		}
+	}
+}
+
+func TestPriorityLimiterPreempt(t *testing.T) {
+	p := NewPriorityLimiter(NewPacer(1024), true)
+	low, high := p.Class(0), p.Class(1)
+
+	lowDone := make(chan time.Duration)
+	start := time.Now()
+	go func() {
+		_ = low.Wait(context.Background(), 512)
+		lowDone <- time.Since(start)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// The low-priority wait is preempted, so high only waits for its own bytes
+	_ = high.Wait(context.Background(), 10)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("high priority wait took %s, expected preemption", elapsed)
+	}
+
+	// The preempted wait is refunded and retried, rather than charged twice
+	err := verifyWithSlop(<-lowDone, 522*time.Second/1024, 50*time.Millisecond)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
@@ -0,0 +1,57 @@
+package throughput
+
+import (
+	"net/http"
+	"net/http/httputil"
+)
+
+// ProxyKeyFunc identifies the limiter a proxied response is charged to.
+type ProxyKeyFunc func(resp *http.Response) string
+
+// ByBackend charges responses to the backend host which served them.
+func ByBackend(resp *http.Response) string {
+	return resp.Request.URL.Host
+}
+
+// ByClient charges responses to the client they were proxied for, as identified by clientIP. The request it is
+// given is the one sent to the backend, which retains the client's RemoteAddr, so RemoteIP works as expected.
+func ByClient(clientIP ClientIPFunc) ProxyKeyFunc {
+	if clientIP == nil {
+		clientIP = RemoteIP
+	}
+	return func(resp *http.Response) string {
+		return clientIP(resp.Request)
+	}
+}
+
+// ShapeReverseProxy throttles the response bodies p proxies, using a limiter from limiters per key. This allows
+// API gateways to enforce egress bandwidth policies, e.g. per backend:
+//
+//	limiters := NewRegistry(func(string) Limiter { return NewTokenBucket(10<<20, 1<<20) }, time.Minute)
+//	ShapeReverseProxy(proxy, limiters, ByBackend)
+//
+// Any existing ModifyResponse hook is called first. Protocol upgrades, e.g. to WebSockets, are not throttled.
+func ShapeReverseProxy(p *httputil.ReverseProxy, limiters *Registry[string], key ProxyKeyFunc, opts ...HTTPOption) {
+	acct := newHTTPAccounting(opts)
+	next := p.ModifyResponse
+	p.ModifyResponse = func(resp *http.Response) error {
+		if next != nil {
+			if err := next(resp); err != nil {
+				return err
+			}
+		}
+
+		// ReverseProxy requires an upgraded response's body to be writable, so it can't be wrapped.
+		if resp.StatusCode == http.StatusSwitchingProtocols || acct.exempt(resp.ContentLength) {
+			return nil
+		}
+
+		ctx := resp.Request.Context()
+		lim := limiters.Get(key(resp))
+		if err := acct.chargeHeaders(ctx, lim, statusLineSize, resp.Header); err != nil {
+			return err
+		}
+		resp.Body = &limitedBody{Reader: NewReader(ctx, resp.Body, lim), Closer: resp.Body}
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+package throughput
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestShapeReverseProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, 32*1024))
+	}))
+	defer backend.Close()
+	target, _ := url.Parse(backend.URL)
+
+	clock := NewVirtualClock(time.Unix(0, 0))
+	var keys []string
+	limiters := NewRegistry(func(key string) Limiter {
+		keys = append(keys, key)
+		return NewTokenBucket(64*1024, 0, WithClock(clock))
+	}, time.Minute)
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	ShapeReverseProxy(proxy, limiters, ByBackend)
+	front := httptest.NewServer(proxy)
+	defer front.Close()
+
+	// Both responses come from the same backend, so share its limiter.
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(front.URL)
+		if err != nil {
+			t.Fatalf("get: %s", err)
+		}
+		n, _ := io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if n != 32*1024 {
+			t.Errorf("response was %d bytes, want 32768", n)
+		}
+	}
+
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != time.Second {
+		t.Errorf("64KB at 64KB/sec took %s, want 1s", elapsed)
+	}
+	if len(keys) != 1 || keys[0] != target.Host {
+		t.Errorf("limiters created for %v, want just %s", keys, target.Host)
+	}
+}
+
+func TestByClient(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	if got := ByClient(nil)(&http.Response{Request: r}); got != "192.0.2.1" {
+		t.Errorf("ByClient(nil) = %q, want 192.0.2.1", got)
+	}
+}
@@ -0,0 +1,223 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned by QuotaLimiter once its byte budget is exhausted.
+var ErrQuotaExceeded = errors.New("throughput: quota exceeded")
+
+// QuotaLimiter enforces an absolute byte budget, e.g. "at most 5 GiB for this session", optionally in addition
+// to a rate.
+//
+// Once the budget has been used, Wait returns ErrQuotaExceeded so callers can stop cleanly. As Reader and Writer
+// wait after I/O, the read or write which crosses the budget has already happened, and is the one which fails --
+// so the budget may be overshot by up to one read or write. Use Remaining to size I/O if that matters.
+//
+// A quota created with NewCalendarQuotaLimiter resets at the start of every period, e.g. "100 GiB per month,
+// reset on the 1st", for metered connections and cloud egress budgets.
+//
+// A quota created with NewStoredQuotaLimiter keeps the bytes used in a QuotaStore, so it can survive restarts
+// and be shared between processes.
+type QuotaLimiter struct {
+	lim    Limiter
+	period *QuotaPeriod
+	store  QuotaStore
+	key    string
+	w      waiter
+
+	mu          sync.Mutex
+	quota       int64
+	used        int64 // when using a store, the last value it returned
+	periodStart time.Time
+}
+
+// NewQuotaLimiter returns a limiter allowing quota bytes in total, which are also charged to lim.
+// A nil lim means the quota is enforced without a rate.
+func NewQuotaLimiter(quota int64, lim Limiter, opts ...Option) *QuotaLimiter {
+	return &QuotaLimiter{lim: lim, quota: quota, w: newWaiter(opts)}
+}
+
+// NewCalendarQuotaLimiter returns a limiter allowing quota bytes per period, which are also charged to lim.
+// A nil lim means the quota is enforced without a rate.
+func NewCalendarQuotaLimiter(quota int64, period QuotaPeriod, lim Limiter, opts ...Option) *QuotaLimiter {
+	q := NewQuotaLimiter(quota, lim, opts...)
+	q.period = &period
+	q.periodStart = period.Start(q.w.now())
+	return q
+}
+
+// NewStoredQuotaLimiter returns a limiter allowing quota bytes, which are also charged to lim, with the bytes used
+// kept in store under key. If period is non-nil, the quota resets every period: the key is suffixed with the
+// start of each period, so processes sharing the store agree on when it resets.
+func NewStoredQuotaLimiter(
+	quota int64,
+	store QuotaStore,
+	key string,
+	period *QuotaPeriod,
+	lim Limiter,
+	opts ...Option,
+) *QuotaLimiter {
+	q := NewQuotaLimiter(quota, lim, opts...)
+	q.store = store
+	q.key = key
+	if period != nil {
+		q.period = period
+		q.periodStart = period.Start(q.w.now())
+	}
+	return q
+}
+
+// storeKey returns the key for the current period. Must be called with mu held.
+func (q *QuotaLimiter) storeKey() string {
+	if q.period == nil {
+		return q.key
+	}
+	return q.key + "/" + q.periodStart.UTC().Format(time.RFC3339)
+}
+
+// add adds n to the bytes used in the current period, which may be negative, and returns the new total.
+func (q *QuotaLimiter) add(ctx context.Context, n int64) (int64, error) {
+	q.mu.Lock()
+	q.rollover()
+	if q.store == nil {
+		defer q.mu.Unlock()
+		q.used = max(q.used+n, 0)
+		return q.used, nil
+	}
+	key := q.storeKey()
+	q.mu.Unlock()
+
+	used, err := q.store.Add(ctx, key, n)
+	if err != nil {
+		return 0, fmt.Errorf("updating quota store: %w", err)
+	}
+
+	q.mu.Lock()
+	if key == q.storeKey() {
+		q.used = used
+	}
+	q.mu.Unlock()
+	return used, nil
+}
+
+// rollover resets the bytes used if a new period has started. Must be called with mu held.
+func (q *QuotaLimiter) rollover() {
+	if q.period == nil {
+		return
+	}
+	if start := q.period.Start(q.w.now()); start.After(q.periodStart) {
+		q.periodStart = start
+		q.used = 0
+	}
+}
+
+func (q *QuotaLimiter) Wait(ctx context.Context, n int) error {
+	used, err := q.add(ctx, int64(n))
+	if err != nil {
+		return err
+	}
+
+	if quota := q.Quota(); used > quota {
+		if used-int64(n) >= quota {
+			// The quota was already exhausted, so these bytes aren't charged.
+			_, _ = q.add(ctx, -int64(n))
+		}
+		return ErrQuotaExceeded
+	}
+
+	if q.lim != nil {
+		err = q.lim.Wait(ctx, n)
+		if err != nil {
+			// Only the quota is refunded, as lim is left to undo its own charge if it can
+			_, _ = q.add(context.Background(), -int64(n))
+			return err
+		}
+	}
+	return nil
+}
+
+// Refund returns n bytes to the budget, e.g. when a transfer they were charged for didn't happen, and to the rate
+// limiter, if it is a Refunder.
+func (q *QuotaLimiter) Refund(n int) {
+	_, _ = q.add(context.Background(), -int64(n))
+	if r, ok := q.lim.(Refunder); ok {
+		r.Refund(n)
+	}
+}
+
+func (q *QuotaLimiter) refunds() bool {
+	return refunds(q.lim)
+}
+
+// Used returns the number of bytes charged against the budget. When using a store, if the store can't be read,
+// the last known value is returned.
+func (q *QuotaLimiter) Used() int64 {
+	q.mu.Lock()
+	q.rollover()
+	if q.store == nil {
+		defer q.mu.Unlock()
+		return q.used
+	}
+	key := q.storeKey()
+	q.mu.Unlock()
+
+	used, err := q.store.Get(context.Background(), key)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err == nil && key == q.storeKey() {
+		q.used = used
+	}
+	return q.used
+}
+
+// Remaining returns the number of bytes left in the budget.
+func (q *QuotaLimiter) Remaining() int64 {
+	return max(q.Quota()-q.Used(), 0)
+}
+
+// ResetsAt returns when the quota next resets, or the zero time if it was not created with a period.
+func (q *QuotaLimiter) ResetsAt() time.Time {
+	if q.period == nil {
+		return time.Time{}
+	}
+	return q.period.Next(q.w.now())
+}
+
+// Quota returns the budget in bytes.
+func (q *QuotaLimiter) Quota() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.quota
+}
+
+// SetQuota changes the budget to quota bytes. Bytes already used still count against it.
+func (q *QuotaLimiter) SetQuota(quota int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.quota = quota
+}
+
+// Reset forgets the bytes used, restoring the whole budget.
+func (q *QuotaLimiter) Reset() error {
+	q.mu.Lock()
+	q.rollover()
+	q.used = 0
+	key := q.storeKey()
+	q.mu.Unlock()
+
+	if q.store != nil {
+		err := q.store.Reset(context.Background(), key)
+		if err != nil {
+			return fmt.Errorf("resetting quota store: %w", err)
+		}
+	}
+	return nil
+}
+
+var _ Refunder = (*QuotaLimiter)(nil)
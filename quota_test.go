@@ -0,0 +1,84 @@
+package throughput
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestQuotaLimiter(t *testing.T) {
+	var rate countingLimiter
+	q := NewQuotaLimiter(100, &rate)
+	ctx := context.Background()
+
+	if err := q.Wait(ctx, 60); err != nil {
+		t.Fatalf("Wait within quota: %v", err)
+	}
+	if err := q.Wait(ctx, 40); err != nil {
+		t.Fatalf("Wait exactly using quota: %v", err)
+	}
+	if got := q.Remaining(); got != 0 {
+		t.Errorf("Remaining() = %d, want 0", got)
+	}
+	if err := q.Wait(ctx, 1); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Wait beyond quota: err = %v, want ErrQuotaExceeded", err)
+	}
+	if got := rate.n.Load(); got != 100 {
+		t.Errorf("rate limiter charged %d bytes, want 100", got)
+	}
+
+	q.Reset()
+	if got := q.Remaining(); got != 100 {
+		t.Errorf("Remaining() = %d after Reset, want 100", got)
+	}
+
+	data, _ := q.MarshalBinary()
+	_ = q.Wait(ctx, 30)
+	restored := NewQuotaLimiter(100, nil)
+	_ = restored.UnmarshalBinary(data)
+	if got := restored.Used(); got != 0 {
+		t.Errorf("Used() = %d after restore, want 0", got)
+	}
+	data, _ = q.MarshalBinary()
+	_ = restored.UnmarshalBinary(data)
+	if got := restored.Used(); got != 30 {
+		t.Errorf("Used() = %d after restore, want 30", got)
+	}
+}
+
+func TestQuotaLimiter_Reader(t *testing.T) {
+	src := bytes.NewReader(make([]byte, 1000))
+	r := NewReader(context.Background(), src, NewQuotaLimiter(250, nil))
+
+	buf := make([]byte, 100)
+	var total int
+	var err error
+	for err == nil {
+		var n int
+		n, err = r.Read(buf)
+		total += n
+	}
+
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("err = %v, want ErrQuotaExceeded", err)
+	}
+	if errors.Is(err, io.EOF) || total != 300 {
+		t.Errorf("read %d bytes, want 300 (overshooting by one read)", total)
+	}
+}
+
+func TestQuotaLimiter_RefundsLimiter(t *testing.T) {
+	tb := NewTokenBucket(1000, 1000)
+	q := NewQuotaLimiter(1000, tb)
+	_ = q.Wait(context.Background(), 600)
+
+	q.Refund(600)
+	if got := q.Used(); got != 0 {
+		t.Errorf("Used() = %d after Refund, want 0", got)
+	}
+	if got := tb.Tokens(); got < 999 {
+		t.Errorf("rate limiter has %v tokens after Refund, want 1000", got)
+	}
+}
@@ -0,0 +1,272 @@
+package throughput
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuotaStore persists the bytes used by quotas, allowing a QuotaLimiter to survive restarts, or to be shared
+// between processes via a shared backend such as a database.
+//
+// Implementations must be safe for concurrent use. Add must be atomic, as concurrent waits on a quota each add
+// their bytes and compare the result against the quota.
+type QuotaStore interface {
+	// Get returns the bytes used under key, or zero if there is no such key.
+	Get(ctx context.Context, key string) (int64, error)
+
+	// Add adds n to the bytes used under key, and returns the new total. n may be negative, but the total
+	// should not fall below zero.
+	Add(ctx context.Context, key string, n int64) (int64, error)
+
+	// Reset sets the bytes used under key to zero.
+	Reset(ctx context.Context, key string) error
+}
+
+// MemoryQuotaStore is a QuotaStore held in memory, which can be shared by quotas within a process.
+type MemoryQuotaStore struct {
+	mu   sync.Mutex
+	used map[string]int64
+}
+
+// NewMemoryQuotaStore returns an empty in-memory store.
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{used: make(map[string]int64)}
+}
+
+func (m *MemoryQuotaStore) Get(_ context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.used[key], nil
+}
+
+func (m *MemoryQuotaStore) Add(_ context.Context, key string, n int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.used[key] = max(m.used[key]+n, 0)
+	return m.used[key], nil
+}
+
+func (m *MemoryQuotaStore) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.used, key)
+	return nil
+}
+
+// FileQuotaStore is a QuotaStore kept in a JSON file, so quotas survive restarts.
+//
+// Changes are made in memory, and written to the file (atomically, via a temporary file) at most once per
+// interval, and on Flush or Close -- so changes since the last write are lost if the process crashes. The file
+// should only be used by one process at a time.
+//
+// Keys of periodic quotas, which are suffixed with the start of each period, are dropped from the file once a
+// later period's key has been used, so the file doesn't grow with every period.
+type FileQuotaStore struct {
+	path     string
+	interval time.Duration
+
+	mu    sync.Mutex
+	used  map[string]int64 // nil until loaded
+	dirty bool             // whether used has changed since it was last written
+	saved time.Time
+}
+
+// NewFileQuotaStore returns a store kept in the file at path, which is created if it doesn't exist. Changes are
+// written at most once per interval; an interval of zero writes every change.
+func NewFileQuotaStore(path string, interval time.Duration) *FileQuotaStore {
+	return &FileQuotaStore{path: path, interval: interval}
+}
+
+func (f *FileQuotaStore) Get(_ context.Context, key string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := f.load()
+	if err != nil {
+		return 0, err
+	}
+	return f.used[key], nil
+}
+
+func (f *FileQuotaStore) Add(_ context.Context, key string, n int64) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := f.load()
+	if err != nil {
+		return 0, err
+	}
+
+	prev, existed := f.used[key]
+	used := max(prev+n, 0)
+	f.used[key] = used
+	err = f.changed()
+	if err != nil {
+		if existed {
+			f.used[key] = prev
+		} else {
+			delete(f.used, key)
+		}
+		return 0, err
+	}
+	return used, nil
+}
+
+func (f *FileQuotaStore) Reset(_ context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := f.load()
+	if err != nil {
+		return err
+	}
+
+	prev, existed := f.used[key]
+	if !existed {
+		return nil
+	}
+	delete(f.used, key)
+	err = f.changed()
+	if err != nil {
+		f.used[key] = prev
+		return err
+	}
+	return nil
+}
+
+// Flush writes any changes which haven't been written yet.
+func (f *FileQuotaStore) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.flush()
+}
+
+// Close writes any changes which haven't been written yet. The store shouldn't be used afterwards.
+func (f *FileQuotaStore) Close() error {
+	return f.Flush()
+}
+
+// changed marks used as changed, writing it if interval has passed since the last write. If the write fails, the
+// store is left as it was before the change, which the caller must undo. Must be called with mu held.
+func (f *FileQuotaStore) changed() error {
+	wasDirty := f.dirty
+	f.dirty = true
+	if time.Since(f.saved) < f.interval {
+		return nil
+	}
+
+	err := f.flush()
+	if err != nil {
+		f.dirty = wasDirty
+		return err
+	}
+	return nil
+}
+
+// flush writes used if it has changed. Must be called with mu held.
+func (f *FileQuotaStore) flush() error {
+	if !f.dirty {
+		return nil
+	}
+	f.prune()
+	err := f.save()
+	if err != nil {
+		return err
+	}
+	f.dirty = false
+	f.saved = time.Now()
+	return nil
+}
+
+// prune drops keys of periodic quotas, suffixed with the start of a period, for which a later period's key exists.
+// Must be called with mu held.
+func (f *FileQuotaStore) prune() {
+	latest := make(map[string]time.Time)
+	for key := range f.used {
+		if prefix, start, ok := splitPeriodKey(key); ok && start.After(latest[prefix]) {
+			latest[prefix] = start
+		}
+	}
+	for key := range f.used {
+		if prefix, start, ok := splitPeriodKey(key); ok && start.Before(latest[prefix]) {
+			delete(f.used, key)
+		}
+	}
+}
+
+// splitPeriodKey splits a key created by QuotaLimiter for a periodic quota into its prefix and period start.
+func splitPeriodKey(key string) (prefix string, start time.Time, ok bool) {
+	i := strings.LastIndexByte(key, '/')
+	if i < 0 {
+		return "", time.Time{}, false
+	}
+	start, err := time.Parse(time.RFC3339, key[i+1:])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return key[:i], start, true
+}
+
+// load reads the file, the first time it's needed. Must be called with mu held.
+func (f *FileQuotaStore) load() error {
+	if f.used != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		f.used = make(map[string]int64)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading quota store: %w", err)
+	}
+
+	used := make(map[string]int64)
+	err = json.Unmarshal(data, &used)
+	if err != nil {
+		return fmt.Errorf("parsing quota store %s: %w", f.path, err)
+	}
+	f.used = used
+	return nil
+}
+
+// save writes the file via a temporary file, so it is never left partially written. Must be called with mu held.
+func (f *FileQuotaStore) save() error {
+	data, err := json.Marshal(f.used)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("writing quota store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), f.path)
+	}
+	if err != nil {
+		return fmt.Errorf("writing quota store: %w", err)
+	}
+	return nil
+}
+
+var _ QuotaStore = (*MemoryQuotaStore)(nil)
+var _ QuotaStore = (*FileQuotaStore)(nil)
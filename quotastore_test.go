@@ -0,0 +1,126 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQuotaStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) QuotaStore{
+		"Memory": func(t *testing.T) QuotaStore { return NewMemoryQuotaStore() },
+		"File": func(t *testing.T) QuotaStore {
+			return NewFileQuotaStore(filepath.Join(t.TempDir(), "quota.json"), 0)
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+			ctx := context.Background()
+
+			if got, _ := s.Add(ctx, "a", 10); got != 10 {
+				t.Errorf("Add() = %d, want 10", got)
+			}
+			if got, _ := s.Add(ctx, "a", 5); got != 15 {
+				t.Errorf("Add() = %d, want 15", got)
+			}
+			if got, _ := s.Add(ctx, "a", -20); got != 0 {
+				t.Errorf("Add() = %d, want 0 as the total can't go negative", got)
+			}
+			_, _ = s.Add(ctx, "b", 7)
+			if got, _ := s.Get(ctx, "b"); got != 7 {
+				t.Errorf("Get() = %d, want 7", got)
+			}
+			_ = s.Reset(ctx, "b")
+			if got, _ := s.Get(ctx, "b"); got != 0 {
+				t.Errorf("Get() = %d after Reset, want 0", got)
+			}
+		})
+	}
+}
+
+func TestFileQuotaStore_Restart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	ctx := context.Background()
+
+	q := NewStoredQuotaLimiter(100, NewFileQuotaStore(path, 0), "egress", nil, nil)
+	_ = q.Wait(ctx, 60)
+
+	// A new process picks up where the last left off
+	q = NewStoredQuotaLimiter(100, NewFileQuotaStore(path, 0), "egress", nil, nil)
+	if got := q.Used(); got != 60 {
+		t.Errorf("Used() = %d after restart, want 60", got)
+	}
+	_ = q.Wait(ctx, 40)
+	if err := q.Wait(ctx, 1); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Wait beyond quota: err = %v, want ErrQuotaExceeded", err)
+	}
+}
+
+func TestFileQuotaStore_Interval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	ctx := context.Background()
+
+	// The first change is written immediately, and later ones once the interval has passed
+	s := NewFileQuotaStore(path, time.Hour)
+	_, _ = s.Add(ctx, "a", 10)
+	_, _ = s.Add(ctx, "a", 5)
+	if got, _ := NewFileQuotaStore(path, 0).Get(ctx, "a"); got != 10 {
+		t.Errorf("file has %d bytes before Close, want 10", got)
+	}
+
+	err := s.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got, _ := NewFileQuotaStore(path, 0).Get(ctx, "a"); got != 15 {
+		t.Errorf("file has %d bytes after Close, want 15", got)
+	}
+}
+
+func TestFileQuotaStore_PrunesPeriods(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	ctx := context.Background()
+
+	s := NewFileQuotaStore(path, 0)
+	_, _ = s.Add(ctx, "egress/2024-03-05T00:00:00Z", 10)
+	_, _ = s.Add(ctx, "ingress/2024-03-05T00:00:00Z", 10)
+	_, _ = s.Add(ctx, "egress/2024-03-06T00:00:00Z", 20)
+
+	s = NewFileQuotaStore(path, 0)
+	if got, _ := s.Get(ctx, "egress/2024-03-05T00:00:00Z"); got != 0 {
+		t.Errorf("expired period has %d bytes, want it pruned", got)
+	}
+	if got, _ := s.Get(ctx, "egress/2024-03-06T00:00:00Z"); got != 20 {
+		t.Errorf("current period has %d bytes, want 20", got)
+	}
+	if got, _ := s.Get(ctx, "ingress/2024-03-05T00:00:00Z"); got != 10 {
+		t.Errorf("another key's period has %d bytes, want 10", got)
+	}
+}
+
+func TestStoredQuotaLimiter_Period(t *testing.T) {
+	c := &stepClock{now: time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryQuotaStore()
+	period := DailyPeriod(0, time.UTC)
+	ctx := context.Background()
+
+	// Quotas sharing a store share a budget
+	q1 := NewStoredQuotaLimiter(100, store, "egress", &period, nil, WithClock(c))
+	q2 := NewStoredQuotaLimiter(100, store, "egress", &period, nil, WithClock(c))
+	_ = q1.Wait(ctx, 70)
+	if err := q2.Wait(ctx, 40); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Wait beyond shared quota: err = %v, want ErrQuotaExceeded", err)
+	}
+	if got, _ := store.Get(ctx, "egress/2024-03-05T00:00:00Z"); got != 110 {
+		t.Errorf("stored %d bytes under the period's key, want 110", got)
+	}
+
+	c.NewTimer(12 * time.Hour)
+	if got := q1.Remaining(); got != 100 {
+		t.Errorf("Remaining() = %d in new period, want 100", got)
+	}
+}
@@ -0,0 +1,101 @@
+package throughput
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// RampCurve maps progress through a ramp (0 to 1) to the fraction of the rate increase to apply (0 to 1).
+type RampCurve func(progress float64) float64
+
+// LinearRamp increases the rate at a constant pace.
+func LinearRamp(progress float64) float64 {
+	return progress
+}
+
+// ExponentialRamp increases the rate slowly at first, then quickly, similar in spirit to TCP slow start.
+func ExponentialRamp(progress float64) float64 {
+	return (math.Pow(2, 10*progress) - 1) / 1023
+}
+
+// RampLimiter wraps an AdjustableLimiter, starting at a low rate and increasing towards the wrapped limiter's
+// original rate over a configurable duration. This is useful for warming caches, and for avoiding overwhelming
+// a cold downstream when a big transfer begins.
+//
+// The ramp begins on the first call to Wait, and the rate is adjusted lazily as Wait is called -- no background
+// goroutines are involved. The wrapped limiter's burst capacity is unaffected, so a limiter with a large burst
+// will still allow that burst at the start of the ramp.
+type RampLimiter struct {
+	lim      AdjustableLimiter
+	from, to int64
+	over     time.Duration
+	curve    RampCurve
+
+	start atomic.Pointer[time.Time]
+	done  atomic.Bool
+	last  atomic.Int64
+	w     waiter
+}
+
+// NewRampLimiter returns a limiter that ramps lim from the rate from, in bytes per second, up to lim's current rate
+// over the duration over, following curve. A nil curve is treated as LinearRamp.
+func NewRampLimiter(lim AdjustableLimiter, from int64, over time.Duration, curve RampCurve, opts ...Option) *RampLimiter {
+	if curve == nil {
+		curve = LinearRamp
+	}
+	r := &RampLimiter{
+		lim:   lim,
+		from:  from,
+		to:    lim.Limit(),
+		over:  over,
+		curve: curve,
+		w:     newWaiter(opts),
+	}
+	r.Restart()
+	return r
+}
+
+// Restart resets the rate to the start of the ramp. The ramp begins again on the next call to Wait.
+func (r *RampLimiter) Restart() {
+	r.start.Store(nil)
+	r.last.Store(r.from)
+	r.done.Store(false)
+	r.lim.SetLimit(r.from)
+}
+
+func (r *RampLimiter) Wait(ctx context.Context, n int) error {
+	if !r.done.Load() {
+		r.adjust()
+	}
+	return r.lim.Wait(ctx, n)
+}
+
+func (r *RampLimiter) adjust() {
+	now := r.w.now()
+	start := r.start.Load()
+	if start == nil {
+		r.start.CompareAndSwap(nil, &now)
+		start = r.start.Load()
+	}
+
+	progress := float64(now.Sub(*start)) / float64(r.over)
+	if progress >= 1 || r.over <= 0 {
+		r.done.Store(true)
+		r.set(r.to)
+		return
+	}
+
+	frac := min(max(r.curve(progress), 0), 1)
+	r.set(r.from + int64(frac*float64(r.to-r.from)))
+}
+
+// set avoids calling SetLimit when the rate hasn't changed, as that may require locking.
+func (r *RampLimiter) set(bytesPerSec int64) {
+	if r.last.Swap(bytesPerSec) != bytesPerSec {
+		r.lim.SetLimit(bytesPerSec)
+	}
+}
+
+var _ Limiter = (*RampLimiter)(nil)
@@ -0,0 +1,54 @@
+package throughput
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRampLimiter(t *testing.T) {
+	adj := &adjustableCountingLimiter{limit: 1000}
+	r := NewRampLimiter(adj, 100, 200*time.Millisecond, LinearRamp)
+
+	if got := adj.Limit(); got != 100 {
+		t.Fatalf("limit before ramp = %d, want 100", got)
+	}
+
+	_ = r.Wait(context.Background(), 1)
+	time.Sleep(100 * time.Millisecond)
+	_ = r.Wait(context.Background(), 1)
+
+	// Halfway through, allowing for scheduling slop
+	if got := adj.Limit(); got < 450 || got > 700 {
+		t.Errorf("limit midway through ramp = %d, want ~550", got)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	_ = r.Wait(context.Background(), 1)
+	if got := adj.Limit(); got != 1000 {
+		t.Errorf("limit after ramp = %d, want 1000", got)
+	}
+
+	r.Restart()
+	if got := adj.Limit(); got != 100 {
+		t.Errorf("limit after restart = %d, want 100", got)
+	}
+}
+
+func TestExponentialRamp(t *testing.T) {
+	if ExponentialRamp(0) != 0 || ExponentialRamp(1) != 1 {
+		t.Error("ExponentialRamp should map 0 to 0 and 1 to 1")
+	}
+	if ExponentialRamp(0.5) >= 0.5 {
+		t.Error("ExponentialRamp should start slowly")
+	}
+}
+
+// adjustableCountingLimiter is a countingLimiter with a settable (but unenforced) limit.
+type adjustableCountingLimiter struct {
+	countingLimiter
+	limit int64
+}
+
+func (a *adjustableCountingLimiter) Limit() int64               { return a.limit }
+func (a *adjustableCountingLimiter) SetLimit(bytesPerSec int64) { a.limit = bytesPerSec }
@@ -0,0 +1,77 @@
+package throughput
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// ByteRange is Len bytes starting at Off, e.g. a segment of a download.
+type ByteRange struct {
+	Off, Len int64
+}
+
+// ReadRanges reads ranges from src concurrently, writing each to the same offset of dst -- the core of a segmented
+// downloader. Each range is a stream of fair, so bandwidth is divided evenly between the ranges still in progress
+// and the aggregate rate is fair's limit, however src schedules reads. Ranges are read in small chunks, so they
+// interleave smoothly.
+//
+// The first error cancels the remaining ranges, and is returned. A range extending past the end of src fails with
+// io.ErrUnexpectedEOF.
+func ReadRanges(ctx context.Context, dst io.WriterAt, src io.ReaderAt, ranges []ByteRange, fair *FairShare) error {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	// Aim for around 10 reads per second per range.
+	size := min(max(fair.Limit()/int64(10*len(ranges)), 512), 32*1024)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		firstErr error
+	)
+	for _, r := range ranges {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := readRange(ctx, dst, src, r, fair.NewStream(1), size); err != nil {
+				failOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+func readRange(ctx context.Context, dst io.WriterAt, src io.ReaderAt, r ByteRange, s *FairStream, size int64) error {
+	defer s.Close()
+
+	buf := make([]byte, min(size, r.Len))
+	for off, end := r.Off, r.Off+r.Len; off < end; {
+		n, err := src.ReadAt(buf[:min(size, end-off)], off)
+		if n > 0 {
+			if _, err := dst.WriteAt(buf[:n], off); err != nil {
+				return err
+			}
+			if err := s.Wait(ctx, n); err != nil {
+				return err
+			}
+			off += int64(n)
+		}
+
+		switch {
+		case err == io.EOF && off < end:
+			return io.ErrUnexpectedEOF
+		case err != nil && err != io.EOF:
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package throughput
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadRanges(t *testing.T) {
+	data := make([]byte, 4000)
+	_, _ = rand.Read(data)
+	dst := &bufferAt{b: make([]byte, len(data))}
+
+	ranges := []ByteRange{{0, 1000}, {1000, 3000}}
+	start := time.Now()
+	err := ReadRanges(context.Background(), dst, bytes.NewReader(data), ranges, NewFairShare(4000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst.b, data) {
+		t.Error("ranges weren't written to their offsets")
+	}
+
+	// Once the short range finishes, the long range gets the whole rate
+	if elapsed := time.Since(start); elapsed < 800*time.Millisecond || elapsed > 1500*time.Millisecond {
+		t.Errorf("4000 bytes at 4000 bytes/sec took %s, want ~1s", elapsed)
+	}
+}
+
+func TestReadRangesPastEnd(t *testing.T) {
+	dst := &bufferAt{b: make([]byte, 2000)}
+	err := ReadRanges(context.Background(), dst, bytes.NewReader(make([]byte, 1000)),
+		[]ByteRange{{0, 500}, {500, 1000}}, NewFairShare(1<<30))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadRanges returned %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+// bufferAt is an io.WriterAt over a fixed-size buffer.
+type bufferAt struct {
+	mu sync.Mutex
+	b  []byte
+}
+
+func (w *bufferAt) WriteAt(p []byte, off int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return copy(w.b[off:], p), nil
+}
@@ -0,0 +1,135 @@
+// Package ratetest provides helpers for testing code which limits throughput, e.g. to assert that a reader
+// produced by throughput.NewReader is held to the expected rate.
+package ratetest
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Clock is a source of time. It is satisfied by throughput.Clock, including throughput.VirtualClock, which
+// allows rates to be asserted in virtual time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// AssertRate reads r to EOF, and reports an error via t if the rate achieved, in bytes per second, differs from
+// want by more than tolerance (e.g. 0.05 for ±5%). Rates are measured in wall time. The achieved rate is returned.
+//
+// The rate is the bytes read divided by the time taken, so a limiter's initial burst allowance inflates it.
+// Read enough data for the burst to be insignificant, or deplete the limiter first.
+func AssertRate(t testing.TB, r io.Reader, want int64, tolerance float64) int64 {
+	t.Helper()
+
+	sink := NewSink(nil)
+	if _, err := io.Copy(sink, r); err != nil {
+		t.Fatalf("reading: %s", err)
+	}
+	return sink.AssertRate(t, want, tolerance)
+}
+
+// AssertDuration reports an error via t if got differs from want by more than slop.
+func AssertDuration(t testing.TB, got, want, slop time.Duration) {
+	t.Helper()
+	if got > want+slop || got < want-slop {
+		t.Errorf("took %s, want %s (±%s)", got, want, slop)
+	}
+}
+
+// Source returns a reader which yields n zero bytes as fast as they are read, so that any limit on the rate
+// comes from the code under test.
+func Source(n int64) io.Reader {
+	return io.LimitReader(zeroReader{}, n)
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// Sink is an io.Writer which discards what is written to it, measuring the rate at which bytes arrive.
+// It is safe for concurrent use, so may be shared by several writers to measure their aggregate rate.
+type Sink struct {
+	clock Clock
+	start time.Time
+
+	mu     sync.Mutex
+	bytes  int64
+	writes int64
+	last   time.Time
+}
+
+// NewSink returns a sink measuring from now, according to clock. A nil clock means wall time.
+func NewSink(clock Clock) *Sink {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &Sink{clock: clock, start: clock.Now()}
+}
+
+func (s *Sink) Write(p []byte) (int, error) {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bytes += int64(len(p))
+	s.writes++
+	if now.After(s.last) {
+		s.last = now
+	}
+	return len(p), nil
+}
+
+// Bytes returns the number of bytes written.
+func (s *Sink) Bytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bytes
+}
+
+// Writes returns the number of calls to Write.
+func (s *Sink) Writes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writes
+}
+
+// Elapsed returns the time from the sink's creation to the last write.
+func (s *Sink) Elapsed() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return max(s.last.Sub(s.start), 0)
+}
+
+// Rate returns the average rate bytes were written at, in bytes per second, from the sink's creation to the
+// last write. It is zero if no time has elapsed.
+func (s *Sink) Rate() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elapsed := s.last.Sub(s.start)
+	if elapsed <= 0 {
+		return 0
+	}
+	return int64(float64(s.bytes) / elapsed.Seconds())
+}
+
+// AssertRate reports an error via t if the sink's rate differs from want by more than tolerance (e.g. 0.05 for
+// ±5%). The rate is returned.
+func (s *Sink) AssertRate(t testing.TB, want int64, tolerance float64) int64 {
+	t.Helper()
+	got := s.Rate()
+	if diff := float64(got - want); diff > tolerance*float64(want) || -diff > tolerance*float64(want) {
+		t.Errorf("rate %d bytes/sec over %s, want %d bytes/sec (±%.0f%%)", got, s.Elapsed(), want, tolerance*100)
+	}
+	return got
+}
@@ -0,0 +1,62 @@
+package ratetest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/iamcalledrob/throughput"
+)
+
+func TestAssertRate(t *testing.T) {
+	lim := throughput.NewTokenBucket(64*1024, 1024)
+	r := throughput.NewReader(context.Background(), Source(64*1024), lim)
+	AssertRate(t, r, 64*1024, 0.1)
+}
+
+func TestSinkVirtualTime(t *testing.T) {
+	clock := throughput.NewVirtualClock(time.Unix(0, 0))
+	lim := throughput.NewTokenBucket(1024, 0, throughput.WithClock(clock))
+
+	sink := NewSink(clock)
+	w := throughput.NewWriter(context.Background(), sink, lim)
+	if _, err := io.CopyBuffer(w, Source(60*1024), make([]byte, 512)); err != nil {
+		t.Fatalf("copy: %s", err)
+	}
+
+	// Writer charges for each write after making it, so the last arrives half a second before the copy completes.
+	AssertDuration(t, sink.Elapsed(), time.Minute-500*time.Millisecond, 0)
+	sink.AssertRate(t, 1024, 0.01)
+	if sink.Bytes() != 60*1024 || sink.Writes() != 120 {
+		t.Errorf("sink got %d bytes in %d writes, want 61440 in 120", sink.Bytes(), sink.Writes())
+	}
+}
+
+func TestSinkAssertRateFails(t *testing.T) {
+	clock := throughput.NewVirtualClock(time.Unix(0, 0))
+	sink := NewSink(clock)
+	clock.Advance(time.Second)
+	_, _ = sink.Write(make([]byte, 2000))
+
+	var rec recorder
+	if got := sink.AssertRate(&rec, 1000, 0.5); got != 2000 {
+		t.Errorf("AssertRate returned %d, want 2000", got)
+	}
+	if len(rec.errors) != 1 {
+		t.Errorf("2000 bytes/sec against 1000±50%% reported %d errors, want 1", len(rec.errors))
+	}
+}
+
+// recorder is a testing.TB which records errors rather than failing.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
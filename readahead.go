@@ -0,0 +1,103 @@
+package throughput
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// ReadAhead reads ahead from a source into a bounded buffer as fast as the source allows, whilst its Read returns
+// data at the limited rate. This decouples upstream latency from downstream pacing: a bursty or stalling source
+// doesn't disturb the output, so long as the buffer doesn't run dry -- as for media-style streaming.
+type ReadAhead struct {
+	*Reader
+	buf *readAheadBuffer
+}
+
+// NewReadAhead returns a ReadAhead buffering up to around size bytes from src, and rate-limited by lim.
+// The context is used to unblock calls to Read, whether rate-limited or waiting for data.
+// The ReadAhead should be closed once it is no longer needed, to stop reading ahead.
+func NewReadAhead(ctx context.Context, src io.Reader, lim Limiter, size int, opts ...StreamOption) *ReadAhead {
+	chunk := min(max(size, 1), 32*1024)
+	buf := &readAheadBuffer{
+		ctx:    ctx,
+		chunk:  chunk,
+		chunks: make(chan []byte, max(size/chunk, 1)),
+		done:   make(chan struct{}),
+	}
+	go buf.fill(src)
+
+	return &ReadAhead{Reader: NewReader(ctx, buf, lim, opts...), buf: buf}
+}
+
+// Buffered returns the number of bytes read ahead, and not yet returned by Read.
+func (r *ReadAhead) Buffered() int {
+	return int(r.buf.buffered.Load())
+}
+
+// Close stops reading ahead, and unblocks any Read. The source isn't closed, and a read from it already in
+// progress isn't interrupted.
+func (r *ReadAhead) Close() error {
+	r.buf.closeOnce()
+	return r.Reader.Close()
+}
+
+// readAheadBuffer is the unthrottled side of a ReadAhead.
+type readAheadBuffer struct {
+	ctx      context.Context
+	chunk    int
+	chunks   chan []byte // closed once the source returns an error
+	err      error       // the source's error, set before chunks is closed
+	pending  []byte      // the rest of a chunk partially returned by Read
+	buffered atomic.Int64
+	done     chan struct{}
+	closed   atomic.Bool
+}
+
+// fill reads from src into chunks until an error or Close.
+func (b *readAheadBuffer) fill(src io.Reader) {
+	for {
+		p := make([]byte, b.chunk)
+		n, err := src.Read(p)
+		if n > 0 {
+			b.buffered.Add(int64(n))
+			select {
+			case b.chunks <- p[:n]:
+			case <-b.done:
+				return
+			}
+		}
+		if err != nil {
+			b.err = err
+			close(b.chunks)
+			return
+		}
+	}
+}
+
+func (b *readAheadBuffer) Read(p []byte) (int, error) {
+	if len(b.pending) == 0 {
+		select {
+		case c, ok := <-b.chunks:
+			if !ok {
+				return 0, b.err
+			}
+			b.pending = c
+		case <-b.ctx.Done():
+			return 0, b.ctx.Err()
+		case <-b.done:
+			return 0, ErrClosed
+		}
+	}
+
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	b.buffered.Add(-int64(n))
+	return n, nil
+}
+
+func (b *readAheadBuffer) closeOnce() {
+	if !b.closed.Swap(true) {
+		close(b.done)
+	}
+}
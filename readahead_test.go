@@ -0,0 +1,48 @@
+package throughput
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestReadAhead(t *testing.T) {
+	data := make([]byte, 10_000)
+	_, _ = rand.Read(data)
+
+	clock := NewVirtualClock(time.Unix(0, 0))
+	r := NewReadAhead(context.Background(), bytes.NewReader(data), NewTokenBucket(1000, 0, WithClock(clock)), 4096)
+	defer r.Close()
+
+	// The source is read ahead before anything is consumed, up to around the buffer size
+	deadline := time.Now().Add(time.Second)
+	for r.Buffered() < 4096 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := r.Buffered(); n < 4096 || n > 2*4096 {
+		t.Errorf("%d bytes buffered, want around 4096", n)
+	}
+
+	// Output is paced at the limited rate
+	got, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("ReadAll returned %d bytes, %v", len(got), err)
+	}
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 10*time.Second {
+		t.Errorf("10000 bytes at 1000 bytes/sec took %s, want 10s", elapsed)
+	}
+}
+
+func TestReadAheadClose(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	r := NewReadAhead(context.Background(), pr, NewTokenBucket(1<<40, 1<<40), 1024)
+
+	time.AfterFunc(10*time.Millisecond, func() { _ = r.Close() })
+	if _, err := r.Read(make([]byte, 10)); err == nil {
+		t.Error("Read returned no error after Close")
+	}
+}
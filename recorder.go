@@ -0,0 +1,127 @@
+package throughput
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Sample is a source's rate over the interval before Time, as recorded by a Recorder.
+type Sample struct {
+	Time  time.Time `json:"time"`
+	Name  string    `json:"name"`
+	Rate  float64   `json:"rate"`  // bytes per second since the previous sample
+	Bytes int64     `json:"bytes"` // total bytes as of Time
+}
+
+// Recorder samples the rates of streams or limiters at an interval into a bounded history, which can be exported
+// as CSV or JSON -- so long transfers can be graphed after the fact without a metrics stack.
+type Recorder struct {
+	w waiter
+
+	mu      sync.Mutex
+	sources []*recorderSource
+	samples []Sample // a ring buffer of at most cap(samples)
+	next    int      // where the next sample goes, once samples is full
+}
+
+type recorderSource struct {
+	name  string
+	total func() int64
+	bytes int64
+	at    time.Time
+}
+
+// NewRecorder returns a recorder keeping the most recent size samples. Only the WithClock option is used.
+func NewRecorder(size int, opts ...Option) *Recorder {
+	return &Recorder{w: newWaiter(opts), samples: make([]Sample, 0, size)}
+}
+
+// Add records the rate of a source, given a func returning its total bytes so far, such as Meter.Total or
+// HierarchyChild.Bytes. Rates are measured from the time of Add.
+func (r *Recorder) Add(name string, total func() int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources = append(r.sources, &recorderSource{name: name, total: total, bytes: total(), at: r.w.now()})
+}
+
+// sourceList returns the sources added so far.
+func (r *Recorder) sourceList() []*recorderSource {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*recorderSource(nil), r.sources...)
+}
+
+// Run calls Record every interval until ctx is done, returning ctx's error.
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			r.Record()
+		}
+	}
+}
+
+// Record takes a sample of every source now, discarding the oldest samples if the history is full.
+func (r *Recorder) Record() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.w.now()
+	for _, src := range r.sources {
+		bytes := src.total()
+		s := Sample{Time: now, Name: src.name, Bytes: bytes}
+		if elapsed := now.Sub(src.at); elapsed > 0 {
+			s.Rate = float64(bytes-src.bytes) / elapsed.Seconds()
+		}
+		src.bytes, src.at = bytes, now
+
+		if len(r.samples) < cap(r.samples) {
+			r.samples = append(r.samples, s)
+		} else if cap(r.samples) > 0 {
+			r.samples[r.next] = s
+			r.next = (r.next + 1) % cap(r.samples)
+		}
+	}
+}
+
+// Samples returns the recorded history, oldest first.
+func (r *Recorder) Samples() []Sample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append(append([]Sample(nil), r.samples[r.next:]...), r.samples[:r.next]...)
+}
+
+// WriteCSV writes the history to w as CSV, with a header row of time, name, rate and bytes. Times are RFC 3339.
+func (r *Recorder) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"time", "name", "rate", "bytes"})
+	for _, s := range r.Samples() {
+		_ = cw.Write([]string{
+			s.Time.Format(time.RFC3339Nano),
+			s.Name,
+			strconv.FormatFloat(s.Rate, 'f', -1, 64),
+			strconv.FormatInt(s.Bytes, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the history to w as a JSON array of samples.
+func (r *Recorder) WriteJSON(w io.Writer) error {
+	samples := r.Samples()
+	if samples == nil {
+		samples = []Sample{}
+	}
+	return json.NewEncoder(w).Encode(samples)
+}
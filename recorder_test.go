@@ -0,0 +1,54 @@
+package throughput
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	clock := NewVirtualClock(time.Unix(0, 0))
+	r := NewRecorder(3, WithClock(clock))
+	m := NewMeter(time.Second, WithClock(clock))
+	r.Add("upload", m.Total)
+
+	for _, n := range []int64{1000, 2000, 3000, 4000} {
+		m.Add(n)
+		clock.Advance(time.Second)
+		r.Record()
+	}
+
+	// The oldest sample is discarded
+	samples := r.Samples()
+	if len(samples) != 3 {
+		t.Fatalf("got %d samples, want 3", len(samples))
+	}
+	for i, want := range []float64{2000, 3000, 4000} {
+		if s := samples[i]; s.Name != "upload" || s.Rate != want || !s.Time.Equal(time.Unix(int64(i+2), 0)) {
+			t.Errorf("sample %d is %+v, want rate %.0f", i, s, want)
+		}
+	}
+	if samples[2].Bytes != 10_000 {
+		t.Errorf("last sample has %d bytes, want 10000", samples[2].Bytes)
+	}
+
+	var csv bytes.Buffer
+	if err := r.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 4 || lines[0] != "time,name,rate,bytes" || !strings.HasSuffix(lines[3], ",upload,4000,10000") {
+		t.Errorf("unexpected CSV:\n%s", csv.String())
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Sample
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 3 || decoded[0].Rate != 2000 {
+		t.Errorf("unexpected JSON %s (%v)", buf.String(), err)
+	}
+}
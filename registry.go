@@ -0,0 +1,107 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Registry holds a limiter per key -- e.g. per user, per API key or per client IP -- creating them on demand.
+//
+// Limiters which haven't been waited on for the idle duration are forgotten, so a registry keyed on an unbounded
+// set of clients doesn't grow forever. A limiter which is still in use after being forgotten continues to work,
+// but it is no longer shared with new callers for the same key.
+type Registry[K comparable] struct {
+	newLimiter func(key K) Limiter
+	idle       time.Duration
+
+	mu      sync.Mutex
+	entries map[K]*registryEntry
+	swept   time.Time
+}
+
+// NewRegistry returns a registry which calls newLimiter to create the limiter for a key, and forgets limiters
+// which have been idle for longer than idle. If idle is zero, limiters are never forgotten.
+func NewRegistry[K comparable](newLimiter func(key K) Limiter, idle time.Duration) *Registry[K] {
+	return &Registry[K]{
+		newLimiter: newLimiter,
+		idle:       idle,
+		entries:    make(map[K]*registryEntry),
+	}
+}
+
+// Get returns the limiter for key, creating it if necessary. The limiter returned wraps the one created by
+// newLimiter to track its use; its Unwrap method returns the created limiter, e.g. to reach methods other than
+// Wait.
+func (r *Registry[K]) Get(key K) Limiter {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sweep(now)
+
+	e, ok := r.entries[key]
+	if !ok {
+		e = &registryEntry{Limiter: r.newLimiter(key)}
+		r.entries[key] = e
+	}
+	e.lastUsed.Store(now.UnixNano())
+	return e
+}
+
+// Delete forgets the limiter for key.
+func (r *Registry[K]) Delete(key K) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, key)
+}
+
+// Len returns the number of limiters held.
+func (r *Registry[K]) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sweep(time.Now())
+	return len(r.entries)
+}
+
+// sweep forgets idle limiters, at most once per idle duration. Must be called with mu held.
+func (r *Registry[K]) sweep(now time.Time) {
+	if r.idle <= 0 || now.Sub(r.swept) < r.idle {
+		return
+	}
+	r.swept = now
+
+	cutoff := now.Add(-r.idle).UnixNano()
+	for key, e := range r.entries {
+		if e.lastUsed.Load() < cutoff {
+			delete(r.entries, key)
+		}
+	}
+}
+
+type registryEntry struct {
+	Limiter
+	lastUsed atomic.Int64 // unix nanoseconds
+}
+
+func (e *registryEntry) Wait(ctx context.Context, n int) error {
+	e.lastUsed.Store(time.Now().UnixNano())
+	return e.Limiter.Wait(ctx, n)
+}
+
+// Unwrap returns the limiter created for the entry's key.
+func (e *registryEntry) Unwrap() Limiter {
+	return e.Limiter
+}
+
+// Refund refunds the registered limiter, if it implements Refunder.
+func (e *registryEntry) Refund(n int) {
+	if r, ok := e.Limiter.(Refunder); ok {
+		r.Refund(n)
+	}
+}
+
+func (e *registryEntry) refunds() bool {
+	return refunds(e.Limiter)
+}
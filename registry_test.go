@@ -0,0 +1,44 @@
+package throughput
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	var created []string
+	r := NewRegistry(func(key string) Limiter {
+		created = append(created, key)
+		return &countingLimiter{}
+	}, 50*time.Millisecond)
+
+	a := r.Get("a")
+	if r.Get("a") != a {
+		t.Error("Get returned a different limiter for the same key")
+	}
+	if _, ok := a.(interface{ Unwrap() Limiter }).Unwrap().(*countingLimiter); !ok {
+		t.Error("Unwrap didn't return the created limiter")
+	}
+	_ = r.Get("b")
+	if got := r.Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2", got)
+	}
+
+	// "a" stays in use, whilst "b" goes idle
+	for range 4 {
+		time.Sleep(20 * time.Millisecond)
+		_ = a.Wait(context.Background(), 1)
+	}
+	if got := r.Len(); got != 1 {
+		t.Errorf("Len() = %d after idle period, want 1", got)
+	}
+	if r.Get("a") != a {
+		t.Error("active limiter was forgotten")
+	}
+
+	_ = r.Get("b")
+	if len(created) != 3 {
+		t.Errorf("created limiters for %v, want a, b, b", created)
+	}
+}
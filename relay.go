@@ -0,0 +1,70 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// RelayStats reports the bytes copied by Relay.
+type RelayStats struct {
+	Up   int64 // bytes copied from a to b
+	Down int64 // bytes copied from b to a
+}
+
+// Relay copies between a and b in both directions until both are done -- the core of a shaping proxy. Bytes
+// from a to b are charged to upLim, and from b to a to downLim, which may be the same limiter.
+//
+// Once one side finishes sending, the other's writing side is closed if it supports half-close, as *net.TCPConn
+// does, so the end of the stream is propagated while the other direction carries on. A conn which can't be
+// half-closed is closed altogether. Relay closes both conns before returning, and when ctx is done.
+//
+// The error returned is the first which didn't come from the conns being closed.
+func Relay(ctx context.Context, a, b net.Conn, upLim, downLim Limiter) (RelayStats, error) {
+	var closeOnce sync.Once
+	closeBoth := func() {
+		closeOnce.Do(func() {
+			_ = a.Close()
+			_ = b.Close()
+		})
+	}
+	defer closeBoth()
+	stop := context.AfterFunc(ctx, closeBoth)
+	defer stop()
+
+	var (
+		stats    RelayStats
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		firstErr error
+	)
+	relay := func(dst, src net.Conn, lim Limiter, n *int64) {
+		defer wg.Done()
+
+		var err error
+		*n, err = Copy(ctx, dst, src, lim)
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			if ctx.Err() == nil {
+				failOnce.Do(func() { firstErr = err })
+			}
+			// The other direction can't carry on
+			closeBoth()
+			return
+		}
+
+		if hc, ok := dst.(interface{ CloseWrite() error }); !ok || hc.CloseWrite() != nil {
+			_ = dst.Close()
+		}
+	}
+
+	wg.Add(2)
+	go relay(b, a, upLim, &stats.Up)
+	go relay(a, b, downLim, &stats.Down)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = context.Cause(ctx)
+	}
+	return stats, firstErr
+}
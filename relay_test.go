@@ -0,0 +1,91 @@
+package throughput
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestRelay(t *testing.T) {
+	client, a := tcpPair(t)
+	b, server := tcpPair(t)
+	defer client.Close()
+	defer server.Close()
+
+	type result struct {
+		stats RelayStats
+		err   error
+	}
+	done := make(chan result)
+	var up, down countingLimiter
+	go func() {
+		stats, err := Relay(context.Background(), a, b, &up, &down)
+		done <- result{stats, err}
+	}()
+
+	_, _ = client.Write([]byte("hello"))
+	_ = client.(*net.TCPConn).CloseWrite()
+
+	// The client's half-close reaches the server, which can still reply
+	if got, _ := io.ReadAll(server); string(got) != "hello" {
+		t.Errorf("server received %q, want hello", got)
+	}
+	_, _ = server.Write([]byte("world!"))
+	_ = server.Close()
+	if got, _ := io.ReadAll(client); string(got) != "world!" {
+		t.Errorf("client received %q, want world!", got)
+	}
+
+	r := <-done
+	if r.err != nil || r.stats != (RelayStats{Up: 5, Down: 6}) {
+		t.Errorf("Relay returned %+v, %v, want 5 up, 6 down", r.stats, r.err)
+	}
+	if up.n.Load() != 5 || down.n.Load() != 6 {
+		t.Errorf("limiters charged %d up, %d down, want 5, 6", up.n.Load(), down.n.Load())
+	}
+}
+
+func TestRelayCancel(t *testing.T) {
+	client, a := tcpPair(t)
+	b, server := tcpPair(t)
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := Relay(ctx, a, b, &countingLimiter{}, &countingLimiter{})
+		done <- err
+	}()
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Relay returned %v, want context.Canceled", err)
+	}
+}
+
+// tcpPair returns both ends of a loopback TCP connection.
+func tcpPair(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn)
+	go func() {
+		conn, _ := ln.Accept()
+		accepted <- conn
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer := <-accepted
+	if peer == nil {
+		t.Fatal("accept failed")
+	}
+	return conn, peer
+}
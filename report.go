@@ -0,0 +1,89 @@
+package throughput
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ProgressReport is a snapshot of a transfer's progress, passed to the callback of Report.
+type ProgressReport struct {
+	Bytes     int64
+	Total     int64 // zero or less if unknown
+	Rate      float64
+	Elapsed   time.Duration
+	Remaining time.Duration
+	HasETA    bool // whether Remaining is known
+	Final     bool // whether this is the last report
+}
+
+// String formats the report like pv, e.g. "34% 12.3 MiB/s ETA 00:41", or "1.2 GiB 12.3 MiB/s" if the size of the
+// transfer is unknown.
+func (p ProgressReport) String() string {
+	if p.Total <= 0 {
+		return FormatBytes(float64(p.Bytes)) + " " + FormatRate(p.Rate)
+	}
+
+	eta := "--:--"
+	if p.HasETA {
+		eta = formatClock(p.Remaining)
+	}
+	percent := min(100*p.Bytes/p.Total, 100)
+	return fmt.Sprintf("%d%% %s ETA %s", percent, FormatRate(p.Rate), eta)
+}
+
+// formatClock formats d as mm:ss, or h:mm:ss from an hour.
+func formatClock(d time.Duration) string {
+	s := int64(d.Round(time.Second) / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%02d:%02d", s/60, s%60)
+}
+
+// Report calls f with the progress eta has measured every interval until ctx is done, and then once more with
+// Final set -- so CLI tools get pv-style output without assembling it themselves. Cancel ctx once the transfer
+// is complete. f may be ReportTo, e.g.
+//
+//	eta := throughput.NewETA(size, 5*time.Second)
+//	go throughput.Report(ctx, eta, time.Second, throughput.ReportTo(os.Stderr))
+//	_, err := throughput.Copy(ctx, dst, src, throughput.Chain(lim, eta))
+func Report(ctx context.Context, eta *ETA, interval time.Duration, f func(ProgressReport)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			f(progressReport(eta, true))
+			return
+		case <-t.C:
+			f(progressReport(eta, false))
+		}
+	}
+}
+
+func progressReport(eta *ETA, final bool) ProgressReport {
+	p := ProgressReport{
+		Bytes:   eta.Bytes(),
+		Total:   eta.Total(),
+		Rate:    eta.Rate(),
+		Elapsed: eta.Elapsed(),
+		Final:   final,
+	}
+	p.Remaining, p.HasETA = eta.Remaining()
+	return p
+}
+
+// ReportTo returns a callback for Report which writes each report to a terminal w, overwriting the previous one.
+// The final report ends the line.
+func ReportTo(w io.Writer) func(ProgressReport) {
+	return func(p ProgressReport) {
+		end := ""
+		if p.Final {
+			end = "\n"
+		}
+		fmt.Fprintf(w, "\r%s\x1b[K%s", p, end)
+	}
+}
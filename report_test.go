@@ -0,0 +1,44 @@
+package throughput
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressReportString(t *testing.T) {
+	tests := []struct {
+		p    ProgressReport
+		want string
+	}{
+		{ProgressReport{Bytes: 34, Total: 100, Rate: 12.3 * 1024 * 1024, Remaining: 41 * time.Second, HasETA: true},
+			"34% 12.3 MiB/s ETA 00:41"},
+		{ProgressReport{Bytes: 1, Total: 100, Rate: 10, Remaining: 2*time.Hour + 5*time.Second, HasETA: true},
+			"1% 10 B/s ETA 2:00:05"},
+		{ProgressReport{Bytes: 0, Total: 100}, "0% 0 B/s ETA --:--"},
+		{ProgressReport{Bytes: 1536, Rate: 2048}, "1.5 KiB 2.0 KiB/s"},
+	}
+	for _, tt := range tests {
+		if got := tt.p.String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	eta := NewETA(1000, time.Second)
+	eta.Add(500)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var buf bytes.Buffer
+	Report(ctx, eta, 10*time.Millisecond, ReportTo(&buf))
+
+	// Reports overwrite each other, and the last ends the line
+	out := buf.String()
+	if strings.Count(out, "\r") < 2 || !strings.HasSuffix(out, "\n") || !strings.Contains(out, "50% ") {
+		t.Errorf("unexpected output %q", out)
+	}
+}
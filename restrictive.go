@@ -0,0 +1,59 @@
+package throughput
+
+import (
+	"context"
+	"time"
+)
+
+// Reserver is implemented by limiters that can charge bytes without waiting, returning the delay instead.
+// This allows several limiters to be charged at once and then waited on together.
+type Reserver interface {
+	Limiter
+
+	// Reserve charges n bytes, and returns how long the caller must wait before proceeding.
+	// If the caller gives up rather than waiting, it should call cancel to return the bytes where possible.
+	Reserve(n int) (delay time.Duration, cancel func())
+}
+
+// MostRestrictiveLimiter layers several limiters -- e.g. network-level and application-level policies -- and
+// enforces whichever is effectively the slowest for each Wait.
+//
+// Every limiter is charged for every byte, so each one's budget accurately reflects all traffic through it, even
+// when another limiter is the one currently causing the delay. The caller then waits once, for the longest of the
+// delays. If the wait is cancelled, every reservation is cancelled.
+//
+// Compared to AllLimiter, which works with any Limiter, this avoids spawning goroutines on each Wait.
+type MostRestrictiveLimiter struct {
+	lims []Reserver
+	w    waiter
+}
+
+// NewMostRestrictiveLimiter returns a limiter which charges all of lims, and waits for the slowest.
+func NewMostRestrictiveLimiter(lims []Reserver, opts ...Option) *MostRestrictiveLimiter {
+	return &MostRestrictiveLimiter{lims: lims, w: newWaiter(opts)}
+}
+
+func (m *MostRestrictiveLimiter) Wait(ctx context.Context, n int) error {
+	if len(m.lims) == 1 {
+		return m.lims[0].Wait(ctx, n)
+	}
+
+	var delay time.Duration
+	cancels := make([]func(), len(m.lims))
+	for i, lim := range m.lims {
+		var d time.Duration
+		d, cancels[i] = lim.Reserve(n)
+		delay = max(delay, d)
+	}
+
+	err := m.w.sleep(ctx, delay)
+	if err != nil {
+		for _, cancel := range cancels {
+			cancel()
+		}
+		return err
+	}
+	return nil
+}
+
+var _ Limiter = (*MostRestrictiveLimiter)(nil)
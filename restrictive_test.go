@@ -0,0 +1,53 @@
+package throughput
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMostRestrictiveLimiter(t *testing.T) {
+	fast := NewTokenBucket(100*1024, 0)
+	slow := NewRateLimiterAdapter(NewBytesPerSecLimiter(1024))
+	slow.lim.AllowN(time.Now(), 1024)
+
+	lim := NewMostRestrictiveLimiter([]Reserver{fast, slow, NewPacer(10 * 1024)})
+
+	start := time.Now()
+	_ = lim.Wait(context.Background(), 100)
+	err := verifyWithSlop(time.Since(start), 100*time.Second/1024, 20*time.Millisecond)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	// Every limiter is charged, not just the slowest
+	if tokens := fast.Tokens(); tokens > 0 {
+		t.Errorf("fast limiter has %.0f tokens, expected to have been charged", tokens)
+	}
+}
+
+func TestMostRestrictiveLimiterCancel(t *testing.T) {
+	a := NewTokenBucket(1024, 1024)
+	b := NewTokenBucket(10, 0)
+	lim := NewMostRestrictiveLimiter([]Reserver{a, b})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := lim.Wait(ctx, 512); err == nil {
+		t.Fatal("expected wait to be cancelled")
+	}
+	if tokens := a.Tokens(); tokens < 1024 {
+		t.Errorf("tokens = %.0f, want 1024 after cancellation", tokens)
+	}
+}
+
+func TestRateLimiterAdapterReserveExceedingBurst(t *testing.T) {
+	lim := NewRateLimiterAdapter(NewBytesPerSecLimiter(1024))
+
+	// 1KB of burst, then 2KB at 1KB/sec
+	delay, _ := lim.Reserve(3 * 1024)
+	err := verifyWithSlop(delay, 2*time.Second, 10*time.Millisecond)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
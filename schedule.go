@@ -0,0 +1,189 @@
+package throughput
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScheduleRule pairs a set of points in time with the limiter to use during them.
+// Rules are created with WindowRule or CronRule, and evaluated by a limiter from NewScheduledLimiter.
+type ScheduleRule struct {
+	match func(t time.Time) bool
+	lim   Limiter
+}
+
+// NewScheduledLimiter returns a limiter that delegates to the limiter of the first rule matching the current time,
+// or to fallback if no rules match. Rules are evaluated in loc, e.g. time.Local, at the time given by the
+// clock set with WithClock.
+//
+// This allows weekly maintenance windows, weekend policies and the like to be expressed directly, e.g.
+//
+//	weekend := WindowRule(0, 24*time.Hour, fast, time.Saturday, time.Sunday)
+//	nightly, _ := CronRule("* 0-5 * * mon-fri", fast)
+//	lim := NewScheduledLimiter(time.Local, []ScheduleRule{weekend, nightly}, slow)
+func NewScheduledLimiter(loc *time.Location, rules []ScheduleRule, fallback Limiter, opts ...Option) *SwitchableLimiter {
+	lims := make([]Limiter, 0, len(rules)+1)
+	for _, r := range rules {
+		lims = append(lims, r.lim)
+	}
+	lims = append(lims, fallback)
+	w := newWaiter(opts)
+
+	return NewSwitchableLimiter(func() int {
+		now := w.now().In(loc)
+		for i, r := range rules {
+			if r.match(now) {
+				return i
+			}
+		}
+		return len(rules)
+	}, lims...)
+}
+
+// WindowRule matches the daily window [from, to), where from and to are offsets from midnight.
+// If to is before from, the window wraps past midnight (e.g. 22h to 6h). If days are provided,
+// the window only opens on those days -- a wrapping window belongs to the day it opens on.
+func WindowRule(from, to time.Duration, lim Limiter, days ...time.Weekday) ScheduleRule {
+	var dayMask uint8
+	for _, d := range days {
+		dayMask |= 1 << d
+	}
+	onDay := func(d time.Weekday) bool {
+		return dayMask == 0 || dayMask&(1<<d) != 0
+	}
+
+	return ScheduleRule{
+		lim: lim,
+		match: func(t time.Time) bool {
+			y, m, d := t.Date()
+			offset := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+
+			if from <= to {
+				return onDay(t.Weekday()) && offset >= from && offset < to
+			}
+			// Wrapping window: either the evening part opened today, or the morning part opened yesterday.
+			if offset >= from {
+				return onDay(t.Weekday())
+			}
+			return offset < to && onDay((t.Weekday()+6)%7)
+		},
+	}
+}
+
+// CronRule matches times described by a standard 5-field cron expression: minute, hour, day of month,
+// month and day of week. Each field accepts "*", values, ranges ("1-5"), steps ("*/15", "0-30/5") and
+// comma-separated lists. Months and days of week also accept three-letter names ("jan", "mon-fri"), and
+// Sunday may be written as 0 or 7.
+//
+// Unlike cron, which fires at matching minutes, the rule is active for the whole of each matching minute.
+// As in cron, when both day of month and day of week are restricted, a time matching either is accepted.
+func CronRule(expr string, lim Limiter) (ScheduleRule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return ScheduleRule{}, fmt.Errorf("cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	var masks [5]uint64
+	for i, spec := range cronFields {
+		mask, err := parseCronField(fields[i], spec)
+		if err != nil {
+			return ScheduleRule{}, fmt.Errorf("cron expression %q: %s field: %w", expr, spec.name, err)
+		}
+		masks[i] = mask
+	}
+
+	// Sunday is both 0 and 7
+	if masks[4]&(1<<7) != 0 {
+		masks[4] |= 1
+	}
+
+	domRestricted := fields[2] != "*"
+	dowRestricted := fields[4] != "*"
+
+	return ScheduleRule{
+		lim: lim,
+		match: func(t time.Time) bool {
+			if masks[0]&(1<<t.Minute()) == 0 || masks[1]&(1<<t.Hour()) == 0 || masks[3]&(1<<t.Month()) == 0 {
+				return false
+			}
+			domMatch := masks[2]&(1<<t.Day()) != 0
+			dowMatch := masks[4]&(1<<t.Weekday()) != 0
+			if domRestricted && dowRestricted {
+				return domMatch || dowMatch
+			}
+			return domMatch && dowMatch
+		},
+	}, nil
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // names[i] is an alias for min+i
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{
+		"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec",
+	}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+func parseCronField(s string, f cronField) (mask uint64, err error) {
+	for _, part := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			lo, err = f.value(loStr)
+			if err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				hi, err = f.value(hiStr)
+				if err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// "5/15" means from 5 to the end of the range, every 15.
+				hi = f.max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << v
+		}
+	}
+	return mask, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q, expected %d-%d", s, f.min, f.max)
+	}
+	return v, nil
+}
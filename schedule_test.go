@@ -0,0 +1,70 @@
+package throughput
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronRule(t *testing.T) {
+	// 2024-06-01 is a Saturday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.June, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{"* * * * *", at(1, 12, 30), true},
+		{"*/15 * * * *", at(1, 12, 30), true},
+		{"*/15 * * * *", at(1, 12, 31), false},
+		{"0-5,30 2 * * *", at(1, 2, 30), true},
+		{"0-5,30 2 * * *", at(1, 3, 30), false},
+		{"* 0-5 * * mon-fri", at(3, 4, 0), true},
+		{"* 0-5 * * mon-fri", at(1, 4, 0), false},
+		{"* * * * sat,7", at(2, 4, 0), true},
+		{"* * * jun *", at(1, 0, 0), true},
+		{"* * * jan-may *", at(1, 0, 0), false},
+		// dom and dow both restricted: either matches
+		{"* * 15 * sat", at(1, 0, 0), true},
+		{"* * 15 * sun", at(1, 0, 0), false},
+	}
+
+	for _, tt := range tests {
+		rule, err := CronRule(tt.expr, nil)
+		if err != nil {
+			t.Fatalf("CronRule(%q): %s", tt.expr, err)
+		}
+		if got := rule.match(tt.t); got != tt.want {
+			t.Errorf("CronRule(%q).match(%s) = %v, want %v", tt.expr, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestCronRuleInvalid(t *testing.T) {
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * * * fri-mon", "*/0 * * * *", "x * * * *"} {
+		if _, err := CronRule(expr, nil); err == nil {
+			t.Errorf("CronRule(%q): expected error", expr)
+		}
+	}
+}
+
+func TestWindowRule(t *testing.T) {
+	overnight := WindowRule(22*time.Hour, 6*time.Hour, nil, time.Friday)
+
+	tests := []struct {
+		t    time.Time
+		want bool
+	}{
+		{time.Date(2024, time.May, 31, 23, 0, 0, 0, time.UTC), true},  // Friday night
+		{time.Date(2024, time.June, 1, 5, 59, 0, 0, time.UTC), true},  // Saturday morning, opened Friday
+		{time.Date(2024, time.June, 1, 6, 0, 0, 0, time.UTC), false},  // Closed
+		{time.Date(2024, time.June, 1, 23, 0, 0, 0, time.UTC), false}, // Saturday night
+	}
+	for _, tt := range tests {
+		if got := overnight.match(tt.t); got != tt.want {
+			t.Errorf("match(%s) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
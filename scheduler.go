@@ -0,0 +1,158 @@
+package throughput
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+)
+
+// errPreempted is the cause used to cancel a waiter's wait on the limiter when it is preempted.
+var errPreempted = errors.New("throughput: preempted")
+
+// scheduler serializes waits on a shared limiter, so that the order in which waiters are admitted is decided
+// by a policy rather than by whichever goroutine happens to win the race for the limiter. Only one waiter
+// waits on the limiter at a time; the rest queue, ordered by key and then by arrival.
+//
+// Because the limiter is waited on one at a time, the aggregate rate is unaffected -- only its distribution.
+//
+// If preempt is set, a waiter arriving with a lower key than the current holder cancels the holder's wait on
+// the limiter (which the limiter should treat as a refund), and the holder re-queues.
+type scheduler struct {
+	lim     Limiter
+	preempt bool
+
+	mu           sync.Mutex
+	busy         bool
+	seq          uint64
+	queue        ticketHeap
+	holderKey    float64
+	holderCancel context.CancelCauseFunc // nil unless preempt is set and the holder may be preempted
+}
+
+type ticket struct {
+	key   float64
+	seq   uint64
+	ready chan struct{}
+	index int // within the heap, or -1 once admitted
+}
+
+// wait queues for the limiter, then waits on it for n. key is called with mu held to determine the waiter's
+// position in the queue, so it may safely access state guarded by mu.
+func (s *scheduler) wait(ctx context.Context, n int, key func() float64) error {
+	s.mu.Lock()
+	k := key()
+	seq := s.seq
+	s.seq++
+
+	for {
+		// s.mu is held at the top of each iteration
+		if !s.busy {
+			s.busy = true
+			s.mu.Unlock()
+		} else {
+			t := &ticket{key: k, seq: seq, ready: make(chan struct{})}
+			heap.Push(&s.queue, t)
+			if s.holderCancel != nil && k < s.holderKey {
+				s.holderCancel(errPreempted)
+				s.holderCancel = nil
+			}
+			s.mu.Unlock()
+
+			select {
+			case <-t.ready:
+			case <-ctx.Done():
+				s.mu.Lock()
+				if t.index >= 0 {
+					heap.Remove(&s.queue, t.index)
+					s.mu.Unlock()
+					return ctx.Err()
+				}
+				s.mu.Unlock()
+
+				// Admitted concurrently with cancellation, so pass the turn on.
+				s.release()
+				return ctx.Err()
+			}
+		}
+
+		err := s.run(ctx, n, k)
+		if err != errPreempted {
+			return err
+		}
+		s.mu.Lock()
+	}
+}
+
+// run waits on the limiter whilst holding the turn, then passes the turn on.
+func (s *scheduler) run(ctx context.Context, n int, key float64) error {
+	defer s.release()
+	if !s.preempt {
+		return s.lim.Wait(ctx, n)
+	}
+
+	wctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	s.mu.Lock()
+	s.holderKey = key
+	s.holderCancel = cancel
+	s.mu.Unlock()
+
+	err := s.lim.Wait(wctx, n)
+
+	s.mu.Lock()
+	s.holderCancel = nil
+	s.mu.Unlock()
+
+	if err != nil && ctx.Err() == nil && context.Cause(wctx) == errPreempted {
+		return errPreempted
+	}
+	return err
+}
+
+// release passes the turn to the next queued waiter, if there is one.
+func (s *scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.queue.Len() == 0 {
+		s.busy = false
+		return
+	}
+
+	t := heap.Pop(&s.queue).(*ticket)
+	close(t.ready)
+}
+
+type ticketHeap []*ticket
+
+func (h ticketHeap) Len() int { return len(h) }
+
+func (h ticketHeap) Less(i, j int) bool {
+	if h[i].key != h[j].key {
+		return h[i].key < h[j].key
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h ticketHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *ticketHeap) Push(x any) {
+	t := x.(*ticket)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *ticketHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*h = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,107 @@
+package throughput
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync/atomic"
+)
+
+// SessionLimiter rate-limits the streams of a multiplexed session, such as yamux or smux, so that each stream has
+// its own limit and all streams together are held to the session's. Per-stream limiters are children of a
+// Hierarchy per direction, so the bytes charged through each stream are rolled up.
+type SessionLimiter struct {
+	read, write *Hierarchy
+	newLim      func() Limiter
+	opts        []StreamOption
+	next        atomic.Uint64
+}
+
+// NewSessionLimiter returns a SessionLimiter where all streams draw from readLim and writeLim, which may be the
+// same limiter. newLim is called for each direction of each stream, returning its own limit, e.g.
+//
+//	func() throughput.Limiter { return throughput.NewTokenBucket(1<<20, 0) }
+//
+// A nil newLim means streams are only limited by the session.
+func NewSessionLimiter(readLim, writeLim Limiter, newLim func() Limiter, opts ...StreamOption) *SessionLimiter {
+	return &SessionLimiter{
+		read:   NewHierarchy(readLim),
+		write:  NewHierarchy(writeLim),
+		newLim: newLim,
+		opts:   opts,
+	}
+}
+
+// Wrap returns stream rate-limited by its own limiters and the session's. The context is used to unblock throttle
+// waits, as with NewConn.
+func (s *SessionLimiter) Wrap(ctx context.Context, stream net.Conn) *SessionStream {
+	name := strconv.FormatUint(s.next.Add(1), 10)
+	var readLim, writeLim Limiter
+	if s.newLim != nil {
+		readLim, writeLim = s.newLim(), s.newLim()
+	}
+	read, write := s.read.NewChild(name, readLim), s.write.NewChild(name, writeLim)
+	return &SessionStream{
+		Conn:  NewConn(ctx, stream, read, write, s.opts...),
+		read:  read,
+		write: write,
+	}
+}
+
+// Listener returns l with every accepted stream wrapped by Wrap. A yamux.Session is a net.Listener, so this
+// limits all the streams a session accepts.
+func (s *SessionLimiter) Listener(ctx context.Context, l net.Listener) net.Listener {
+	return &sessionListener{Listener: l, ctx: ctx, s: s}
+}
+
+// ReadBytes returns the total bytes read through all streams, including those since closed.
+func (s *SessionLimiter) ReadBytes() int64 {
+	return s.read.Bytes()
+}
+
+// WriteBytes returns the total bytes written through all streams, including those since closed.
+func (s *SessionLimiter) WriteBytes() int64 {
+	return s.write.Bytes()
+}
+
+// Streams returns the number of wrapped streams which have not been closed.
+func (s *SessionLimiter) Streams() int {
+	return len(s.read.Children())
+}
+
+// SessionStream is a stream of a multiplexed session, rate-limited by a SessionLimiter.
+type SessionStream struct {
+	*Conn
+	read, write *HierarchyChild
+}
+
+// ReadBytes returns the total bytes read through the stream.
+func (s *SessionStream) ReadBytes() int64 {
+	return s.read.Bytes()
+}
+
+// WriteBytes returns the total bytes written through the stream.
+func (s *SessionStream) WriteBytes() int64 {
+	return s.write.Bytes()
+}
+
+// Close removes the stream from its SessionLimiter, then closes it.
+func (s *SessionStream) Close() error {
+	_ = s.read.Close()
+	_ = s.write.Close()
+	return s.Conn.Close()
+}
+
+type sessionListener struct {
+	net.Listener
+	ctx context.Context
+	s   *SessionLimiter
+}
+
+func (l *sessionListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return l.s.Wrap(l.ctx, conn), nil
+}
@@ -0,0 +1,78 @@
+package throughput
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestSessionLimiter(t *testing.T) {
+	var session countingLimiter
+	var streamLims []*countingLimiter
+	s := NewSessionLimiter(&session, &session, func() Limiter {
+		lim := &countingLimiter{}
+		streamLims = append(streamLims, lim)
+		return lim
+	})
+
+	// Streams accepted from the session are wrapped
+	a, b := net.Pipe()
+	defer b.Close()
+	ln := s.Listener(context.Background(), &pipeListener{conns: []net.Conn{a}})
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := conn.(*SessionStream)
+
+	go func() { _, _ = b.Write(make([]byte, 100)) }()
+	if _, err := io.ReadFull(stream, make([]byte, 100)); err != nil {
+		t.Fatal(err)
+	}
+	go func() { _, _ = io.ReadFull(b, make([]byte, 50)) }()
+	if _, err := stream.Write(make([]byte, 50)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(streamLims) != 2 || streamLims[0].n.Load() != 100 || streamLims[1].n.Load() != 50 {
+		t.Error("stream reads and writes weren't charged to their own limiters")
+	}
+	if session.n.Load() != 150 || s.ReadBytes() != 100 || s.WriteBytes() != 50 {
+		t.Errorf("session charged %d, read %d, wrote %d, want 150, 100, 50",
+			session.n.Load(), s.ReadBytes(), s.WriteBytes())
+	}
+	if stream.ReadBytes() != 100 || stream.WriteBytes() != 50 {
+		t.Errorf("stream read %d, wrote %d, want 100, 50", stream.ReadBytes(), stream.WriteBytes())
+	}
+
+	if s.Streams() != 1 {
+		t.Errorf("got %d streams, want 1", s.Streams())
+	}
+	_ = stream.Close()
+	if s.Streams() != 0 {
+		t.Errorf("got %d streams after close, want 0", s.Streams())
+	}
+}
+
+// pipeListener accepts each of conns in turn.
+type pipeListener struct {
+	conns []net.Conn
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	if len(l.conns) == 0 {
+		return nil, net.ErrClosed
+	}
+	conn := l.conns[0]
+	l.conns = l.conns[1:]
+	return conn, nil
+}
+
+func (l *pipeListener) Close() error   { return nil }
+func (l *pipeListener) Addr() net.Addr { return pipeAddr{} }
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
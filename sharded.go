@@ -0,0 +1,145 @@
+package throughput
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShardedLimiter splits an aggregate rate across several independent token buckets, so that goroutines sharing
+// a limiter don't all serialize on one lock. It suits very high aggregate rates, e.g. 10+ GB/s spread over many
+// streams, where even a lock-free limiter's single word becomes a point of contention.
+//
+// Each Wait is charged to the less busy of two shards chosen at random, which keeps waiters spread evenly
+// without any shared state. Shares are rebalanced towards demand as shards use up their
+// allowance, without any background goroutines. As a single Wait only draws on one shard, a lone goroutine
+// achieves only a fraction of the aggregate rate: ShardedLimiter is intended for many concurrent streams.
+type ShardedLimiter struct {
+	shards   []*limiterShard
+	interval time.Duration
+
+	mu   sync.Mutex // held whilst rebalancing or changing the rate
+	rate atomic.Int64
+}
+
+type limiterShard struct {
+	lim       *TokenBucket
+	demand    atomic.Int64 // bytes charged since the last rebalance
+	threshold atomic.Int64 // demand which triggers a rebalance
+	waiting   atomic.Int64 // calls to Wait in progress
+	_         [32]byte     // pads to a cache line, so shards don't contend through false sharing
+}
+
+// NewShardedLimiter returns a limiter allowing bytesPerSec in aggregate across shards shards, each holding an
+// equal share of burst. Every shard is allowed at least 1 byte/sec, so that none is stuck at zero, which means
+// the aggregate can exceed a rate lower than the number of shards.
+//
+// Shares are rebalanced when a shard has used roughly interval's worth of its rate, as part of a Wait, rather
+// than periodically -- so the shares of a limiter which falls idle stay as they were.
+func NewShardedLimiter(bytesPerSec, burst int64, shards int, interval time.Duration, opts ...Option) *ShardedLimiter {
+	shards = max(shards, 1)
+	l := &ShardedLimiter{
+		shards:   make([]*limiterShard, shards),
+		interval: interval,
+	}
+	l.rate.Store(bytesPerSec)
+
+	for i := range l.shards {
+		share := shardShare(bytesPerSec, i, shards)
+		s := &limiterShard{lim: NewTokenBucket(share, split(burst, i, shards), opts...)}
+		s.threshold.Store(l.threshold(share))
+		l.shards[i] = s
+	}
+	return l
+}
+
+// split returns shard i's part of total divided between shards, spreading the remainder over the first shards.
+func split(total int64, i, shards int) int64 {
+	part := total / int64(shards)
+	if int64(i) < total%int64(shards) {
+		part++
+	}
+	return part
+}
+
+// shardShare returns shard i's part of rate, which is at least 1 byte/sec unless rate is zero.
+func shardShare(rate int64, i, shards int) int64 {
+	if rate <= 0 {
+		return 0
+	}
+	return max(split(rate, i, shards), 1)
+}
+
+// threshold returns the demand at which a shard with rate triggers a rebalance.
+func (l *ShardedLimiter) threshold(rate int64) int64 {
+	return max(int64(float64(rate)*l.interval.Seconds()), 1)
+}
+
+func (l *ShardedLimiter) Wait(ctx context.Context, n int) error {
+	s := l.shards[rand.IntN(len(l.shards))]
+	if other := l.shards[rand.IntN(len(l.shards))]; other.waiting.Load() < s.waiting.Load() {
+		s = other
+	}
+
+	if s.demand.Add(int64(n)) >= s.threshold.Load() && l.mu.TryLock() {
+		l.rebalance()
+		l.mu.Unlock()
+	}
+
+	s.waiting.Add(1)
+	defer s.waiting.Add(-1)
+	return s.lim.Wait(ctx, n)
+}
+
+// rebalance shares the rate between shards in proportion to their demand since the last rebalance. Every shard
+// keeps a small share, so that it isn't starved if demand shifts to it. l.mu must be held.
+func (l *ShardedLimiter) rebalance() {
+	demands := make([]int64, len(l.shards))
+	var total int64
+	for i, s := range l.shards {
+		demands[i] = s.demand.Swap(0)
+		total += demands[i]
+	}
+
+	floor := max(total/int64(4*len(l.shards)), 1)
+	var weights int64
+	for i := range demands {
+		demands[i] += floor
+		weights += demands[i]
+	}
+
+	rate := l.rate.Load()
+	for i, s := range l.shards {
+		share := int64(float64(rate) * float64(demands[i]) / float64(weights))
+		if rate > 0 {
+			share = max(share, 1)
+		}
+		s.lim.SetLimit(share)
+		s.threshold.Store(l.threshold(share))
+	}
+}
+
+// Limit returns the aggregate rate in bytes per second.
+func (l *ShardedLimiter) Limit() int64 {
+	return l.rate.Load()
+}
+
+// SetLimit changes the aggregate rate to bytesPerSec, scaling each shard's share.
+func (l *ShardedLimiter) SetLimit(bytesPerSec int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	old := l.rate.Swap(bytesPerSec)
+	for i, s := range l.shards {
+		share := shardShare(bytesPerSec, i, len(l.shards))
+		if old > 0 && bytesPerSec > 0 {
+			share = max(int64(float64(s.lim.Limit())*float64(bytesPerSec)/float64(old)), 1)
+		}
+		s.lim.SetLimit(share)
+		s.threshold.Store(l.threshold(share))
+	}
+}
+
+var _ AdjustableLimiter = (*ShardedLimiter)(nil)
@@ -0,0 +1,69 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedLimiter(t *testing.T) {
+	lim := NewShardedLimiter(1024*1024, 64*1024, 4, 100*time.Millisecond)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1024; j++ {
+				_ = lim.Wait(context.Background(), 64)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 1MB in aggregate at 1MB/sec, less the 64KB burst
+	err := verifyWithSlop(time.Since(start), 937*time.Millisecond, 100*time.Millisecond)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestShardedLimiterRebalance(t *testing.T) {
+	lim := NewShardedLimiter(4000, 0, 4, time.Second)
+
+	lim.shards[0].demand.Store(3000)
+	lim.shards[1].demand.Store(1000)
+	lim.mu.Lock()
+	lim.rebalance()
+	lim.mu.Unlock()
+
+	// Shares follow demand, with a floor for idle shards.
+	var total int64
+	for i, want := range []int64{2600, 1000, 200, 200} {
+		got := lim.shards[i].lim.Limit()
+		if got < want-5 || got > want+5 {
+			t.Errorf("shard %d has %d bytes/sec, want about %d", i, got, want)
+		}
+		total += got
+	}
+	if total < 3990 || total > 4000 {
+		t.Errorf("shares total %d bytes/sec, want 4000", total)
+	}
+
+	lim.SetLimit(8000)
+	if got := lim.shards[0].lim.Limit(); got < 5190 || got > 5210 {
+		t.Errorf("after doubling the limit, shard 0 has %d bytes/sec, want about 5200", got)
+	}
+}
+
+func TestShardedLimiterLowRate(t *testing.T) {
+	// A rate lower than the number of shards still leaves every shard able to send, at 1 byte/sec
+	lim := NewShardedLimiter(4, 4, 8, time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	if err := lim.Wait(ctx, 1); err != nil {
+		t.Errorf("Wait returned %v", err)
+	}
+}
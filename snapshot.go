@@ -0,0 +1,164 @@
+package throughput
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrInvalidSnapshot is returned when restoring a limiter from data which isn't a snapshot of that kind of limiter.
+var ErrInvalidSnapshot = errors.New("throughput: invalid snapshot")
+
+// Snapshots are a version, a kind, then a fixed number of 64-bit fields for the kind.
+const snapshotVersion = 1
+
+const (
+	snapshotTokenBucket byte = iota + 1
+	snapshotGCRA
+	snapshotLeakyBucket
+	snapshotQuota
+)
+
+func marshalSnapshot(kind byte, fields ...uint64) []byte {
+	data := []byte{snapshotVersion, kind}
+	for _, f := range fields {
+		data = binary.BigEndian.AppendUint64(data, f)
+	}
+	return data
+}
+
+func unmarshalSnapshot(data []byte, kind byte, fields int) ([]uint64, error) {
+	if len(data) != 2+8*fields || data[0] != snapshotVersion || data[1] != kind {
+		return nil, ErrInvalidSnapshot
+	}
+
+	values := make([]uint64, fields)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint64(data[2+8*i:])
+	}
+	return values, nil
+}
+
+// Times are stored as Unix nanoseconds, with 0 for the zero time.
+func timeField(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func fieldTime(f uint64) time.Time {
+	if f == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(f))
+}
+
+// MarshalBinary snapshots the bucket's tokens, so they can be restored after a restart with UnmarshalBinary.
+// The rate and burst are not included.
+func (t *TokenBucket) MarshalBinary() ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.b.advance(t.w.now())
+	return marshalSnapshot(snapshotTokenBucket, math.Float64bits(t.b.tokens), timeField(t.b.last)), nil
+}
+
+// UnmarshalBinary restores tokens from a snapshot made by MarshalBinary. Tokens accrue for the time since the
+// snapshot was made, up to the current burst.
+func (t *TokenBucket) UnmarshalBinary(data []byte) error {
+	f, err := unmarshalSnapshot(data, snapshotTokenBucket, 2)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.w.now()
+	t.b.tokens = min(math.Float64frombits(f[0]), t.b.burst)
+	t.b.last = fieldTime(f[1])
+	if t.b.last.After(now) {
+		// Don't trust a snapshot from the future
+		t.b.last = now
+	}
+	t.b.advance(now)
+	return nil
+}
+
+// MarshalBinary snapshots the limiter's theoretical arrival time, so it can be restored after a restart with
+// UnmarshalBinary. The rate and burst are not included.
+func (g *GCRALimiter) MarshalBinary() ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return marshalSnapshot(snapshotGCRA, timeField(g.tat)), nil
+}
+
+// UnmarshalBinary restores the limiter from a snapshot made by MarshalBinary.
+func (g *GCRALimiter) UnmarshalBinary(data []byte) error {
+	f, err := unmarshalSnapshot(data, snapshotGCRA, 1)
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tat = fieldTime(f[0])
+	return nil
+}
+
+// MarshalBinary snapshots the bytes queued in the bucket, so they can be restored after a restart with
+// UnmarshalBinary. The rate and capacity are not included.
+func (l *LeakyBucket) MarshalBinary() ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return marshalSnapshot(snapshotLeakyBucket, timeField(l.drained)), nil
+}
+
+// UnmarshalBinary restores the bucket from a snapshot made by MarshalBinary.
+func (l *LeakyBucket) UnmarshalBinary(data []byte) error {
+	f, err := unmarshalSnapshot(data, snapshotLeakyBucket, 1)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.drained = fieldTime(f[0])
+	return nil
+}
+
+// MarshalBinary snapshots the bytes used from the budget and the period they were used in, so they can be
+// restored after a restart with UnmarshalBinary. The budget itself is not included.
+func (q *QuotaLimiter) MarshalBinary() ([]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollover()
+	return marshalSnapshot(snapshotQuota, uint64(q.used), timeField(q.periodStart)), nil
+}
+
+// UnmarshalBinary restores the bytes used from a snapshot made by MarshalBinary. If the snapshot is from an
+// earlier period, the quota resets as usual.
+func (q *QuotaLimiter) UnmarshalBinary(data []byte) error {
+	f, err := unmarshalSnapshot(data, snapshotQuota, 2)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.used = int64(f[0])
+	q.periodStart = fieldTime(f[1])
+	q.rollover()
+	return nil
+}
+
+var _ encoding.BinaryMarshaler = (*TokenBucket)(nil)
+var _ encoding.BinaryUnmarshaler = (*TokenBucket)(nil)
+var _ encoding.BinaryMarshaler = (*GCRALimiter)(nil)
+var _ encoding.BinaryUnmarshaler = (*GCRALimiter)(nil)
+var _ encoding.BinaryMarshaler = (*LeakyBucket)(nil)
+var _ encoding.BinaryUnmarshaler = (*LeakyBucket)(nil)
+var _ encoding.BinaryMarshaler = (*QuotaLimiter)(nil)
+var _ encoding.BinaryUnmarshaler = (*QuotaLimiter)(nil)
@@ -0,0 +1,77 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketSnapshot(t *testing.T) {
+	c := &stepClock{now: time.Unix(1000, 0)}
+	tb := NewTokenBucket(100, 1000, WithClock(c))
+	_ = tb.Wait(context.Background(), 900)
+
+	data, err := tb.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	// The restarted bucket begins full, but is restored to 100 tokens, plus 1s of refill whilst down
+	c.NewTimer(time.Second)
+	restored := NewTokenBucket(100, 1000, WithClock(c))
+	err = restored.UnmarshalBinary(data)
+	if err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if got := restored.Tokens(); got != 200 {
+		t.Errorf("Tokens() = %.0f after restore, want 200", got)
+	}
+
+	err = restored.UnmarshalBinary(data[:5])
+	if !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("truncated snapshot: err = %v, want ErrInvalidSnapshot", err)
+	}
+	gcraData, _ := NewGCRALimiter(100, 0).MarshalBinary()
+	err = restored.UnmarshalBinary(gcraData)
+	if !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("snapshot of another limiter: err = %v, want ErrInvalidSnapshot", err)
+	}
+}
+
+func TestGCRASnapshot(t *testing.T) {
+	g := NewGCRALimiter(1000, 0)
+	g.Reserve(1000) // TAT is now ~1s in the future
+
+	data, _ := g.MarshalBinary()
+	restored := NewGCRALimiter(1000, 0)
+	err := restored.UnmarshalBinary(data)
+	if err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+
+	delay, _ := restored.Reserve(0)
+	if delay < 900*time.Millisecond {
+		t.Errorf("restored limiter delays %v, want ~1s", delay)
+	}
+}
+
+func TestLeakyBucketSnapshot(t *testing.T) {
+	l := NewLeakyBucket(1000, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = l.Wait(ctx, 1000) }()
+	for l.Queued() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	data, _ := l.MarshalBinary()
+	restored := NewLeakyBucket(1000, 0)
+	err := restored.UnmarshalBinary(data)
+	if err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if got := restored.Queued(); got < 900 {
+		t.Errorf("Queued() = %d after restore, want ~1000", got)
+	}
+}
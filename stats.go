@@ -0,0 +1,79 @@
+package throughput
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NewStatsHandler returns an http.Handler streaming the current rates and totals of rec's sources, for lightweight
+// dashboards. Clients accepting text/event-stream receive a "stats" Server-Sent Event every interval, whose data
+// is a JSON array of samples, until they disconnect. Other clients are long-polled: the response is a single JSON
+// array of samples, measured over the interval after the request.
+//
+// Rates are measured by the handler for each client, independently of Record.
+func NewStatsHandler(rec *Recorder, interval time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := &statsSampler{rec: rec, bytes: make(map[*recorderSource]int64)}
+		s.sample()
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			select {
+			case <-r.Context().Done():
+			case <-t.C:
+				writeJSON(w, s.sample())
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		rc := http.NewResponseController(w)
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-t.C:
+			}
+
+			data, _ := json.Marshal(s.sample())
+			_, err := fmt.Fprintf(w, "event: stats\ndata: %s\n\n", data)
+			if err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				return
+			}
+		}
+	})
+}
+
+// statsSampler measures the rates of a Recorder's sources since its previous sample.
+type statsSampler struct {
+	rec   *Recorder
+	bytes map[*recorderSource]int64
+	at    time.Time
+}
+
+func (s *statsSampler) sample() []Sample {
+	now := s.rec.w.now()
+	elapsed := now.Sub(s.at)
+
+	samples := []Sample{}
+	for _, src := range s.rec.sourceList() {
+		bytes := src.total()
+		smp := Sample{Time: now, Name: src.name, Bytes: bytes}
+		if prev, ok := s.bytes[src]; ok && elapsed > 0 {
+			smp.Rate = float64(bytes-prev) / elapsed.Seconds()
+		}
+		s.bytes[src] = bytes
+		samples = append(samples, smp)
+	}
+	s.at = now
+	return samples
+}
@@ -0,0 +1,78 @@
+package throughput
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsHandler(t *testing.T) {
+	rec := NewRecorder(10)
+	var lim countingLimiter
+	rec.Add("download", lim.n.Load)
+	lim.n.Store(500)
+
+	srv := httptest.NewServer(NewStatsHandler(rec, 10*time.Millisecond))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type is %q, want text/event-stream", ct)
+	}
+
+	// The first event carries the source, and subsequent events keep coming
+	sc := bufio.NewScanner(resp.Body)
+	var events []string
+	for sc.Scan() && len(events) < 2 {
+		if data, ok := strings.CutPrefix(sc.Text(), "data: "); ok {
+			events = append(events, data)
+		}
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	var samples []Sample
+	if err := json.Unmarshal([]byte(events[0]), &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Name != "download" || samples[0].Bytes != 500 {
+		t.Errorf("unexpected samples %+v", samples)
+	}
+}
+
+func TestStatsHandlerLongPoll(t *testing.T) {
+	rec := NewRecorder(10)
+	var lim countingLimiter
+	rec.Add("download", lim.n.Load)
+
+	srv := httptest.NewServer(NewStatsHandler(rec, 50*time.Millisecond))
+	defer srv.Close()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		lim.n.Store(1000)
+	}()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var samples []Sample
+	if err := json.NewDecoder(resp.Body).Decode(&samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Bytes != 1000 || samples[0].Rate <= 0 {
+		t.Errorf("unexpected samples %+v, want the bytes and rate over the interval", samples)
+	}
+}
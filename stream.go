@@ -0,0 +1,250 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrWaitCanceled matches, via errors.Is, an error from a Reader or Writer whose throttle wait failed -- usually
+// because its context was done -- as opposed to an error from the underlying I/O. The I/O itself succeeded.
+var ErrWaitCanceled = errors.New("throughput: wait canceled")
+
+// ThrottleError is returned by a Reader or Writer when the throttle wait after a successful read or write fails.
+// It matches ErrWaitCanceled, and unwraps to the limiter's error, e.g. context.Canceled.
+//
+// The Bytes were transferred regardless, and are also returned as n. Retry logic should resume after them, and
+// can use Charged to decide whether they still need to be accounted for.
+type ThrottleError struct {
+	Op    string // "read" or "write"
+	Bytes int    // the bytes transferred before the wait
+	Err   error  // the limiter's error
+
+	// Charged reports whether the limiter may have kept the charge for Bytes. It is false only when the limiter
+	// is known to have undone it, i.e. it implements Refunder, as does any limiter it wraps.
+	Charged bool
+}
+
+func newThrottleError(op string, n int, lim Limiter, err error) *ThrottleError {
+	return &ThrottleError{Op: op, Bytes: n, Err: err, Charged: !refunds(lim)}
+}
+
+func (e *ThrottleError) Error() string {
+	verb := "reading"
+	if e.Op == "write" {
+		verb = "writing"
+	}
+	return fmt.Sprintf("waiting after %s %d bytes: %v", verb, e.Bytes, e.Err)
+}
+
+func (e *ThrottleError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ThrottleError) Is(target error) bool {
+	return target == ErrWaitCanceled
+}
+
+// StreamOption configures a Reader or Writer.
+type StreamOption func(*stream)
+
+// WithConcurrent serializes the underlying I/O of a Reader or Writer with a mutex, so that it can be used from
+// multiple goroutines even when the wrapped io.Reader or io.Writer isn't safe for concurrent use, e.g. a
+// bufio.Writer. Waits aren't serialized, so one goroutine's I/O can proceed whilst another is being throttled.
+//
+// This isn't needed for sources and destinations which are already safe for concurrent use, such as net.Conn.
+func WithConcurrent() StreamOption {
+	return func(s *stream) {
+		s.mu = new(sync.Mutex)
+	}
+}
+
+// WithDeferredWait changes what happens when the context is done during the wait after a successful read or
+// write: rather than returning the bytes transferred alongside an error, the Reader or Writer returns them with a
+// nil error, and charges them to the limiter before the next read or write instead. This suits callers such as
+// io.Copy, which don't expect data to be transferred when an error is reported.
+//
+// A read or write made whilst the deferred charge can't be paid, e.g. as the context is still done, fails without
+// transferring anything.
+func WithDeferredWait() StreamOption {
+	return func(s *stream) {
+		s.deferWait = true
+	}
+}
+
+// WithCloseUnderlying makes Close on a Reader or Writer also close the wrapped io.Reader or io.Writer, if it
+// implements io.Closer.
+func WithCloseUnderlying() StreamOption {
+	return func(s *stream) {
+		s.closeUnderlying = true
+	}
+}
+
+// stream holds the state shared by Reader and Writer.
+type stream struct {
+	ctx             context.Context // the context passed at construction
+	closing         context.Context // canceled by Close
+	cancelClosing   context.CancelFunc
+	closed          atomic.Bool
+	lim             Limiter
+	mu              *sync.Mutex // serializes I/O, if WithConcurrent
+	deferWait       bool
+	debt            atomic.Int64 // bytes to charge before the next I/O, if deferWait
+	closeUnderlying bool
+	paused          atomic.Pointer[chan struct{}] // closed on Resume, nil if not paused
+	disabled        atomic.Bool                   // bypasses lim
+}
+
+func newStream(ctx context.Context, lim Limiter, opts []StreamOption) *stream {
+	s := &stream{ctx: ctx, lim: lim}
+	if ctx.Done() == nil {
+		// ctx can't be canceled, so a child of it registers nothing, and waits on ctx can use closing alone.
+		s.closing, s.cancelClosing = context.WithCancel(ctx)
+	} else {
+		// A child of ctx would stay registered on it until the stream is closed, which many never are.
+		s.closing, s.cancelClosing = context.WithCancel(context.Background())
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// bind returns a context which is done when either ctx is, or the stream is closed. Waits bind their context for
+// their duration only, so nothing is left registered on ctx once they return.
+func (s *stream) bind(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == s.ctx && ctx.Done() == nil {
+		return s.closing, nop
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.closing, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func nop() {}
+
+// close unblocks waits in progress and fails further I/O, then closes underlying if WithCloseUnderlying.
+func (s *stream) close(underlying any) error {
+	if s.closed.Swap(true) {
+		return nil
+	}
+	s.cancelClosing()
+
+	if c, ok := underlying.(io.Closer); ok && s.closeUnderlying {
+		return c.Close()
+	}
+	return nil
+}
+
+func (s *stream) pause() {
+	ch := make(chan struct{})
+	s.paused.CompareAndSwap(nil, &ch)
+}
+
+func (s *stream) resume() {
+	if ch := s.paused.Swap(nil); ch != nil {
+		close(*ch)
+	}
+}
+
+// park blocks whilst the stream is paused.
+func (s *stream) park(ctx context.Context) error {
+	ch := s.paused.Load()
+	if ch == nil {
+		return nil
+	}
+	select {
+	case <-*ch:
+		return nil
+	case <-ctx.Done():
+		if s.closed.Load() {
+			return ErrClosed
+		}
+		return ctx.Err()
+	}
+}
+
+// wait charges n bytes transferred by op, returning a *ThrottleError if the wait fails.
+func (s *stream) wait(ctx context.Context, op string, n int) error {
+	if s.disabled.Load() && s.paused.Load() == nil {
+		return nil
+	}
+	ctx, cancel := s.bind(ctx)
+	defer cancel()
+
+	var err error
+	if !s.disabled.Load() {
+		err = s.lim.Wait(ctx, n)
+	}
+	if err == nil {
+		err = s.park(ctx)
+		if err != nil && !s.deferWait {
+			// The bytes were charged, but the stream was paused before they could be returned
+			return &ThrottleError{Op: op, Bytes: n, Err: err, Charged: true}
+		}
+		return nil
+	}
+
+	if s.closed.Load() {
+		return newThrottleError(op, n, s.lim, ErrClosed)
+	}
+	if s.deferWait && ctx.Err() != nil {
+		// Bytes still charged to the limiter needn't be charged again
+		if refunds(s.lim) {
+			s.debt.Add(int64(n))
+		}
+		return nil
+	}
+	return newThrottleError(op, n, s.lim, err)
+}
+
+// before parks whilst the stream is paused, then charges bytes deferred by WithDeferredWait, ahead of further I/O.
+func (s *stream) before(ctx context.Context, op string) error {
+	owes := s.deferWait && !s.disabled.Load() && s.debt.Load() != 0
+	if s.paused.Load() == nil && !owes {
+		return nil
+	}
+	ctx, cancel := s.bind(ctx)
+	defer cancel()
+
+	if err := s.park(ctx); err != nil {
+		return newThrottleError(op, 0, s.lim, err)
+	}
+	if !s.deferWait || s.disabled.Load() {
+		return nil
+	}
+	debt := s.debt.Swap(0)
+	if debt == 0 {
+		return nil
+	}
+
+	err := s.lim.Wait(ctx, int(debt))
+	if err != nil {
+		if refunds(s.lim) {
+			s.debt.Add(debt)
+		}
+		if s.closed.Load() {
+			err = ErrClosed
+		}
+		return newThrottleError(op, 0, s.lim, err)
+	}
+	return nil
+}
+
+func (s *stream) lock() {
+	if s.mu != nil {
+		s.mu.Lock()
+	}
+}
+
+func (s *stream) unlock() {
+	if s.mu != nil {
+		s.mu.Unlock()
+	}
+}
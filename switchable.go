@@ -0,0 +1,43 @@
+package throughput
+
+import (
+	"context"
+	"fmt"
+)
+
+// SwitchableLimiter delegates each call to Wait to one of several limiters, chosen by a caller-supplied selector.
+//
+// The selector is evaluated on every Wait, so the active policy can change (e.g. peak vs off-peak, wifi vs
+// cellular) without rebuilding the readers and writers that use the limiter. It should be cheap and safe for
+// concurrent use, as it sits on the hot path.
+type SwitchableLimiter struct {
+	sel  func() int
+	lims []Limiter
+}
+
+// NewSwitchableLimiter returns a limiter that delegates to lims[sel()] on each Wait.
+// A selector returning an index outside of lims causes Wait to return an error.
+func NewSwitchableLimiter(sel func() int, lims ...Limiter) *SwitchableLimiter {
+	return &SwitchableLimiter{sel: sel, lims: lims}
+}
+
+// NewPredicateLimiter is a convenience function to switch between two limiters based on a boolean predicate.
+// whenTrue is used when pred returns true, otherwise whenFalse is used.
+func NewPredicateLimiter(pred func() bool, whenTrue, whenFalse Limiter) *SwitchableLimiter {
+	return NewSwitchableLimiter(func() int {
+		if pred() {
+			return 0
+		}
+		return 1
+	}, whenTrue, whenFalse)
+}
+
+func (s *SwitchableLimiter) Wait(ctx context.Context, n int) error {
+	i := s.sel()
+	if i < 0 || i >= len(s.lims) {
+		return fmt.Errorf("selector chose limiter %d of %d", i, len(s.lims))
+	}
+	return s.lims[i].Wait(ctx, n)
+}
+
+var _ Limiter = (*SwitchableLimiter)(nil)
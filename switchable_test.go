@@ -0,0 +1,42 @@
+package throughput
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSwitchableLimiter(t *testing.T) {
+	var a, b countingLimiter
+	var useB atomic.Bool
+
+	lim := NewPredicateLimiter(useB.Load, &b, &a)
+
+	_ = lim.Wait(context.Background(), 10)
+	useB.Store(true)
+	_ = lim.Wait(context.Background(), 20)
+
+	if got := a.n.Load(); got != 10 {
+		t.Errorf("limiter a charged %d bytes, want 10", got)
+	}
+	if got := b.n.Load(); got != 20 {
+		t.Errorf("limiter b charged %d bytes, want 20", got)
+	}
+}
+
+func TestSwitchableLimiterOutOfRange(t *testing.T) {
+	lim := NewSwitchableLimiter(func() int { return 1 }, &countingLimiter{})
+	if err := lim.Wait(context.Background(), 1); err == nil {
+		t.Error("expected error for out of range selector")
+	}
+}
+
+// countingLimiter never delays, and records the total n passed to Wait.
+type countingLimiter struct {
+	n atomic.Int64
+}
+
+func (c *countingLimiter) Wait(_ context.Context, n int) error {
+	c.n.Add(int64(n))
+	return nil
+}
@@ -2,9 +2,9 @@ package throughput
 
 import (
 	"context"
-	"fmt"
 	"golang.org/x/time/rate"
 	"io"
+	"net"
 	"sync/atomic"
 )
 
@@ -15,70 +15,278 @@ type Limiter interface {
 	Wait(ctx context.Context, n int) error
 }
 
+// AdjustableLimiter is a Limiter whose rate can be changed at runtime, allowing wrappers to implement
+// policies such as ramping up, boosting or adapting the rate.
+type AdjustableLimiter interface {
+	Limiter
+
+	// Limit returns the current rate in bytes per second.
+	Limit() int64
+
+	// SetLimit changes the rate to bytesPerSec.
+	SetLimit(bytesPerSec int64)
+}
+
 type Reader struct {
-	ctx context.Context
+	*stream
 	src io.Reader
-	lim Limiter
 }
 
 type Writer struct {
-	ctx context.Context
+	*stream
 	dst io.Writer
-	lim Limiter
 }
 
 // NewReader returns an io.Reader that reads from src and is rate-limited by lim.
 // The context is used to unblock calls to Read when rate-limited.
 // A limiter can be shared across multiple readers.
-func NewReader(ctx context.Context, src io.Reader, lim Limiter) *Reader {
+//
+// A Reader is safe for concurrent use if src and lim are. Otherwise, see WithConcurrent.
+func NewReader(ctx context.Context, src io.Reader, lim Limiter, opts ...StreamOption) *Reader {
 	return &Reader{
-		ctx: ctx,
-		src: src,
-		lim: lim,
+		stream: newStream(ctx, lim, opts),
+		src:    src,
 	}
 }
 
 // NewWriter returns an io.Writer that writes into dst and is rate-limited by lim.
 // The context is used to unblock calls to Write when rate-limited.
 // A limiter can be shared across multiple writers.
-func NewWriter(ctx context.Context, dst io.Writer, lim Limiter) *Writer {
+//
+// A Writer is safe for concurrent use if dst and lim are. Otherwise, see WithConcurrent.
+func NewWriter(ctx context.Context, dst io.Writer, lim Limiter, opts ...StreamOption) *Writer {
 	return &Writer{
-		ctx: ctx,
-		dst: dst,
-		lim: lim,
+		stream: newStream(ctx, lim, opts),
+		dst:    dst,
 	}
 }
 
 func (s *Reader) Read(p []byte) (n int, err error) {
-	n, err = s.src.Read(p)
+	return s.read(s.ctx, p)
+}
+
+// ReadContext is Read, but waits on ctx rather than the Reader's context. This lets a long-lived Reader, such as
+// one wrapping a connection serving many requests, bound each throttle wait by a per-request deadline.
+func (s *Reader) ReadContext(ctx context.Context, p []byte) (n int, err error) {
+	return s.read(ctx, p)
+}
+
+// read reads into p, waiting on ctx. Waits are also unblocked by Close.
+func (s *Reader) read(ctx context.Context, p []byte) (n int, err error) {
+	if s.closed.Load() {
+		return 0, ErrClosed
+	}
+	err = s.before(ctx, "read")
 	if err != nil {
 		return
 	}
 
-	// Wait must occur after Read, as n is unknown until Read has occurred
-	err = s.lim.Wait(s.ctx, n)
+	s.lock()
+	n, err = s.src.Read(p)
+	s.unlock()
 	if err != nil {
-		err = fmt.Errorf("waiting after reading %d bytes: %w", n, err)
+		// Bytes returned alongside an error, e.g. io.EOF, were still transferred. The error takes precedence.
+		if n > 0 {
+			_ = s.wait(ctx, "read", n)
+		}
 		return
 	}
+
+	// Wait must occur after Read, as n is unknown until Read has occurred
+	err = s.wait(ctx, "read", n)
 	return
 }
 
 func (s *Writer) Write(p []byte) (n int, err error) {
+	return s.write(s.ctx, p)
+}
+
+// WriteContext is Write, but waits on ctx rather than the Writer's context. See Reader.ReadContext.
+func (s *Writer) WriteContext(ctx context.Context, p []byte) (n int, err error) {
+	return s.write(ctx, p)
+}
+
+// write writes p, waiting on ctx. Waits are also unblocked by Close.
+func (s *Writer) write(ctx context.Context, p []byte) (n int, err error) {
+	if s.closed.Load() {
+		return 0, ErrClosed
+	}
+	err = s.before(ctx, "write")
+	if err != nil {
+		return
+	}
+
+	s.lock()
 	n, err = s.dst.Write(p)
+	s.unlock()
 	if err != nil {
+		if n > 0 {
+			_ = s.wait(ctx, "write", n)
+		}
 		return
 	}
 
 	// Wait occurs after Write for consistency with Read.
-	err = s.lim.Wait(s.ctx, n)
+	err = s.wait(ctx, "write", n)
+	return
+}
+
+// WriteBuffers writes bufs, charging the limiter once for the total. If dst is a net.Conn supporting vectored
+// I/O, such as *net.TCPConn, a single writev syscall is made rather than a write per buffer -- which writing bufs
+// to the Writer with net.Buffers.WriteTo can't do. Like net.Buffers.WriteTo, it consumes bufs.
+func (s *Writer) WriteBuffers(bufs *net.Buffers) (int64, error) {
+	return s.writeBuffers(s.ctx, bufs)
+}
+
+// writeBuffers is WriteBuffers, waiting on ctx.
+func (s *Writer) writeBuffers(ctx context.Context, bufs *net.Buffers) (n int64, err error) {
+	if s.closed.Load() {
+		return 0, ErrClosed
+	}
+	err = s.before(ctx, "write")
 	if err != nil {
-		err = fmt.Errorf("waiting after writing %d bytes: %w", n, err)
 		return
 	}
+
+	s.lock()
+	n, err = bufs.WriteTo(s.dst)
+	s.unlock()
+	if err != nil {
+		if n > 0 {
+			_ = s.wait(ctx, "write", int(n))
+		}
+		return
+	}
+
+	err = s.wait(ctx, "write", int(n))
 	return
 }
 
+// SetEnabled enables or disables throttling of this Reader alone. Whilst disabled, bytes read aren't charged to
+// the limiter, so other readers and writers sharing it stay shaped -- unlike DisableableLimiter, which bypasses
+// the limiter for all of them.
+func (s *Reader) SetEnabled(enabled bool) {
+	s.disabled.Store(!enabled)
+}
+
+// SetEnabled enables or disables throttling of this Writer alone. See Reader.SetEnabled.
+func (s *Writer) SetEnabled(enabled bool) {
+	s.disabled.Store(!enabled)
+}
+
+// Pause suspends reads until Resume is called: calls to Read block before reading, and a Read already waiting on
+// the limiter blocks before returning. Unlike setting the limiter's rate to zero, this doesn't affect other
+// readers or writers sharing the limiter.
+func (s *Reader) Pause() {
+	s.pause()
+}
+
+// Resume continues reads suspended by Pause.
+func (s *Reader) Resume() {
+	s.resume()
+}
+
+// Pause suspends writes until Resume is called. See Reader.Pause.
+func (s *Writer) Pause() {
+	s.pause()
+}
+
+// Resume continues writes suspended by Pause.
+func (s *Writer) Resume() {
+	s.resume()
+}
+
+// Close unblocks any Read waiting on the limiter, which returns a *ThrottleError matching ErrClosed, and causes
+// further reads to fail with ErrClosed. With WithCloseUnderlying, it also closes src.
+func (s *Reader) Close() error {
+	return s.close(s.src)
+}
+
+// Close unblocks any Write waiting on the limiter, which returns a *ThrottleError matching ErrClosed, and causes
+// further writes to fail with ErrClosed. With WithCloseUnderlying, it also closes dst.
+func (s *Writer) Close() error {
+	return s.close(s.dst)
+}
+
+// ReadWriter is a bidirectional stream with separate limiters for reads and writes, e.g. for asymmetric links.
+// The Reader and Writer are exposed for per-direction control, such as pausing one direction.
+type ReadWriter struct {
+	*Reader
+	*Writer
+	rw io.ReadWriter
+}
+
+// NewReadWriter returns an io.ReadWriter that reads from and writes to rw, with reads rate-limited by readLim and
+// writes by writeLim. The context is used to unblock calls when rate-limited.
+func NewReadWriter(ctx context.Context, rw io.ReadWriter, readLim, writeLim Limiter, opts ...StreamOption) *ReadWriter {
+	return &ReadWriter{
+		Reader: NewReader(ctx, rw, readLim, opts...),
+		Writer: NewWriter(ctx, rw, writeLim, opts...),
+		rw:     rw,
+	}
+}
+
+// Close unblocks any reads or writes waiting on their limiters, then closes rw if it implements io.Closer.
+func (s *ReadWriter) Close() error {
+	_ = s.Reader.close(nil)
+	_ = s.Writer.close(nil)
+	if c, ok := s.rw.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Copy copies from src to dst until EOF or an error, rate-limited by lim, and returns the number of bytes copied.
+// It is io.Copy through a Reader, but sizes its buffer to the limiter's rate where known, so that slow rates
+// are met with small, evenly-spaced reads rather than a large read followed by a long wait.
+func Copy(ctx context.Context, dst io.Writer, src io.Reader, lim Limiter) (int64, error) {
+	size := copyChunkSize(lim, 32*1024)
+	// dst is wrapped to hide any ReaderFrom implementation, which would use its own buffer.
+	return io.CopyBuffer(struct{ io.Writer }{dst}, NewReader(ctx, src, lim), make([]byte, size))
+}
+
+// CopyChunked is Copy for destinations implementing io.ReaderFrom, such as *os.File and *net.TCPConn, where
+// io.Copy would use the kernel's fast path -- sendfile, splice or copy_file_range -- which wrapping either side in
+// a Reader or Writer defeats. Instead, bounded chunks of src are handed to dst's ReadFrom, so the fast path is
+// still used for each chunk, and lim is charged in between.
+//
+// If dst doesn't implement io.ReaderFrom, CopyChunked is equivalent to Copy.
+func CopyChunked(ctx context.Context, dst io.Writer, src io.Reader, lim Limiter) (int64, error) {
+	rf, ok := dst.(io.ReaderFrom)
+	if !ok {
+		return Copy(ctx, dst, src, lim)
+	}
+
+	// Larger chunks than Copy, as they don't pass through a buffer.
+	size := copyChunkSize(lim, 1024*1024)
+	chunk := &io.LimitedReader{R: src}
+
+	var written int64
+	for {
+		chunk.N = size
+		n, err := rf.ReadFrom(chunk)
+		written += n
+		if n > 0 {
+			if werr := lim.Wait(ctx, int(n)); werr != nil && err == nil {
+				err = newThrottleError("write", int(n), lim, werr)
+			}
+		}
+		if err != nil || n < size {
+			// A short chunk means src reached EOF
+			return written, err
+		}
+	}
+}
+
+// copyChunkSize returns the size of the chunks to copy through lim, up to limit.
+func copyChunkSize(lim Limiter, limit int64) int64 {
+	if a, ok := lim.(AdjustableLimiter); ok {
+		// Aim for around 10 chunks per second.
+		return min(max(a.Limit()/10, 512), limit)
+	}
+	return limit
+}
+
 // NewBytesPerSecLimiter is a convenience function to create a rate.Limiter token bucket to allow bytesPerSec.
 //
 // By default, the bucket begins full. So NewBytesPerSecLimiter(1024) would allow 1024 bytes at 0s, then another
@@ -90,9 +298,14 @@ func NewBytesPerSecLimiter(bytesPerSec int64) *rate.Limiter {
 
 // DisableableLimiter implements a fast path to bypass the wrapped Limiter.
 // Depending on the Limiter used, this may be much more performant than setting an infinite rate limit.
+//
+// The wrapped Limiter can also be replaced at runtime, so a DisableableLimiter can serve as the single control
+// point for a subsystem's throttling.
 type DisableableLimiter struct {
 	disabled atomic.Bool
-	Limiter
+	Limiter  // wrapped until replaced by Swap
+
+	swapped atomic.Pointer[Limiter]
 }
 
 func NewDisableableLimiter(wrapping Limiter) *DisableableLimiter {
@@ -104,9 +317,31 @@ func (e *DisableableLimiter) Wait(ctx context.Context, n int) error {
 		return nil
 	}
 
-	return e.Limiter.Wait(ctx, n)
+	return e.Wrapped().Wait(ctx, n)
 }
 
 func (e *DisableableLimiter) SetEnabled(enabled bool) {
 	e.disabled.Store(!enabled)
 }
+
+// Enabled reports whether the wrapped Limiter is in use.
+func (e *DisableableLimiter) Enabled() bool {
+	return !e.disabled.Load()
+}
+
+// Wrapped returns the Limiter currently wrapped.
+func (e *DisableableLimiter) Wrapped() Limiter {
+	if lim := e.swapped.Load(); lim != nil {
+		return *lim
+	}
+	return e.Limiter
+}
+
+// Swap atomically replaces the wrapped Limiter with lim, returning the old one. Waits already in progress
+// complete on the old Limiter. The Limiter field keeps the limiter wrapped before the first Swap.
+func (e *DisableableLimiter) Swap(lim Limiter) (old Limiter) {
+	if prev := e.swapped.Swap(&lim); prev != nil {
+		return *prev
+	}
+	return e.Limiter
+}
@@ -4,10 +4,16 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"github.com/dustin/go-humanize"
 	"golang.org/x/time/rate"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -50,7 +56,7 @@ func TestSharedLimiter(t *testing.T) {
 	done := make(chan struct{})
 	for i := 0; i < n; i++ {
 		go func() {
-			testReadWithLimiter(t, lim, 16*time.Second, 32, expectedBytesPerSec)
+			testReadWithLimiter(t, lim, SystemClock, 16*time.Second, 32, expectedBytesPerSec)
 			done <- struct{}{}
 		}()
 	}
@@ -61,22 +67,24 @@ func TestSharedLimiter(t *testing.T) {
 }
 
 func testRead(t *testing.T, duration time.Duration, readSize int, bytesPerSecLimit int) {
-	lim := depletedLimiter(bytesPerSecLimit)
-	testReadWithLimiter(t, lim, duration, readSize, bytesPerSecLimit)
+	// Virtual time makes the result exact, and the test near-instant, as there's a single reader.
+	clock := NewVirtualClock(time.Unix(0, 0))
+	lim := depletedLimiter(bytesPerSecLimit, WithClock(clock))
+	testReadWithLimiter(t, lim, clock, duration, readSize, bytesPerSecLimit)
 }
 
 // Read a fixed amount of data, measure how long it took and compare
 // By taking limiter and expectedBytesPerSec, it's possible to test multiple readers of a shared limiter.
-func testReadWithLimiter(t *testing.T, lim Limiter, expectedDuration time.Duration, readSize int, expectedBytesPerSec int) {
+func testReadWithLimiter(t *testing.T, lim Limiter, clock Clock, expectedDuration time.Duration, readSize int, expectedBytesPerSec int) {
 	count := int64((expectedDuration.Seconds() + 0.0) * float64(expectedBytesPerSec))
 	r := NewReader(context.Background(), &nopReader{}, lim)
 
-	start := time.Now()
+	start := clock.Now()
 	_, err := io.CopyBuffer(io.Discard, io.LimitReader(r, count), make([]byte, readSize))
 	if err != nil {
 		t.Fatalf("copy: %s", err)
 	}
-	elapsed := time.Since(start)
+	elapsed := clock.Now().Sub(start)
 
 	// Ensure limiter didn't allow more or less than expected
 	// Allow 5% slop each way to account for overhead of copying the bytes and for a small concurrency
@@ -109,15 +117,16 @@ func testWrite(t *testing.T, expectedDuration time.Duration, writeSize int, byte
 
 	got := bytes.NewBuffer(make([]byte, 0, len(want)))
 
-	lim := depletedLimiter(bytesPerSecLimit)
+	clock := NewVirtualClock(time.Unix(0, 0))
+	lim := depletedLimiter(bytesPerSecLimit, WithClock(clock))
 	w := NewWriter(context.Background(), got, lim)
 
-	start := time.Now()
+	start := clock.Now()
 	_, err := io.CopyBuffer(w, bytes.NewReader(want), make([]byte, writeSize))
 	if err != nil {
 		t.Fatalf("copy: %s", err)
 	}
-	elapsed := time.Since(start)
+	elapsed := clock.Since(start)
 
 	// Ensure limiter didn't allow more or less than expected
 	// Allow 5% slop each way to account for overhead of copying the bytes and for a small concurrency
@@ -134,6 +143,450 @@ func testWrite(t *testing.T, expectedDuration time.Duration, writeSize int, byte
 	}
 }
 
+func TestRateLimiterAdapterClock(t *testing.T) {
+	clock := &stepClock{now: time.Unix(0, 0)}
+	lim := rate.NewLimiter(1024, 1024)
+	lim.AllowN(clock.Now(), 1024)
+	adapter := NewRateLimiterAdapter(lim, WithClock(clock))
+
+	// n larger than the burst is split into sequential reservations, each waited out on the clock.
+	_ = adapter.Wait(context.Background(), 3000)
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed.Round(time.Millisecond) != 2930*time.Millisecond {
+		t.Errorf("3000 bytes at 1KB/sec took %s, want 2.93s", elapsed)
+	}
+}
+
+func TestRateLimiterAdapterBurst(t *testing.T) {
+	clock := &stepClock{now: time.Unix(0, 0)}
+	lim := rate.NewLimiter(1024, 1024)
+	adapter := NewRateLimiterAdapter(lim, WithClock(clock))
+
+	// An increase via the adapter is used straight away: 4KB fits in a single reservation of a full bucket.
+	adapter.SetBurst(4096)
+	clock.NewTimer(3 * time.Second)
+	_ = adapter.Wait(context.Background(), 4096)
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 3*time.Second {
+		t.Errorf("4KB with a 4KB burst took %s beyond refilling, want no delay", elapsed-3*time.Second)
+	}
+
+	// A reduction made directly on the rate.Limiter is detected, and the cache refreshed.
+	lim.SetBurst(256)
+	if err := adapter.Wait(context.Background(), 1024); err != nil {
+		t.Fatalf("wait after reducing burst: %s", err)
+	}
+	if got := adapter.Burst(); got != 256 {
+		t.Errorf("Burst() = %d after reducing it directly, want 256", got)
+	}
+
+	lim.SetBurst(0)
+	if err := adapter.Wait(context.Background(), 10); err == nil {
+		t.Error("expected an error with zero burst capacity at a finite rate")
+	}
+}
+
+// TestStreamReleasesContext checks that Readers and Writers which are never closed, as Copy's aren't, don't leave
+// anything registered on a cancellable context once their waits return.
+func TestStreamReleasesContext(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx := &registeringContext{Context: parent}
+
+	for range 10 {
+		_, err := Copy(ctx, io.Discard, bytes.NewReader(make([]byte, 1000)), &countingLimiter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := NewWriter(ctx, io.Discard, &countingLimiter{})
+	_, _ = w.Write(make([]byte, 10))
+
+	if n := ctx.active.Load(); n != 0 {
+		t.Errorf("%d registrations left on the context, want 0", n)
+	}
+}
+
+// registeringContext counts the children and AfterFuncs registered on it which are still active.
+type registeringContext struct {
+	context.Context
+	active atomic.Int64
+}
+
+// Value hides the embedded context's internals, so children register through AfterFunc rather than directly.
+func (c *registeringContext) Value(any) any {
+	return nil
+}
+
+func (c *registeringContext) AfterFunc(f func()) func() bool {
+	c.active.Add(1)
+	stop := context.AfterFunc(c.Context, f)
+	var once sync.Once
+	return func() bool {
+		once.Do(func() { c.active.Add(-1) })
+		return stop()
+	}
+}
+
+func TestCopy(t *testing.T) {
+	clock := NewVirtualClock(time.Unix(0, 0))
+	lim := NewTokenBucket(1000, 0, WithClock(clock))
+
+	var dst maxWriteRecorder
+	n, err := Copy(context.Background(), &dst, bytes.NewReader(make([]byte, 2000)), lim)
+	if n != 2000 || err != nil {
+		t.Fatalf("Copy returned %d, %v", n, err)
+	}
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 2*time.Second {
+		t.Errorf("2000 bytes at 1000 bytes/sec took %s, want 2s", elapsed)
+	}
+	if dst.max != 512 {
+		t.Errorf("largest write was %d bytes, want 512 at a slow rate", dst.max)
+	}
+}
+
+func TestWriteContext(t *testing.T) {
+	lim := NewTokenBucket(1000, 0)
+	w := NewWriter(context.Background(), io.Discard, lim)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	n, err := w.WriteContext(ctx, make([]byte, 10_000))
+	if n != 10_000 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WriteContext returned %d, %v, want 10000, context.DeadlineExceeded", n, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("WriteContext took %s, want it bounded by the per-call deadline", elapsed)
+	}
+}
+
+func TestThrottleError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := NewReader(ctx, bytes.NewReader(make([]byte, 100)), NewTokenBucket(10, 0))
+	n, err := r.Read(make([]byte, 100))
+	if n != 100 {
+		t.Errorf("Read returned %d bytes, want 100", n)
+	}
+
+	var te *ThrottleError
+	if !errors.As(err, &te) || te.Op != "read" || te.Bytes != 100 {
+		t.Fatalf("Read returned %v, want a ThrottleError for 100 bytes read", err)
+	}
+	if te.Charged {
+		t.Error("TokenBucket undoes the charge for a failed wait, but the error reports it as charged")
+	}
+	if !errors.Is(err, ErrWaitCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Read returned %v, want it to match ErrWaitCanceled and context.Canceled", err)
+	}
+	if err.Error() != "waiting after reading 100 bytes: context canceled" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+
+	// A limiter which can't refund may have kept the charge
+	r = NewReader(ctx, bytes.NewReader(make([]byte, 100)), NewRateLimiterAdapter(rate.NewLimiter(10, 10)))
+	if _, err := r.Read(make([]byte, 100)); !errors.As(err, &te) || !te.Charged {
+		t.Errorf("Read returned %v, want a ThrottleError reporting the bytes as charged", err)
+	}
+
+	// Wrappers which implement Refunder regardless report on the limiter they wrap
+	adapter := NewRateLimiterAdapter(rate.NewLimiter(10, 10))
+	registry := NewRegistry(func(string) Limiter { return adapter }, 0)
+	wrappers := []Limiter{NewDebugLimiter(adapter, func(error) {}), registry.Get("a"), NewQuotaLimiter(1000, adapter)}
+	for _, lim := range wrappers {
+		r = NewReader(ctx, bytes.NewReader(make([]byte, 100)), lim)
+		if _, err := r.Read(make([]byte, 100)); !errors.As(err, &te) || !te.Charged {
+			t.Errorf("Read through %T returned %v, want a ThrottleError reporting the bytes as charged", lim, err)
+		}
+	}
+	r = NewReader(ctx, bytes.NewReader(make([]byte, 100)), NewDebugLimiter(NewTokenBucket(10, 0), func(error) {}))
+	if _, err := r.Read(make([]byte, 100)); !errors.As(err, &te) || te.Charged {
+		t.Errorf("Read through a DebugLimiter returned %v, want the TokenBucket's charge reported as undone", err)
+	}
+	r = NewReader(ctx, bytes.NewReader(make([]byte, 100)), NewQuotaLimiter(1000, NewTokenBucket(10, 0)))
+	if _, err := r.Read(make([]byte, 100)); !errors.As(err, &te) || te.Charged {
+		t.Errorf("Read through a QuotaLimiter returned %v, want the TokenBucket's charge reported as undone", err)
+	}
+
+	// Errors from the underlying I/O are returned as-is
+	w := NewWriter(ctx, errWriter{}, NewTokenBucket(10, 0))
+	if _, err := w.Write([]byte("x")); errors.Is(err, ErrWaitCanceled) {
+		t.Errorf("Write returned %v, which shouldn't match ErrWaitCanceled", err)
+	}
+}
+
+func TestWithDeferredWait(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	src := bytes.NewReader(make([]byte, 1000))
+	r := NewReader(canceled, src, NewTokenBucket(1000, 0), WithDeferredWait())
+
+	// Bytes transferred are returned without an error, and their charge deferred
+	if n, err := r.Read(make([]byte, 100)); n != 100 || err != nil {
+		t.Fatalf("Read returned %d, %v, want 100, nil", n, err)
+	}
+
+	// The deferred charge can't be paid whilst the context is done, so nothing more is read
+	if n, err := r.Read(make([]byte, 100)); n != 0 || !errors.Is(err, ErrWaitCanceled) {
+		t.Fatalf("Read returned %d, %v, want 0, ErrWaitCanceled", n, err)
+	}
+	if src.Len() != 900 {
+		t.Errorf("%d bytes were read from the source, want 100", 1000-src.Len())
+	}
+
+	// Once a wait can complete, the deferred charge is paid along with the next read's
+	start := time.Now()
+	if n, err := r.ReadContext(context.Background(), make([]byte, 100)); n != 100 || err != nil {
+		t.Fatalf("ReadContext returned %d, %v, want 100, nil", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("200 bytes at 1000 bytes/sec took %s, want ~200ms", elapsed)
+	}
+}
+
+func TestReaderClose(t *testing.T) {
+	src := &closeRecorder{Reader: bytes.NewReader(make([]byte, 1000))}
+	r := NewReader(context.Background(), src, NewTokenBucket(10, 0), WithCloseUnderlying())
+
+	time.AfterFunc(50*time.Millisecond, func() { _ = r.Close() })
+
+	start := time.Now()
+	n, err := r.Read(make([]byte, 100))
+	if n != 100 || !errors.Is(err, ErrClosed) || !errors.Is(err, ErrWaitCanceled) {
+		t.Errorf("Read returned %d, %v, want 100 and an error matching ErrClosed", n, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Read took %s, want it unblocked by Close", elapsed)
+	}
+	if !src.closed.Load() {
+		t.Error("underlying reader wasn't closed")
+	}
+
+	// Also unblocks calls with their own context, and fails further reads
+	if n, err := r.ReadContext(context.Background(), make([]byte, 100)); n != 0 || err != ErrClosed {
+		t.Errorf("ReadContext after Close returned %d, %v, want 0, ErrClosed", n, err)
+	}
+}
+
+func TestWriterPause(t *testing.T) {
+	var written atomic.Int64
+	w := NewWriter(context.Background(), writeCounter{&written}, NewTokenBucket(1<<40, 1<<40))
+
+	w.Pause()
+	done := make(chan error)
+	go func() {
+		_, err := w.Write(make([]byte, 100))
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Write returned whilst paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if n := written.Load(); n != 0 {
+		t.Fatalf("%d bytes were written whilst paused", n)
+	}
+
+	w.Resume()
+	if err := <-done; err != nil {
+		t.Fatalf("Write returned %v after Resume", err)
+	}
+	if n := written.Load(); n != 100 {
+		t.Errorf("%d bytes were written after Resume, want 100", n)
+	}
+
+	// A paused Write can still be unblocked by its context
+	w.Pause()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if n, err := w.WriteContext(ctx, make([]byte, 100)); n != 0 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WriteContext returned %d, %v whilst paused, want 0, context.DeadlineExceeded", n, err)
+	}
+}
+
+func TestWriterSetEnabled(t *testing.T) {
+	clock := NewVirtualClock(time.Unix(0, 0))
+	lim := NewTokenBucket(1000, 0, WithClock(clock))
+	w1 := NewWriter(context.Background(), io.Discard, lim)
+	w2 := NewWriter(context.Background(), io.Discard, lim)
+
+	// A disabled writer isn't throttled, and doesn't charge the shared limiter
+	w1.SetEnabled(false)
+	_, _ = w1.Write(make([]byte, 1000))
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 0 {
+		t.Errorf("disabled writer took %s, want 0", elapsed)
+	}
+
+	_, _ = w2.Write(make([]byte, 1000))
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != time.Second {
+		t.Errorf("other writer took %s, want 1s", elapsed)
+	}
+
+	w1.SetEnabled(true)
+	_, _ = w1.Write(make([]byte, 1000))
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 2*time.Second {
+		t.Errorf("re-enabled writer finished at %s, want 2s", elapsed)
+	}
+}
+
+type writeCounter struct {
+	n *atomic.Int64
+}
+
+func (w writeCounter) Write(p []byte) (int, error) {
+	w.n.Add(int64(len(p)))
+	return len(p), nil
+}
+
+func TestReadWriter(t *testing.T) {
+	clock := NewVirtualClock(time.Unix(0, 0))
+	var buf bytes.Buffer
+	rw := NewReadWriter(context.Background(), &buf,
+		NewTokenBucket(10_000, 0, WithClock(clock)), NewTokenBucket(1000, 0, WithClock(clock)))
+
+	// Writes and reads are limited separately
+	_, _ = rw.Write(make([]byte, 1000))
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != time.Second {
+		t.Errorf("1000 bytes written at 1000 bytes/sec took %s, want 1s", elapsed)
+	}
+	_, _ = io.ReadFull(rw, make([]byte, 1000))
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 1100*time.Millisecond {
+		t.Errorf("1000 bytes read at 10000 bytes/sec finished at %s, want 1.1s", elapsed)
+	}
+
+	if err := rw.Close(); err != nil {
+		t.Errorf("Close returned %v for a non-Closer", err)
+	}
+	if _, err := rw.Write([]byte("x")); err != ErrClosed {
+		t.Errorf("Write after Close returned %v, want ErrClosed", err)
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+// TestWithConcurrent writes to a bytes.Buffer, which isn't safe for concurrent use, from several goroutines.
+// Run with -race to check the writes are serialized.
+func TestWithConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(context.Background(), &buf, NewTokenBucket(1<<40, 1<<40), WithConcurrent())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = w.Write([]byte("0123456789"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if buf.Len() != 8*100*10 {
+		t.Errorf("wrote %d bytes, want %d", buf.Len(), 8*100*10)
+	}
+	if got := strings.Count(buf.String(), "0123456789"); got != 8*100 {
+		t.Errorf("found %d intact writes, want %d", got, 8*100)
+	}
+}
+
+func TestCopyChunked(t *testing.T) {
+	data := make([]byte, 10_000)
+	_, _ = rand.Read(data)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "src"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	src, err := os.Open(filepath.Join(dir, "src"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	dst, err := os.Create(filepath.Join(dir, "dst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	// File to file may use copy_file_range for each chunk
+	clock := NewVirtualClock(time.Unix(0, 0))
+	n, err := CopyChunked(context.Background(), dst, src, NewTokenBucket(10_000, 0, WithClock(clock)))
+	if n != 10_000 || err != nil {
+		t.Fatalf("CopyChunked returned %d, %v", n, err)
+	}
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != time.Second {
+		t.Errorf("10000 bytes at 10000 bytes/sec took %s, want 1s", elapsed)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "dst")); !bytes.Equal(got, data) {
+		t.Error("destination doesn't match the source")
+	}
+}
+
+type maxWriteRecorder struct {
+	max int
+}
+
+func (w *maxWriteRecorder) Write(p []byte) (int, error) {
+	w.max = max(w.max, len(p))
+	return len(p), nil
+}
+
+// hotLimiters returns limiters whose rate is high enough that a Wait of up to 1KB is never delayed.
+func hotLimiters() map[string]Limiter {
+	return map[string]Limiter{
+		"RateLimiterAdapter": NewRateLimiterAdapter(rate.NewLimiter(1<<40, 1<<30)),
+		"TokenBucket":        NewTokenBucket(1<<40, 1<<40),
+		"AtomicTokenBucket":  NewAtomicTokenBucket(1<<40, 1<<40),
+		"GCRALimiter":        NewGCRALimiter(1<<40, 1<<40),
+		"LeakyBucket":        NewLeakyBucket(1<<40, 0),
+	}
+}
+
+// TestWaitDoesNotAllocate guards the happy path of Reader and Writer, where no delay is needed, against
+// regressions: it runs for every Read and Write, so must stay allocation-free.
+func TestWaitDoesNotAllocate(t *testing.T) {
+	p := make([]byte, 1024)
+	for name, lim := range hotLimiters() {
+		r := NewReader(context.Background(), &nopReader{}, lim)
+		w := NewWriter(context.Background(), io.Discard, lim)
+
+		allocs := testing.AllocsPerRun(1000, func() {
+			_, _ = r.Read(p)
+			_, _ = w.Write(p)
+		})
+		if allocs > 0 {
+			t.Errorf("%s: Read and Write made %.0f allocations, want 0", name, allocs)
+		}
+	}
+}
+
+func BenchmarkWait(b *testing.B) {
+	for name, lim := range hotLimiters() {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			benchmarkRead(b, lim)
+		})
+	}
+}
+
 func benchmarkRead(b *testing.B, lim Limiter) {
 	r := NewReader(context.Background(), &nopReader{}, lim)
 
@@ -143,6 +596,39 @@ func benchmarkRead(b *testing.B, lim Limiter) {
 	}
 }
 
+func TestDisableableLimiter(t *testing.T) {
+	var first, second countingLimiter
+	lim := NewDisableableLimiter(&first)
+
+	_ = lim.Wait(context.Background(), 10)
+	lim.SetEnabled(false)
+	if lim.Enabled() {
+		t.Error("Enabled returned true after SetEnabled(false)")
+	}
+	_ = lim.Wait(context.Background(), 10)
+	lim.SetEnabled(true)
+
+	if old := lim.Swap(&second); old != &first {
+		t.Errorf("Swap returned %v, want the original limiter", old)
+	}
+	if lim.Wrapped() != &second {
+		t.Error("Wrapped didn't return the swapped-in limiter")
+	}
+	_ = lim.Wait(context.Background(), 20)
+
+	if first.n.Load() != 10 || second.n.Load() != 20 {
+		t.Errorf("limiters were charged %d and %d bytes, want 10 and 20", first.n.Load(), second.n.Load())
+	}
+
+	// The wrapped Limiter can still be set directly
+	var third countingLimiter
+	lit := &DisableableLimiter{Limiter: &third}
+	_ = lit.Wait(context.Background(), 30)
+	if third.n.Load() != 30 || lit.Wrapped() != &third {
+		t.Errorf("limiter set by field was charged %d bytes, want 30", third.n.Load())
+	}
+}
+
 func BenchmarkDisableableLimiter(b *testing.B) {
 	b.Run("WithoutDisableableLimiter", func(b *testing.B) {
 		lim := NewRateLimiterAdapter(rate.NewLimiter(rate.Inf, 0))
@@ -159,10 +645,11 @@ func BenchmarkDisableableLimiter(b *testing.B) {
 // Depleted limiter removes initial burst capacity, which is easier to reason about for tests.
 // This is because the # of bytes allowed would equal the limit * secs, rather than being off-by-one
 // due to the initial burst.
-func depletedLimiter(limit int) *RateLimiterAdapter {
+func depletedLimiter(limit int, opts ...Option) *RateLimiterAdapter {
+	w := newWaiter(opts)
 	lim := NewBytesPerSecLimiter(int64(limit))
-	lim.AllowN(time.Now(), limit)
-	return NewRateLimiterAdapter(lim)
+	lim.AllowN(w.now(), limit)
+	return NewRateLimiterAdapter(lim, opts...)
 }
 
 func verifyWithSlop(actual time.Duration, expected time.Duration, slop time.Duration) error {
@@ -0,0 +1,43 @@
+package throughput
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// timerCompensationTicks is how many timer ticks WithTimerCompensation batches delays into. Each sleep may overrun
+// by up to a tick, so batching into 8 keeps the rate within around a tenth of that configured.
+const timerCompensationTicks = 8
+
+var timerResolution = sync.OnceValue(measureTimerResolution)
+
+// TimerResolution returns the granularity of sleeps on this system: the typical time taken by a very short
+// sleep. It is measured once, on first use, which takes a few of those sleeps.
+//
+// This is usually well under a millisecond, but can be 15.6ms on Windows if high-resolution timers aren't
+// available -- making short waits wildly inaccurate. See WithTimerCompensation.
+func TimerResolution() time.Duration {
+	return timerResolution()
+}
+
+func measureTimerResolution() time.Duration {
+	samples := make([]time.Duration, 5)
+	for i := range samples {
+		start := time.Now()
+		time.Sleep(time.Microsecond)
+		samples[i] = time.Since(start)
+	}
+	slices.Sort(samples)
+	return samples[len(samples)/2]
+}
+
+// WithTimerCompensation batches delays into multiples of the system's TimerResolution, so that configured rates
+// are honoured on systems with coarse timers. It is WithMinSleep, with a minimum of several timer ticks.
+//
+// On Windows, this applies by default when timers are found to be coarse, unless WithSpin or WithMinSleep are
+// used. Like WithMinSleep, it never applies to SlidingWindowLimiter or IsochronousLimiter. Elsewhere, it is rarely
+// needed, but may help on heavily loaded or virtualized systems.
+func WithTimerCompensation() Option {
+	return WithMinSleep(timerCompensationTicks * TimerResolution())
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package throughput
+
+import "time"
+
+// platformMinSleep returns the default minimum sleep for waiters using the system clock.
+func platformMinSleep() time.Duration {
+	return 0
+}
+
+// RaiseTimerResolution asks the OS for 1ms timer resolution, for more accurate short waits, until restore is
+// called. This increases power usage system-wide, so should be restored when no longer needed.
+//
+// It only has an effect on Windows; elsewhere it does nothing.
+func RaiseTimerResolution() (restore func(), err error) {
+	return func() {}, nil
+}
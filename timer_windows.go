@@ -0,0 +1,45 @@
+package throughput
+
+import (
+	"fmt"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// coarseMinSleep is the minimum sleep applied by default when the system's timers are coarse, or zero until
+// TimerResolution has been measured. It is measured in the background, so that no limiter blocks on it.
+var coarseMinSleep atomic.Int64
+
+func init() {
+	go func() {
+		if res := TimerResolution(); res > 2*time.Millisecond {
+			coarseMinSleep.Store(int64(timerCompensationTicks * res))
+		}
+	}()
+}
+
+// platformMinSleep returns the default minimum sleep for waiters using the system clock.
+func platformMinSleep() time.Duration {
+	return time.Duration(coarseMinSleep.Load())
+}
+
+var (
+	winmm           = syscall.NewLazyDLL("winmm.dll")
+	timeBeginPeriod = winmm.NewProc("timeBeginPeriod")
+	timeEndPeriod   = winmm.NewProc("timeEndPeriod")
+)
+
+// RaiseTimerResolution asks the OS for 1ms timer resolution, for more accurate short waits, until restore is
+// called. This increases power usage system-wide, so should be restored when no longer needed.
+//
+// It only has an effect on Windows; elsewhere it does nothing.
+func RaiseTimerResolution() (restore func(), err error) {
+	if err := timeBeginPeriod.Find(); err != nil {
+		return nil, err
+	}
+	if r, _, _ := timeBeginPeriod.Call(1); r != 0 {
+		return nil, fmt.Errorf("timeBeginPeriod failed with code %d", r)
+	}
+	return func() { _, _, _ = timeEndPeriod.Call(1) }, nil
+}
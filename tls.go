@@ -0,0 +1,55 @@
+package throughput
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync/atomic"
+)
+
+// TLSServer returns a TLS server connection over conn, whose application data is rate-limited by readLim and
+// writeLim. The handshake is completed before returning, and its bytes aren't charged to readLim or writeLim --
+// so a tight limit doesn't make handshakes take tens of seconds -- but to handshakeLim, if it isn't nil.
+//
+// ctx bounds the handshake only: once it has completed, cancelling ctx doesn't affect the connection, as with
+// tls.Conn.HandshakeContext. Waits on the limiters end when the connection is closed. If the handshake fails,
+// conn is closed.
+func TLSServer(ctx context.Context, conn net.Conn, config *tls.Config, readLim, writeLim, handshakeLim Limiter) (*tls.Conn, error) {
+	return tlsHandshake(ctx, conn, readLim, writeLim, handshakeLim, func(c net.Conn) *tls.Conn {
+		return tls.Server(c, config)
+	})
+}
+
+// TLSClient is TLSServer for the client side of a connection.
+func TLSClient(ctx context.Context, conn net.Conn, config *tls.Config, readLim, writeLim, handshakeLim Limiter) (*tls.Conn, error) {
+	return tlsHandshake(ctx, conn, readLim, writeLim, handshakeLim, func(c net.Conn) *tls.Conn {
+		return tls.Client(c, config)
+	})
+}
+
+func tlsHandshake(
+	ctx context.Context,
+	conn net.Conn,
+	readLim, writeLim, handshakeLim Limiter,
+	newConn func(net.Conn) *tls.Conn,
+) (*tls.Conn, error) {
+	if handshakeLim == nil {
+		handshakeLim = unlimited{}
+	}
+
+	// Bytes are charged to handshakeLim until the handshake is complete. The connection outlives ctx, whilst
+	// HandshakeContext closes it if ctx is done during the handshake.
+	var done atomic.Bool
+	c := NewConn(context.WithoutCancel(ctx), conn,
+		NewPredicateLimiter(done.Load, readLim, handshakeLim),
+		NewPredicateLimiter(done.Load, writeLim, handshakeLim))
+
+	tc := newConn(c)
+	err := tc.HandshakeContext(ctx)
+	if err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	done.Store(true)
+	return tc, nil
+}
@@ -0,0 +1,53 @@
+package throughput
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTLSServerExcludesHandshake(t *testing.T) {
+	// Borrow httptest's certificate
+	srv := httptest.NewTLSServer(nil)
+	serverConfig := &tls.Config{Certificates: srv.TLS.Certificates}
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	srv.Close()
+
+	a, b := net.Pipe()
+	go func() {
+		c := tls.Client(b, &tls.Config{RootCAs: roots, ServerName: "example.com"})
+		_ = c.Handshake()
+		_, _ = c.Write([]byte("hello"))
+		_, _ = io.Copy(io.Discard, c) // until the server closes
+	}()
+
+	var app, handshake countingLimiter
+	ctx, cancel := context.WithCancel(context.Background())
+	c, err := TLSServer(ctx, a, serverConfig, &app, &app, &handshake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if handshake.n.Load() == 0 {
+		t.Error("handshake bytes weren't charged to the handshake limiter")
+	}
+	if n := app.n.Load(); n != 0 {
+		t.Errorf("%d handshake bytes were charged to the application limiters, want 0", n)
+	}
+
+	// Application data is charged once the handshake is complete, which ctx no longer bounds
+	cancel()
+	_, err = c.Read(make([]byte, 5))
+	if err != nil {
+		t.Errorf("Read after the handshake's ctx was cancelled: %v", err)
+	}
+	if app.n.Load() == 0 {
+		t.Error("application data wasn't charged to the application limiters")
+	}
+}
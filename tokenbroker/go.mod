@@ -0,0 +1,22 @@
+module github.com/iamcalledrob/throughput/tokenbroker
+
+go 1.23.0
+
+toolchain go1.23.3
+
+require (
+	github.com/iamcalledrob/throughput v0.0.0
+	google.golang.org/grpc v1.70.0
+	google.golang.org/protobuf v1.35.2
+)
+
+require (
+	golang.org/x/net v0.32.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	golang.org/x/time v0.11.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20241202173237-19429a94021a // indirect
+)
+
+// The broker is developed alongside the limiters it builds on.
+replace github.com/iamcalledrob/throughput => ../
@@ -0,0 +1,111 @@
+// Package tokenclient provides a limiter drawing byte grants over gRPC from a central tokenserver, so a rate can
+// be shared across a fleet of processes.
+package tokenclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iamcalledrob/throughput"
+	"github.com/iamcalledrob/throughput/tokenbroker/tokenserver"
+	"google.golang.org/grpc"
+)
+
+// Limiter is a throughput.Limiter which requests grants of bytes from a tokenserver in batches, to avoid making
+// a request for every Wait. Larger batches mean fewer requests, but bytes granted to an idle process are
+// unavailable to the rest of the fleet.
+type Limiter struct {
+	client tokenserver.TokenServerClient
+	key    string
+	batch  int64
+
+	mu        sync.Mutex
+	available int64
+	fetching  chan struct{} // closed once the grant being requested arrives, or nil if none is
+}
+
+// New returns a limiter requesting grants of at least batch bytes for key from the tokenserver at the other end
+// of conn, e.g. a *grpc.ClientConn.
+func New(conn grpc.ClientConnInterface, key string, batch int64) *Limiter {
+	return &Limiter{
+		client: tokenserver.NewTokenServerClient(conn),
+		key:    key,
+		batch:  max(batch, 1),
+	}
+}
+
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	need := int64(n)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for need > 0 {
+		if l.available > 0 {
+			take := min(l.available, need)
+			l.available -= take
+			need -= take
+			continue
+		}
+
+		if fetching := l.fetching; fetching != nil {
+			// Another Wait is requesting a grant, which this one can share once it arrives
+			l.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				l.mu.Lock()
+				// Bytes already taken from earlier grants are returned for the next Wait.
+				l.available += int64(n) - need
+				return ctx.Err()
+			case <-fetching:
+			}
+			l.mu.Lock()
+			continue
+		}
+
+		// The lock isn't held whilst requesting, so other waits can give up if their ctx is done.
+		fetching := make(chan struct{})
+		l.fetching = fetching
+		l.mu.Unlock()
+		granted, err := l.grant(ctx, max(need, l.batch))
+		l.mu.Lock()
+		l.fetching = nil
+		close(fetching)
+
+		if err != nil {
+			l.available += int64(n) - need
+			return err
+		}
+		take := min(granted, need)
+		l.available += granted - take
+		need -= take
+	}
+	return nil
+}
+
+// grant requests n bytes, and waits until they may be used.
+func (l *Limiter) grant(ctx context.Context, n int64) (int64, error) {
+	res, err := l.client.Grant(ctx, &tokenserver.GrantRequest{Key: l.key, Bytes: n})
+	if err != nil {
+		return 0, fmt.Errorf("requesting grant: %w", err)
+	}
+	if res.GetBytes() <= 0 {
+		return 0, fmt.Errorf("requesting grant: server granted %d bytes", res.GetBytes())
+	}
+
+	if delay := res.GetDelay().AsDuration(); delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			// The grant is lost, as the server has already charged it.
+			return 0, ctx.Err()
+		case <-t.C:
+		}
+	}
+	return res.GetBytes(), nil
+}
+
+var _ throughput.Limiter = (*Limiter)(nil)
@@ -0,0 +1,130 @@
+package tokenclient
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iamcalledrob/throughput"
+	"github.com/iamcalledrob/throughput/tokenbroker/tokenserver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestLimiter(t *testing.T) {
+	var requests atomic.Int64
+	srv := tokenserver.NewServer(func(string) throughput.Reserver {
+		return throughput.NewTokenBucket(40*1024, 0)
+	}, 64*1024, 0)
+	conn := serve(t, srv, &requests)
+
+	// Two clients share the fleet-wide 40 KiB/s
+	clients := []*Limiter{
+		New(conn, "fleet", 4096),
+		New(conn, "fleet", 4096),
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				err := c.Wait(context.Background(), 1024)
+				if err != nil {
+					t.Errorf("Wait: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 20 KiB at 40 KiB/s, in 4 KiB grants
+	elapsed := time.Since(start)
+	if elapsed < 350*time.Millisecond || elapsed > 800*time.Millisecond {
+		t.Errorf("took %v, want ~500ms", elapsed)
+	}
+	if got := requests.Load(); got > 6 {
+		t.Errorf("made %d grant requests, want 6 at most", got)
+	}
+}
+
+func TestLimiter_Canceled(t *testing.T) {
+	srv := tokenserver.NewServer(func(string) throughput.Reserver {
+		return throughput.NewTokenBucket(1024, 0)
+	}, 1024, 0)
+	conn := serve(t, srv, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := New(conn, "fleet", 1024).Wait(ctx, 1024)
+	if err == nil {
+		t.Error("expected error when context is cancelled during a grant's delay")
+	}
+}
+
+func TestLimiter_CanceledWhilstSharing(t *testing.T) {
+	srv := tokenserver.NewServer(func(string) throughput.Reserver {
+		return throughput.NewTokenBucket(1024, 0)
+	}, 1024, 0)
+	conn := serve(t, srv, nil)
+	lim := New(conn, "fleet", 1024)
+
+	// The first Wait requests a grant which won't be usable for a second
+	go func() {
+		_ = lim.Wait(context.Background(), 1024)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := lim.Wait(ctx, 1024)
+	if err == nil {
+		t.Error("expected error when context is cancelled whilst another Wait requests a grant")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("took %v to give up, want ~50ms", elapsed)
+	}
+}
+
+// serve serves srv over an in-memory connection, counting grant requests in requests if it isn't nil.
+func serve(t *testing.T, srv tokenserver.TokenServerServer, requests *atomic.Int64) *grpc.ClientConn {
+	t.Helper()
+
+	ln := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(grpc.UnaryInterceptor(func(
+		ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+	) (any, error) {
+		if requests != nil {
+			requests.Add(1)
+		}
+		return handler(ctx, req)
+	}))
+	tokenserver.RegisterTokenServerServer(s, srv)
+	go func() {
+		_ = s.Serve(ln)
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
@@ -0,0 +1,67 @@
+// Package tokenserver is a central broker handing out byte grants to tokenclient limiters over gRPC, enabling
+// fleet-wide bandwidth caps without shared storage such as Redis.
+//
+// The service is defined in tokenserver.proto; run go generate after editing it.
+package tokenserver
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative tokenserver.proto
+
+import (
+	"context"
+	"time"
+
+	"github.com/iamcalledrob/throughput"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// maxKeyLen bounds the keys clients may request grants for, as each key holds a limiter until it goes idle.
+const maxKeyLen = 256
+
+// Server is a TokenServerServer granting bytes from a limiter per key. Grants are charged to the limiter
+// immediately, and clients are told how long to wait before using them, so the server never blocks.
+//
+// Register it with a grpc.Server using RegisterTokenServerServer.
+type Server struct {
+	UnimplementedTokenServerServer
+
+	limiters *throughput.Registry[string]
+	maxGrant int64
+}
+
+// NewServer returns a server which calls newLimiter to create the limiter for a key, e.g. a
+// throughput.TokenBucket with the fleet-wide rate. Requests for more than maxGrant bytes are granted maxGrant.
+// Limiters which haven't granted anything for the idle duration are forgotten; if idle is zero, they're kept
+// forever.
+func NewServer(newLimiter func(key string) throughput.Reserver, maxGrant int64, idle time.Duration) *Server {
+	return &Server{
+		limiters: throughput.NewRegistry(func(key string) throughput.Limiter {
+			return newLimiter(key)
+		}, idle),
+		maxGrant: max(maxGrant, 1),
+	}
+}
+
+func (s *Server) Grant(_ context.Context, req *GrantRequest) (*GrantResponse, error) {
+	if req.GetBytes() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "bytes must be positive")
+	}
+	if len(req.GetKey()) > maxKeyLen {
+		return nil, status.Errorf(codes.InvalidArgument, "key is longer than %d bytes", maxKeyLen)
+	}
+
+	n := min(req.GetBytes(), s.maxGrant)
+	// Get marks the key as in use, so the limiter it wraps can be reserved from directly
+	lim := s.limiters.Get(req.GetKey()).(unwrapper).Unwrap().(throughput.Reserver)
+	delay, _ := lim.Reserve(int(n))
+
+	return &GrantResponse{Bytes: n, Delay: durationpb.New(max(delay, 0))}, nil
+}
+
+// unwrapper is implemented by the limiters a throughput.Registry returns.
+type unwrapper interface {
+	Unwrap() throughput.Limiter
+}
+
+var _ TokenServerServer = (*Server)(nil)
@@ -0,0 +1,127 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: tokenserver.proto
+
+package tokenserver
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TokenServer_Grant_FullMethodName = "/throughput.tokenserver.TokenServer/Grant"
+)
+
+// TokenServerClient is the client API for TokenServer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// TokenServer hands out grants of bytes from a limiter per key.
+type TokenServerClient interface {
+	// Grant charges the limiter for key, and returns how long the client must wait before using the grant.
+	Grant(ctx context.Context, in *GrantRequest, opts ...grpc.CallOption) (*GrantResponse, error)
+}
+
+type tokenServerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTokenServerClient(cc grpc.ClientConnInterface) TokenServerClient {
+	return &tokenServerClient{cc}
+}
+
+func (c *tokenServerClient) Grant(ctx context.Context, in *GrantRequest, opts ...grpc.CallOption) (*GrantResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GrantResponse)
+	err := c.cc.Invoke(ctx, TokenServer_Grant_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TokenServerServer is the server API for TokenServer service.
+// All implementations must embed UnimplementedTokenServerServer
+// for forward compatibility.
+//
+// TokenServer hands out grants of bytes from a limiter per key.
+type TokenServerServer interface {
+	// Grant charges the limiter for key, and returns how long the client must wait before using the grant.
+	Grant(context.Context, *GrantRequest) (*GrantResponse, error)
+	mustEmbedUnimplementedTokenServerServer()
+}
+
+// UnimplementedTokenServerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTokenServerServer struct{}
+
+func (UnimplementedTokenServerServer) Grant(context.Context, *GrantRequest) (*GrantResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Grant not implemented")
+}
+func (UnimplementedTokenServerServer) mustEmbedUnimplementedTokenServerServer() {}
+func (UnimplementedTokenServerServer) testEmbeddedByValue()                     {}
+
+// UnsafeTokenServerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TokenServerServer will
+// result in compilation errors.
+type UnsafeTokenServerServer interface {
+	mustEmbedUnimplementedTokenServerServer()
+}
+
+func RegisterTokenServerServer(s grpc.ServiceRegistrar, srv TokenServerServer) {
+	// If the following call pancis, it indicates UnimplementedTokenServerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TokenServer_ServiceDesc, srv)
+}
+
+func _TokenServer_Grant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GrantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServerServer).Grant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TokenServer_Grant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServerServer).Grant(ctx, req.(*GrantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TokenServer_ServiceDesc is the grpc.ServiceDesc for TokenServer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TokenServer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "throughput.tokenserver.TokenServer",
+	HandlerType: (*TokenServerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Grant",
+			Handler:    _TokenServer_Grant_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "tokenserver.proto",
+}
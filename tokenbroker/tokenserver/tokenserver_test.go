@@ -0,0 +1,88 @@
+package tokenserver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iamcalledrob/throughput"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_Grant(t *testing.T) {
+	srv := NewServer(func(string) throughput.Reserver {
+		return throughput.NewTokenBucket(1024, 1024)
+	}, 1024, 0)
+
+	// The first grant comes from the burst, and the second must wait for it to refill
+	res, err := srv.Grant(context.Background(), &GrantRequest{Key: "a", Bytes: 1024})
+	if err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+	if res.GetBytes() != 1024 || res.GetDelay().AsDuration() != 0 {
+		t.Errorf("granted %d bytes after %v, want 1024 immediately", res.GetBytes(), res.GetDelay().AsDuration())
+	}
+
+	res, err = srv.Grant(context.Background(), &GrantRequest{Key: "a", Bytes: 4096})
+	if err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+	if res.GetBytes() != 1024 {
+		t.Errorf("granted %d bytes, want maxGrant of 1024", res.GetBytes())
+	}
+	if d := res.GetDelay().AsDuration(); d < 900*time.Millisecond || d > time.Second {
+		t.Errorf("granted after %v, want ~1s", d)
+	}
+
+	// Keys have their own limiters
+	res, err = srv.Grant(context.Background(), &GrantRequest{Key: "b", Bytes: 1024})
+	if err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+	if d := res.GetDelay().AsDuration(); d != 0 {
+		t.Errorf("granted a fresh key after %v, want immediately", d)
+	}
+}
+
+func TestServer_GrantInvalid(t *testing.T) {
+	srv := NewServer(func(string) throughput.Reserver {
+		return throughput.NewTokenBucket(1024, 1024)
+	}, 1024, 0)
+
+	for _, req := range []*GrantRequest{
+		{Key: "a", Bytes: 0},
+		{Key: "a", Bytes: -1},
+		{Key: strings.Repeat("a", maxKeyLen+1), Bytes: 1},
+	} {
+		_, err := srv.Grant(context.Background(), req)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Grant(%d bytes, %d byte key) = %v, want InvalidArgument", req.Bytes, len(req.Key), err)
+		}
+	}
+}
+
+func TestServer_ForgetsIdle(t *testing.T) {
+	created := 0
+	srv := NewServer(func(string) throughput.Reserver {
+		created++
+		return throughput.NewTokenBucket(1024, 1024)
+	}, 1024, 50*time.Millisecond)
+
+	for range 2 {
+		_, err := srv.Grant(context.Background(), &GrantRequest{Key: "a", Bytes: 1})
+		if err != nil {
+			t.Fatalf("Grant: %v", err)
+		}
+	}
+	time.Sleep(120 * time.Millisecond)
+	_, err := srv.Grant(context.Background(), &GrantRequest{Key: "a", Bytes: 1})
+	if err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+
+	if created != 2 {
+		t.Errorf("created %d limiters, want 2 as the first went idle", created)
+	}
+}
@@ -0,0 +1,105 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TokenBucket is a token bucket limiter tuned for throttling bytes, which doesn't depend on golang.org/x/time/rate.
+//
+// Unlike rate.Limiter, n is unbounded: a Wait larger than the burst capacity puts the bucket into debt, and the
+// caller waits until the debt is repaid. This avoids the chunked reservations RateLimiterAdapter has to make.
+// The burst capacity can be read without locking, and the clock is injectable via WithClock.
+type TokenBucket struct {
+	mu    sync.Mutex
+	b     bucket
+	burst atomic.Int64 // mirrors b.burst, for lock-free reads
+	w     waiter
+}
+
+// NewTokenBucket returns a bucket refilling at bytesPerSec, holding at most burst bytes. The bucket begins full.
+func NewTokenBucket(bytesPerSec, burst int64, opts ...Option) *TokenBucket {
+	t := &TokenBucket{w: newWaiter(opts)}
+	t.b = newBucket(bytesPerSec, burst, t.w.now())
+	t.burst.Store(burst)
+	return t
+}
+
+func (t *TokenBucket) Wait(ctx context.Context, n int) error {
+	delay := t.reserve(n)
+	if delay <= 0 {
+		return nil
+	}
+
+	err := t.w.sleep(ctx, delay)
+	if err != nil {
+		t.Refund(n)
+		return err
+	}
+	return nil
+}
+
+// Reserve charges n bytes without waiting, and returns how long the caller must wait before proceeding.
+func (t *TokenBucket) Reserve(n int) (delay time.Duration, cancel func()) {
+	return t.reserve(n), func() { t.Refund(n) }
+}
+
+func (t *TokenBucket) reserve(n int) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.b.advance(t.w.now())
+	t.b.take(float64(n))
+	return t.b.debtDelay()
+}
+
+// Refund returns n bytes to the bucket, e.g. when a transfer they were charged for didn't happen.
+func (t *TokenBucket) Refund(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.b.advance(t.w.now())
+	t.b.refund(float64(n))
+}
+
+// Tokens returns the number of bytes available without waiting. It is negative when the bucket is in debt.
+func (t *TokenBucket) Tokens() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.b.advance(t.w.now())
+	return t.b.tokens
+}
+
+// Limit returns the refill rate in bytes per second.
+func (t *TokenBucket) Limit() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return int64(t.b.rate)
+}
+
+// SetLimit changes the refill rate to bytesPerSec. Tokens accrued at the old rate are kept.
+func (t *TokenBucket) SetLimit(bytesPerSec int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.b.advance(t.w.now())
+	t.b.rate = float64(bytesPerSec)
+}
+
+// Burst returns the maximum number of bytes the bucket can hold.
+func (t *TokenBucket) Burst() int64 {
+	return t.burst.Load()
+}
+
+// SetBurst changes the maximum number of bytes the bucket can hold, discarding any excess tokens.
+func (t *TokenBucket) SetBurst(burst int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.b.advance(t.w.now())
+	t.b.burst = float64(burst)
+	t.b.tokens = min(t.b.tokens, t.b.burst)
+	t.burst.Store(burst)
+}
+
+var _ AdjustableLimiter = (*TokenBucket)(nil)
+var _ Refunder = (*TokenBucket)(nil)
+var _ Reserver = (*TokenBucket)(nil)
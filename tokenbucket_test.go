@@ -0,0 +1,75 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	clock := &stepClock{now: time.Unix(0, 0)}
+	lim := NewTokenBucket(1024, 1024, WithClock(clock))
+
+	// Burst is available immediately
+	_ = lim.Wait(context.Background(), 1024)
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 0 {
+		t.Errorf("burst took %s, want 0", elapsed)
+	}
+
+	// n larger than the burst puts the bucket into debt, rather than failing
+	_ = lim.Wait(context.Background(), 4096)
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 4*time.Second {
+		t.Errorf("4KB at 1KB/sec took %s, want 4s", elapsed)
+	}
+}
+
+func TestTokenBucketCancelRefunds(t *testing.T) {
+	lim := NewTokenBucket(1024, 1024)
+	_ = lim.Wait(context.Background(), 1024)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := lim.Wait(ctx, 2048); err == nil {
+		t.Fatal("expected wait to be cancelled")
+	}
+	if tokens := lim.Tokens(); tokens < 0 {
+		t.Errorf("tokens = %.0f after cancelled wait, want >= 0", tokens)
+	}
+}
+
+func TestTokenBucketSetBurst(t *testing.T) {
+	lim := NewTokenBucket(1024, 4096)
+	lim.SetBurst(1024)
+	if lim.Burst() != 1024 {
+		t.Errorf("Burst() = %d, want 1024", lim.Burst())
+	}
+	if tokens := lim.Tokens(); tokens > 1024 {
+		t.Errorf("tokens = %.0f, want <= 1024", tokens)
+	}
+}
+
+// stepClock is a Clock whose timers fire immediately, advancing the clock by the timer's duration.
+type stepClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *stepClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *stepClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *stepClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	c.mu.Unlock()
+	return firedTimer(ch)
+}
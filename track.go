@@ -0,0 +1,29 @@
+package throughput
+
+import (
+	"context"
+	"time"
+)
+
+// trackRate polls rate every interval, applying it to lim, until ctx is done. If the first poll fails its error
+// is returned, whilst subsequent failures leave the rate unchanged.
+func trackRate(ctx context.Context, lim AdjustableLimiter, interval time.Duration, rate func() (int64, error)) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for first := true; ; first = false {
+		bytesPerSec, err := rate()
+		if err != nil && first {
+			return err
+		}
+		if err == nil && bytesPerSec != lim.Limit() {
+			lim.SetLimit(bytesPerSec)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
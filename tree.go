@@ -0,0 +1,108 @@
+package throughput
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// TreeProgress reports the progress of CopyTree.
+type TreeProgress struct {
+	Path       string // the file being copied, slash-separated and relative to the root
+	FileBytes  int64  // bytes of the file copied so far
+	FileSize   int64
+	TotalBytes int64 // bytes of all files copied so far
+	TotalSize  int64 // bytes of all files to copy
+}
+
+// CopyTree recursively copies src into the directory dst, which is created if necessary, with all file data
+// flowing through lim -- the building block of a bandwidth-limited backup. Existing files are overwritten, and
+// symlinks and other irregular files are skipped.
+//
+// If progress isn't nil, it is called after each chunk of a file is written.
+func CopyTree(ctx context.Context, dst string, src fs.FS, lim Limiter, progress func(TreeProgress)) error {
+	// Sized up front, so progress can report the total
+	var total int64
+	err := fs.WalkDir(src, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	p := TreeProgress{TotalSize: total}
+	return fs.WalkDir(src, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, filepath.FromSlash(path))
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0o700)
+		case d.Type().IsRegular():
+			p.Path, p.FileBytes, p.FileSize = path, 0, info.Size()
+			return copyTreeFile(ctx, target, src, path, info.Mode().Perm(), lim, &p, progress)
+		default:
+			return nil
+		}
+	})
+}
+
+func copyTreeFile(
+	ctx context.Context,
+	target string,
+	src fs.FS,
+	path string,
+	perm fs.FileMode,
+	lim Limiter,
+	p *TreeProgress,
+	progress func(TreeProgress),
+) error {
+	in, err := src.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = Copy(ctx, &progressWriter{w: out, p: p, progress: progress}, in, lim)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// progressWriter reports the progress of CopyTree as bytes are written.
+type progressWriter struct {
+	w        *os.File
+	p        *TreeProgress
+	progress func(TreeProgress)
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.p.FileBytes += int64(n)
+	w.p.TotalBytes += int64(n)
+	if w.progress != nil {
+		w.progress(*w.p)
+	}
+	return n, err
+}
@@ -0,0 +1,59 @@
+package throughput
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCopyTree(t *testing.T) {
+	src := fstest.MapFS{
+		"a.txt":         {Data: bytes.Repeat([]byte("a"), 1000)},
+		"dir/b.txt":     {Data: bytes.Repeat([]byte("b"), 500)},
+		"dir/sub/c.txt": {Data: bytes.Repeat([]byte("c"), 500)},
+		"empty":         {Mode: 0o755 | os.ModeDir},
+	}
+	dst := t.TempDir()
+
+	clock := NewVirtualClock(time.Unix(0, 0))
+	var last TreeProgress
+	files := make(map[string]int64)
+	err := CopyTree(context.Background(), dst, src, NewTokenBucket(1000, 0, WithClock(clock)), func(p TreeProgress) {
+		if p.TotalBytes < last.TotalBytes || p.TotalSize != 2000 {
+			t.Errorf("unexpected progress %+v after %+v", p, last)
+		}
+		files[p.Path] = p.FileBytes
+		last = p
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, f := range src {
+		got, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(name)))
+		if f.Mode.IsDir() {
+			if info, err := os.Stat(filepath.Join(dst, name)); err != nil || !info.IsDir() {
+				t.Errorf("directory %s wasn't created", name)
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(got, f.Data) {
+			t.Errorf("%s wasn't copied: %v", name, err)
+		}
+		if files[name] != int64(len(f.Data)) {
+			t.Errorf("progress for %s reached %d bytes, want %d", name, files[name], len(f.Data))
+		}
+	}
+	if last.TotalBytes != 2000 {
+		t.Errorf("progress reached %d bytes in total, want 2000", last.TotalBytes)
+	}
+
+	// All files share the limiter
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != 2*time.Second {
+		t.Errorf("2000 bytes at 1000 bytes/sec took %s, want 2s", elapsed)
+	}
+}
@@ -0,0 +1,153 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDropped is returned by limiters that drop, rather than delay, non-conforming traffic.
+var ErrDropped = errors.New("throughput: dropped non-conforming bytes")
+
+// Color is the classification given to each Wait by a ThreeColorLimiter.
+type Color int
+
+const (
+	// Green bytes conform to the committed rate.
+	Green Color = iota
+	// Yellow bytes exceed the committed rate, but conform to the peak rate.
+	Yellow
+	// Red bytes exceed the peak rate.
+	Red
+)
+
+func (c Color) String() string {
+	switch c {
+	case Green:
+		return "green"
+	case Yellow:
+		return "yellow"
+	case Red:
+		return "red"
+	default:
+		return "unknown"
+	}
+}
+
+// ColorAction is the treatment applied to bytes of a given Color.
+type ColorAction int
+
+const (
+	// Pass allows the bytes immediately, charging them as a two-rate three-color marker would.
+	Pass ColorAction = iota
+	// Delay waits until the bytes conform to both the committed and peak rates.
+	Delay
+	// Drop causes Wait to return ErrDropped, without charging the bytes.
+	Drop
+)
+
+// ColorPolicy configures how a ThreeColorLimiter treats each color.
+// The zero value passes everything, making the limiter a pure marker.
+type ColorPolicy struct {
+	Green, Yellow, Red ColorAction
+
+	// OnMark, if set, is called with the color of every Wait -- e.g. to record metrics.
+	OnMark func(c Color, n int)
+}
+
+func (p ColorPolicy) action(c Color) ColorAction {
+	switch c {
+	case Green:
+		return p.Green
+	case Yellow:
+		return p.Yellow
+	default:
+		return p.Red
+	}
+}
+
+// ThreeColorLimiter is a two-rate three-color marker (trTCM, RFC 2698) operating in color-blind mode.
+//
+// Each Wait is classified as green, yellow or red based on a committed bucket (CIR, CBS) and a peak bucket
+// (PIR, PBS), then treated according to a ColorPolicy. This enables differentiated treatment -- e.g. pass green,
+// delay yellow, drop red -- rather than pure delay.
+type ThreeColorLimiter struct {
+	policy ColorPolicy
+	w      waiter
+
+	mu        sync.Mutex
+	committed bucket
+	peak      bucket
+}
+
+// NewThreeColorLimiter returns a marker with a committed rate and burst, and a peak rate and burst, all in bytes.
+// Both buckets begin full.
+func NewThreeColorLimiter(
+	committedRate, committedBurst, peakRate, peakBurst int64,
+	policy ColorPolicy,
+	opts ...Option,
+) *ThreeColorLimiter {
+	t := &ThreeColorLimiter{policy: policy, w: newWaiter(opts)}
+	now := t.w.now()
+	t.committed = newBucket(committedRate, committedBurst, now)
+	t.peak = newBucket(peakRate, peakBurst, now)
+	return t
+}
+
+func (t *ThreeColorLimiter) Wait(ctx context.Context, n int) error {
+	nf := float64(n)
+
+	t.mu.Lock()
+	now := t.w.now()
+	t.committed.advance(now)
+	t.peak.advance(now)
+
+	color := Green
+	if t.peak.tokens < nf {
+		color = Red
+	} else if t.committed.tokens < nf {
+		color = Yellow
+	}
+
+	action := t.policy.action(color)
+
+	var delay time.Duration
+	switch action {
+	case Pass:
+		// Per RFC 2698: green is charged to both buckets, yellow only to the peak bucket, and red to neither.
+		switch color {
+		case Green:
+			t.committed.take(nf)
+			t.peak.take(nf)
+		case Yellow:
+			t.peak.take(nf)
+		}
+	case Delay:
+		t.committed.take(nf)
+		t.peak.take(nf)
+		delay = max(t.committed.debtDelay(), t.peak.debtDelay())
+	}
+	t.mu.Unlock()
+
+	if t.policy.OnMark != nil {
+		t.policy.OnMark(color, n)
+	}
+
+	switch action {
+	case Drop:
+		return ErrDropped
+	case Delay:
+		err := t.w.sleep(ctx, delay)
+		if err != nil {
+			t.mu.Lock()
+			t.committed.refund(nf)
+			t.peak.refund(nf)
+			t.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
+
+var _ Limiter = (*ThreeColorLimiter)(nil)
@@ -0,0 +1,43 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestThreeColorLimiterMarking(t *testing.T) {
+	var marks []Color
+	lim := NewThreeColorLimiter(1, 100, 1, 150, ColorPolicy{
+		OnMark: func(c Color, n int) { marks = append(marks, c) },
+	})
+
+	// 100 green (committed bucket spent), 50 yellow (peak bucket spent), then red.
+	for i := 0; i < 3; i++ {
+		if err := lim.Wait(context.Background(), 50); err != nil {
+			t.Fatalf("wait: %s", err)
+		}
+	}
+	_ = lim.Wait(context.Background(), 50)
+
+	want := []Color{Green, Green, Yellow, Red}
+	if len(marks) != len(want) {
+		t.Fatalf("got %d marks, want %d", len(marks), len(want))
+	}
+	for i := range want {
+		if marks[i] != want[i] {
+			t.Errorf("mark %d = %s, want %s", i, marks[i], want[i])
+		}
+	}
+}
+
+func TestThreeColorLimiterDrop(t *testing.T) {
+	lim := NewThreeColorLimiter(1, 10, 1, 10, ColorPolicy{Red: Drop})
+
+	if err := lim.Wait(context.Background(), 10); err != nil {
+		t.Fatalf("green wait: %s", err)
+	}
+	if err := lim.Wait(context.Background(), 10); !errors.Is(err, ErrDropped) {
+		t.Errorf("red wait: got %v, want ErrDropped", err)
+	}
+}
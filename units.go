@@ -0,0 +1,82 @@
+package throughput
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseRate parses a human-readable rate, such as "512K", "1.5MiB/s" or "100Mbit", into bytes per second.
+//
+// Byte units are binary, following curl and pv: "1K", "1KB" and "1KiB" are all 1024 bytes. Bit units are decimal,
+// following network convention: "1Mbit" and "1Mbps" are 1,000,000 bits. A trailing "/s" is optional.
+func ParseRate(s string) (int64, error) {
+	str := strings.TrimSpace(s)
+	str = strings.TrimSuffix(str, "/s")
+
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if i < 0 {
+		i = len(str)
+	}
+	num, unit := str[:i], strings.ToLower(strings.TrimSpace(str[i:]))
+
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("parsing rate %q: invalid number", s)
+	}
+
+	bits := false
+	for _, suffix := range []string{"bits", "bit", "bps"} {
+		if strings.HasSuffix(unit, suffix) {
+			unit, bits = strings.TrimSuffix(unit, suffix), true
+			break
+		}
+	}
+	if !bits {
+		unit = strings.TrimSuffix(unit, "b")
+	}
+	unit = strings.TrimSuffix(unit, "i")
+
+	exp := 0
+	if unit != "" {
+		exp = strings.Index("kmgt", unit) + 1
+		if len(unit) > 1 || exp == 0 {
+			return 0, fmt.Errorf("parsing rate %q: unknown unit", s)
+		}
+	}
+
+	base := 1024.0
+	if bits {
+		base = 1000
+	}
+	for range exp {
+		v *= base
+	}
+	if bits {
+		v /= 8
+	}
+	return int64(v), nil
+}
+
+// FormatRate formats a rate in bytes per second using binary units, e.g. "1.5 MiB/s".
+func FormatRate(bytesPerSec float64) string {
+	return FormatBytes(bytesPerSec) + "/s"
+}
+
+// FormatBytes formats a number of bytes using binary units, e.g. "1.5 MiB".
+func FormatBytes(n float64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return fmt.Sprintf("%.0f B", n)
+	}
+
+	i := -1
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %ciB", n, units[i])
+}
@@ -0,0 +1,40 @@
+package throughput
+
+import "testing"
+
+func TestParseRate(t *testing.T) {
+	for s, want := range map[string]int64{
+		"1024":     1024,
+		"512K":     512 * 1024,
+		"512 KB":   512 * 1024,
+		"1.5MiB/s": 3 * 512 * 1024,
+		"2g":       2 << 30,
+		"100Mbit":  12_500_000,
+		"8kbps":    1000,
+		"64 bits":  8,
+	} {
+		got, err := ParseRate(s)
+		if err != nil || got != want {
+			t.Errorf("ParseRate(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+
+	for _, s := range []string{"", "fast", "-1K", "10X", "10KK"} {
+		if _, err := ParseRate(s); err == nil {
+			t.Errorf("ParseRate(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	for rate, want := range map[float64]string{
+		0:                "0 B/s",
+		1023:             "1023 B/s",
+		1536:             "1.5 KiB/s",
+		64 * 1024 * 1024: "64.0 MiB/s",
+	} {
+		if got := FormatRate(rate); got != want {
+			t.Errorf("FormatRate(%.0f) = %q, want %q", rate, got, want)
+		}
+	}
+}
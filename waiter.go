@@ -0,0 +1,162 @@
+package throughput
+
+import (
+	"context"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Option configures how a limiter waits out the delays it computes.
+type Option func(*waiter)
+
+// WithJitter randomly lengthens each wait by up to frac (e.g. 0.1 for up to 10%), so that many transfers sharing
+// a schedule don't synchronize into lockstep bursts.
+//
+// Jitter never wakes a waiter early, so a limit is never exceeded. Limiters which accrue allowance whilst idle,
+// such as TokenBucket, make up the extra time on later Waits if their burst allows; otherwise the achieved rate
+// falls by up to frac.
+func WithJitter(frac float64) Option {
+	return func(w *waiter) {
+		w.jitter = min(max(frac, 0), 1)
+	}
+}
+
+// WithClock sets the clock a limiter uses to measure time and to wait, e.g. a fake clock in tests and simulations.
+// Without it, limiters use the standard library's clock directly, at no extra cost.
+func WithClock(c Clock) Option {
+	return func(w *waiter) {
+		w.clock = c
+	}
+}
+
+// WithSpin busy-waits, rather than sleeps, for delays shorter than threshold.
+//
+// At very high rates, e.g. 1+ GB/s, the delay between chunks falls below the OS's sleep granularity, so sleeps
+// overrun and the achieved rate undershoots -- especially for limiters with little or no burst to make up for
+// it. Spinning keeps short delays accurate at the cost of CPU. It only applies when using the system clock.
+func WithSpin(threshold time.Duration) Option {
+	return func(w *waiter) {
+		w.spin = threshold
+	}
+}
+
+// WithMinSleep skips delays shorter than d, leaving the limiter in debt until its delay is long enough to sleep
+// accurately. This is an alternative to WithSpin which doesn't burn CPU, but sends data in bursts of up to d.
+//
+// Limiters which charge bytes before waiting, such as TokenBucket and GCRALimiter, hold their average rate, as the
+// debt is waited out by a later Wait. SlidingWindowLimiter and IsochronousLimiter promise more than an average --
+// at most limit bytes in any window, or one allotment per tick -- so they ignore this option and always sleep.
+func WithMinSleep(d time.Duration) Option {
+	return func(w *waiter) {
+		w.minSleep = d
+	}
+}
+
+// waiter sleeps on behalf of a limiter, applying any configured Options.
+// It is embedded by value in limiters, so the zero value must be usable.
+type waiter struct {
+	clock    Clock // nil means the system clock, avoiding an interface call on the hot path
+	jitter   float64
+	spin     time.Duration
+	minSleep time.Duration
+	exact    bool // never skip delays, for limiters which mustn't let bytes through early
+}
+
+func newWaiter(opts []Option) waiter {
+	var w waiter
+	for _, opt := range opts {
+		opt(&w)
+	}
+	return w
+}
+
+// now returns the current time according to the waiter's clock.
+func (w *waiter) now() time.Time {
+	if w.clock == nil {
+		return time.Now()
+	}
+	return w.clock.Now()
+}
+
+// delay returns d adjusted by the waiter's options.
+func (w *waiter) delay(d time.Duration) time.Duration {
+	if w.jitter > 0 && d > 0 {
+		d = time.Duration(float64(d) * (1 + w.jitter*rand.Float64()))
+	}
+	return d
+}
+
+// sleep blocks for d (as adjusted by the waiter's options), or until ctx is done.
+func (w *waiter) sleep(ctx context.Context, d time.Duration) error {
+	d = w.delay(d)
+	if !w.exact {
+		if d < w.minSleep {
+			return nil
+		}
+		if w.clock == nil && w.spin == 0 && w.minSleep == 0 && d < platformMinSleep() {
+			// Coarse system timers are compensated for by default. See WithTimerCompensation.
+			return nil
+		}
+	}
+	if w.clock == nil && d < w.spin {
+		return spin(ctx, d)
+	}
+	if w.clock == nil || d <= 0 {
+		return sleep(ctx, d)
+	}
+
+	t := w.clock.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// timers pools the timers used by sleep, so that limiters don't allocate a timer for every delayed Wait.
+// Since Go 1.23, a stopped timer never delivers a stale value, so a timer can be reused as soon as it is stopped.
+var timers = sync.Pool{
+	New: func() any {
+		t := time.NewTimer(time.Hour)
+		t.Stop()
+		return t
+	},
+}
+
+// spin busy-waits for d, yielding the processor between checks, or until ctx is done.
+func spin(ctx context.Context, d time.Duration) error {
+	deadline := time.Now().Add(d)
+	for time.Now().Before(deadline) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		runtime.Gosched()
+	}
+	return nil
+}
+
+// sleep blocks for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := timers.Get().(*time.Timer)
+	t.Reset(d)
+	defer func() {
+		t.Stop()
+		timers.Put(t)
+	}()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,119 @@
+package throughput
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithJitter(t *testing.T) {
+	w := newWaiter([]Option{WithJitter(0.1)})
+
+	var longer bool
+	for i := 0; i < 1000; i++ {
+		d := w.delay(time.Second)
+		if d < time.Second || d > 1100*time.Millisecond {
+			t.Fatalf("jittered delay %s outside 0-10%% longer", d)
+		}
+		longer = longer || d > time.Second
+	}
+	if !longer {
+		t.Error("expected jitter to lengthen delays")
+	}
+
+	if d := w.delay(0); d != 0 {
+		t.Errorf("jittered zero delay = %s, want 0", d)
+	}
+}
+
+func TestVirtualClock(t *testing.T) {
+	clock := NewVirtualClock(time.Unix(0, 0))
+	lim := NewGCRALimiter(1000, 0, WithClock(clock))
+
+	// Sequential waits are exact
+	for i := 0; i < 10; i++ {
+		_ = lim.Wait(context.Background(), 100)
+	}
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != time.Second {
+		t.Errorf("1000 bytes at 1000 bytes/sec took %s, want 1s", elapsed)
+	}
+
+	clock.Advance(time.Minute)
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed != time.Minute+time.Second {
+		t.Errorf("after Advance, elapsed is %s, want 1m1s", elapsed)
+	}
+}
+
+func TestVirtualClockConcurrent(t *testing.T) {
+	clock := NewVirtualClock(time.Unix(0, 0))
+	lim := NewGCRALimiter(1000, 0, WithClock(clock))
+
+	// Concurrent waiters advance the clock to at least the latest deadline, never backwards. It may overshoot, if
+	// a waiter's timer is created after another has advanced the clock.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = lim.Wait(context.Background(), 100)
+		}()
+	}
+	wg.Wait()
+
+	if elapsed := clock.Since(time.Unix(0, 0)); elapsed < time.Second {
+		t.Errorf("1000 bytes at 1000 bytes/sec took %s, want at least 1s", elapsed)
+	}
+}
+
+func TestSleepReusesTimers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A timer stopped by cancellation is reused, and mustn't fire early for its next sleep.
+	_ = sleep(ctx, time.Hour)
+	start := time.Now()
+	_ = sleep(context.Background(), 20*time.Millisecond)
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("sleep after cancelled sleep took %s, want at least 20ms", elapsed)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = sleep(context.Background(), time.Microsecond)
+	})
+	if allocs > 0 {
+		t.Errorf("sleep made %.0f allocations, want 0", allocs)
+	}
+}
+
+func TestWithSpin(t *testing.T) {
+	testHighRate(t, WithSpin(time.Millisecond))
+}
+
+func TestWithMinSleep(t *testing.T) {
+	testHighRate(t, WithMinSleep(5*time.Millisecond))
+}
+
+func TestWithTimerCompensation(t *testing.T) {
+	if res := TimerResolution(); res <= 0 || res > 50*time.Millisecond {
+		t.Errorf("TimerResolution() = %s, want a plausible resolution", res)
+	}
+	testHighRate(t, WithTimerCompensation())
+}
+
+// testHighRate checks that a pacer achieves 1 GB/s, where the gap between 64KB chunks is far shorter than the
+// OS's sleep granularity.
+func testHighRate(t *testing.T, opt Option) {
+	lim := NewPacer(1_000_000_000, opt)
+
+	start := time.Now()
+	for i := 0; i < 3000; i++ {
+		_ = lim.Wait(context.Background(), 64*1000)
+	}
+
+	// 192MB at 1GB/sec
+	err := verifyWithSlop(time.Since(start), 192*time.Millisecond, 20*time.Millisecond)
+	if err != nil {
+		t.Error(err)
+	}
+}
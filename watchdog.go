@@ -0,0 +1,107 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTooSlow is the cause of a Watchdog's context being cancelled, and is returned by its Wait, once throughput
+// has fallen below the watchdog's floor.
+var ErrTooSlow = errors.New("throughput: transfer too slow")
+
+// watchdogSlots is how many slots a Watchdog's window is divided into, setting the granularity of its checks.
+const watchdogSlots = 4
+
+// Watchdog detects stalled transfers, complementing limiters which cap the maximum rate: if fewer than floor
+// bytes per second pass through it over a trailing window, its context is cancelled with ErrTooSlow. This lets
+// transfers over dead connections fail fast rather than hang.
+//
+// Bytes are counted by calling Wait, so a Watchdog is typically chained after a limiter, e.g.
+// NewReader(ctx, src, Chain(lim, wd)) where ctx is the watchdog's context. As a Read blocked on a dead connection
+// can't observe the cancellation itself, also use the context for the connection, e.g. as an HTTP request's
+// context, or with context.AfterFunc(ctx, conn.Close).
+type Watchdog struct {
+	floor  int64
+	window time.Duration
+	bytes  atomic.Int64
+	cancel context.CancelCauseFunc
+	ctx    context.Context
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewWatchdog starts a watchdog requiring at least floor bytes per second over each trailing window, and returns
+// it along with a context derived from ctx which it cancels if throughput falls below the floor. The first check
+// happens once a full window has elapsed. The watchdog must be stopped once the transfer is complete.
+//
+// The window is divided into slots, so a window shorter than one nanosecond per slot is lengthened to that.
+func NewWatchdog(ctx context.Context, floor int64, window time.Duration) (*Watchdog, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	w := &Watchdog{
+		floor:  floor,
+		window: max(window, watchdogSlots),
+		cancel: cancel,
+		ctx:    ctx,
+		stop:   make(chan struct{}),
+	}
+	go w.run()
+	return w, ctx
+}
+
+// Wait records n bytes, returning immediately. Once the watchdog has tripped, it returns ErrTooSlow.
+func (w *Watchdog) Wait(_ context.Context, n int) error {
+	if context.Cause(w.ctx) == ErrTooSlow {
+		return ErrTooSlow
+	}
+	w.bytes.Add(int64(n))
+	return nil
+}
+
+// Stop stops the watchdog, and releases its context -- which is cancelled as if by a context.CancelFunc.
+func (w *Watchdog) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+		w.cancel(nil)
+	})
+}
+
+func (w *Watchdog) run() {
+	t := time.NewTicker(w.window / watchdogSlots)
+	defer t.Stop()
+
+	// Bytes counted in each slot of the trailing window, as a ring
+	var slots [watchdogSlots]int64
+	var ticks int
+	required := int64(float64(w.floor) * w.window.Seconds())
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		slots[ticks%watchdogSlots] = w.bytes.Swap(0)
+		ticks++
+		if ticks < watchdogSlots {
+			continue
+		}
+
+		var total int64
+		for _, n := range slots {
+			total += n
+		}
+		if total < required {
+			w.cancel(ErrTooSlow)
+			return
+		}
+	}
+}
+
+var _ Limiter = (*Watchdog)(nil)
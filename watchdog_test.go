@@ -0,0 +1,61 @@
+package throughput
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	wd, ctx := NewWatchdog(context.Background(), 1000, 40*time.Millisecond)
+	defer wd.Stop()
+
+	// 100 bytes per 10ms is 10 KB/s, well above the floor
+	for range 10 {
+		_ = wd.Wait(ctx, 100)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("watchdog tripped during a healthy transfer: %v", context.Cause(ctx))
+	}
+
+	// The transfer stalls
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("watchdog didn't trip after the transfer stalled")
+	}
+	if cause := context.Cause(ctx); !errors.Is(cause, ErrTooSlow) {
+		t.Errorf("cause = %v, want ErrTooSlow", cause)
+	}
+	if err := wd.Wait(ctx, 1); !errors.Is(err, ErrTooSlow) {
+		t.Errorf("Wait after tripping: err = %v, want ErrTooSlow", err)
+	}
+}
+
+func TestWatchdog_Stop(t *testing.T) {
+	wd, ctx := NewWatchdog(context.Background(), 1000, 20*time.Millisecond)
+	wd.Stop()
+	wd.Stop() // no-op
+
+	time.Sleep(50 * time.Millisecond)
+	if cause := context.Cause(ctx); cause != context.Canceled {
+		t.Errorf("cause = %v after Stop, want context.Canceled", cause)
+	}
+	if err := wd.Wait(ctx, 1); err != nil {
+		t.Errorf("Wait after Stop: %v", err)
+	}
+}
+
+func TestWatchdog_ZeroWindow(t *testing.T) {
+	// A zero window would make the ticker panic, if it weren't lengthened
+	wd, ctx := NewWatchdog(context.Background(), 0, 0)
+	defer wd.Stop()
+
+	_ = wd.Wait(ctx, 1)
+	time.Sleep(10 * time.Millisecond)
+	if err := ctx.Err(); err != nil {
+		t.Errorf("watchdog with no floor tripped: %v", context.Cause(ctx))
+	}
+}
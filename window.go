@@ -0,0 +1,125 @@
+package throughput
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// SlidingWindowLimiter allows no more than limit bytes in any trailing window of time, matching how some API
+// quotas are enforced server-side -- so clients can self-throttle to match.
+//
+// Each Wait is recorded individually, so memory use is proportional to the number of calls to Wait within the
+// window. Waits larger than the limit are split into sequential limit-sized chunks.
+type SlidingWindowLimiter struct {
+	limit  int64
+	window time.Duration
+	w      waiter
+
+	mu      sync.Mutex
+	entries []windowEntry // in order of at, which may be in the future for pending waits
+	sum     int64
+}
+
+type windowEntry struct {
+	at time.Time
+	n  int64
+}
+
+// NewSlidingWindowLimiter returns a limiter allowing at most limit bytes in any trailing window.
+// A limit of zero or less blocks all calls to Wait until their context is done.
+func NewSlidingWindowLimiter(limit int64, window time.Duration, opts ...Option) *SlidingWindowLimiter {
+	s := &SlidingWindowLimiter{
+		limit:  limit,
+		window: window,
+		w:      newWaiter(opts),
+	}
+	s.w.exact = true
+	return s
+}
+
+func (s *SlidingWindowLimiter) Wait(ctx context.Context, n int) error {
+	if s.limit <= 0 && n > 0 {
+		// No bytes ever fit in the window
+		return s.w.sleep(ctx, math.MaxInt64)
+	}
+
+	for remaining := int64(n); remaining > 0; {
+		nn := min(remaining, s.limit)
+
+		at, now := s.reserve(nn)
+		err := s.w.sleep(ctx, at.Sub(now))
+		if err != nil {
+			s.cancel(at, nn)
+			return err
+		}
+
+		remaining -= nn
+	}
+	return nil
+}
+
+// reserve records n bytes at the earliest time they fit within the window, which is returned along with now.
+func (s *SlidingWindowLimiter) reserve(n int64) (at time.Time, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now = s.w.now()
+	s.prune(now)
+
+	at = now
+	if s.sum+n > s.limit {
+		// Find the oldest entry whose expiry frees enough of the window.
+		// Pending (future) entries are counted too, so reservations are made in order.
+		freed := int64(0)
+		for _, e := range s.entries {
+			freed += e.n
+			if s.sum-freed+n <= s.limit {
+				at = e.at.Add(s.window)
+				break
+			}
+		}
+	}
+
+	s.entries = append(s.entries, windowEntry{at: at, n: n})
+	s.sum += n
+	return at, now
+}
+
+func (s *SlidingWindowLimiter) cancel(at time.Time, n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if e := &s.entries[i]; e.at.Equal(at) && e.n == n {
+			s.sum -= e.n
+			e.n = 0
+			return
+		}
+	}
+}
+
+// prune forgets entries which have left the window.
+func (s *SlidingWindowLimiter) prune(now time.Time) {
+	i := 0
+	for ; i < len(s.entries); i++ {
+		if s.entries[i].at.Add(s.window).After(now) {
+			break
+		}
+		s.sum -= s.entries[i].n
+	}
+	if i > 0 {
+		s.entries = append(s.entries[:0], s.entries[i:]...)
+	}
+}
+
+// Used returns the number of bytes counted against the current window, including pending waits.
+func (s *SlidingWindowLimiter) Used() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(s.w.now())
+	return s.sum
+}
+
+var _ Limiter = (*SlidingWindowLimiter)(nil)
@@ -0,0 +1,64 @@
+package throughput
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSlidingWindowLimiter(t *testing.T) {
+	lim := NewSlidingWindowLimiter(1000, 100*time.Millisecond)
+
+	// The full limit is available immediately...
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		_ = lim.Wait(context.Background(), 250)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("first window took %s, expected no delay", elapsed)
+	}
+
+	// ...then further bytes wait for the earliest ones to leave the window.
+	_ = lim.Wait(context.Background(), 250)
+	err := verifyWithSlop(time.Since(start), 100*time.Millisecond, 20*time.Millisecond)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestSlidingWindowLimiterLargeWait(t *testing.T) {
+	lim := NewSlidingWindowLimiter(1000, 50*time.Millisecond)
+
+	// 3 chunks, so 2 windows must elapse
+	start := time.Now()
+	_ = lim.Wait(context.Background(), 2500)
+	err := verifyWithSlop(time.Since(start), 100*time.Millisecond, 20*time.Millisecond)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if used := lim.Used(); used != 500 {
+		t.Errorf("Used() = %d, want 500", used)
+	}
+}
+
+func TestSlidingWindowLimiterZeroLimit(t *testing.T) {
+	lim := NewSlidingWindowLimiter(0, time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := lim.Wait(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("Wait returned %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestSlidingWindowLimiterIgnoresMinSleep(t *testing.T) {
+	clock := NewVirtualClock(time.Unix(0, 0))
+	lim := NewSlidingWindowLimiter(1000, time.Second, WithClock(clock), WithMinSleep(time.Hour))
+
+	// Skipping the sleep would let the second window's bytes through early
+	_ = lim.Wait(context.Background(), 1000)
+	_ = lim.Wait(context.Background(), 1000)
+	if elapsed := clock.Now().Sub(time.Unix(0, 0)); elapsed < time.Second {
+		t.Errorf("second window began after %s, want 1s", elapsed)
+	}
+}